@@ -11,13 +11,16 @@ import (
 )
 
 var version, build, configFilePath string
+var showVersion bool
 
 func init() {
-	var showVersion bool
 	flag.BoolVar(&showVersion, "version", false, "Print Kratgo version")
 
 	flag.StringVar(&configFilePath, "config", "/etc/kratgo/kratgo.conf.yml", "Configuration file path")
+}
 
+func main() {
+	// The flags are parsed here instead of in init, so they do not break the flags of the tests
 	flag.Parse()
 
 	if showVersion {
@@ -27,9 +30,16 @@ func init() {
 		fmt.Printf("  Runtime: %s\n", runtime.Version())
 		os.Exit(0)
 	}
-}
 
-func main() {
+	if flag.Arg(0) == "warm" {
+		if err := warm(flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	cfg, err := config.Parse(configFilePath)
 	if err != nil {
 		panic(err)
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/savsgio/kratgo/modules/config"
+	"github.com/savsgio/kratgo/modules/warmer"
+	"github.com/valyala/fasthttp"
+)
+
+const warmPollInterval = time.Second
+
+const warmUsage = `Usage: kratgo [-config <path>] warm [options] [url ...]
+
+Prefill the cache of a running Kratgo through its admin api, with the given urls,
+the urls of a file and/or the urls of a sitemap.
+
+Options:
+`
+
+// warm runs the "warm" command, which starts a warming job in the admin api
+// and prints its progress until it finishes.
+func warm(args []string) error {
+	fs := flag.NewFlagSet("warm", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), warmUsage)
+		fs.PrintDefaults()
+	}
+
+	adminAddr := fs.String("admin", "", "Admin api address (Default: admin address of the configuration file)")
+	urlsFile := fs.String("urls", "", "File with one url per line, or - to read them from stdin")
+	sitemap := fs.String("sitemap", "", "Sitemap url, fetched through the proxy")
+	concurrency := fs.Int("concurrency", 0, "Maximum concurrent requests (Default: warmer configuration)")
+	rate := fs.Int("rate", 0, "Maximum requests per second (Default: warmer configuration)")
+
+	fs.Parse(args)
+
+	job := warmer.Job{
+		URLs:        fs.Args(),
+		Sitemap:     *sitemap,
+		Concurrency: *concurrency,
+		Rate:        *rate,
+	}
+
+	if *urlsFile != "" {
+		urls, err := readURLs(*urlsFile)
+		if err != nil {
+			return err
+		}
+
+		job.URLs = append(job.URLs, urls...)
+	}
+
+	if *adminAddr == "" {
+		cfg, err := config.Parse(configFilePath)
+		if err != nil {
+			return err
+		}
+
+		*adminAddr = cfg.Admin.Addr
+	}
+
+	return runWarm("http://"+*adminAddr+"/warm/", job, os.Stdout)
+}
+
+// readURLs returns the urls of the file, one per line, skipping the empty ones.
+func readURLs(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("Could not open the urls file: %v", err)
+		}
+		defer f.Close()
+
+		r = f
+	}
+
+	var urls []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if url := strings.TrimSpace(scanner.Text()); url != "" {
+			urls = append(urls, url)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Could not read the urls file: %v", err)
+	}
+
+	return urls, nil
+}
+
+func doWarmRequest(method, url string, body []byte, wantStatusCode int, progress *warmer.Progress) error {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+
+	defer func() {
+		fasthttp.ReleaseRequest(req)
+		fasthttp.ReleaseResponse(resp)
+	}()
+
+	req.Header.SetMethod(method)
+	req.SetRequestURI(url)
+
+	if len(body) > 0 {
+		req.Header.SetContentType("application/json")
+		req.SetBody(body)
+	}
+
+	if err := fasthttp.Do(req, resp); err != nil {
+		return fmt.Errorf("Could not connect to the admin api: %v", err)
+	}
+
+	if resp.StatusCode() != wantStatusCode {
+		return fmt.Errorf("Admin api responds with status code '%d': %s", resp.StatusCode(), resp.Body())
+	}
+
+	return json.Unmarshal(resp.Body(), progress)
+}
+
+// runWarm starts the warming job and writes its progress to out until it finishes.
+func runWarm(url string, job warmer.Job, out io.Writer) error {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	progress := warmer.Progress{}
+
+	if err := doWarmRequest(fasthttp.MethodPost, url, body, fasthttp.StatusAccepted, &progress); err != nil {
+		return err
+	}
+
+	for progress.Running {
+		time.Sleep(warmPollInterval)
+
+		if err := doWarmRequest(fasthttp.MethodGet, url, nil, fasthttp.StatusOK, &progress); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "Warmed %d/%d urls, %d failed\n", progress.Done, progress.Total, progress.Failed)
+	}
+
+	for _, f := range progress.Failures {
+		fmt.Fprintf(out, "Failed '%s': %s\n", f.URL, f.Error)
+	}
+
+	if progress.Failed > 0 {
+		return fmt.Errorf("%d urls could not be warmed", progress.Failed)
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/savsgio/kratgo/modules/warmer"
+	"github.com/valyala/fasthttp"
+)
+
+func testAdminAPI(t *testing.T, handler fasthttp.RequestHandler) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &fasthttp.Server{Handler: handler}
+	go s.Serve(ln)
+
+	t.Cleanup(func() {
+		s.Shutdown()
+	})
+
+	return "http://" + ln.Addr().String() + "/warm/"
+}
+
+func Test_readURLs(t *testing.T) {
+	type args struct {
+		content string
+		path    string
+	}
+
+	type want struct {
+		urls []string
+		err  bool
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Ok",
+			args: args{content: "http://www.kratgo.com/a/\n\n  http://www.kratgo.com/b/  \n"},
+			want: want{urls: []string{"http://www.kratgo.com/a/", "http://www.kratgo.com/b/"}},
+		},
+		{
+			name: "Empty",
+			args: args{content: "\n"},
+			want: want{urls: nil},
+		},
+		{
+			name: "NotFound",
+			args: args{path: "/sadasdadr2343dcr4c234/urls.txt"},
+			want: want{err: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.args.path
+
+			if path == "" {
+				path = filepath.Join(t.TempDir(), "urls.txt")
+
+				if err := os.WriteFile(path, []byte(tt.args.content), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			urls, err := readURLs(path)
+			if (err != nil) != tt.want.err {
+				t.Fatalf("readURLs() unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(urls, tt.want.urls) {
+				t.Errorf("readURLs() == '%v', want '%v'", urls, tt.want.urls)
+			}
+		})
+	}
+}
+
+func Test_runWarm(t *testing.T) {
+	type args struct {
+		startStatusCode int
+		start           warmer.Progress
+		finished        warmer.Progress
+	}
+
+	type want struct {
+		output string
+		err    bool
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Ok",
+			args: args{
+				startStatusCode: fasthttp.StatusAccepted,
+				start:           warmer.Progress{Running: true, Total: 2},
+				finished:        warmer.Progress{Total: 2, Done: 2},
+			},
+			want: want{output: "Warmed 2/2 urls, 0 failed\n"},
+		},
+		{
+			name: "Failures",
+			args: args{
+				startStatusCode: fasthttp.StatusAccepted,
+				start:           warmer.Progress{Running: true, Total: 2},
+				finished: warmer.Progress{
+					Total:    2,
+					Done:     2,
+					Failed:   1,
+					Failures: []warmer.Failure{{URL: "http://www.kratgo.com/a/", Error: "status code 500"}},
+				},
+			},
+			want: want{
+				output: "Warmed 2/2 urls, 1 failed\nFailed 'http://www.kratgo.com/a/': status code 500\n",
+				err:    true,
+			},
+		},
+		{
+			name: "AlreadyFinished",
+			args: args{
+				startStatusCode: fasthttp.StatusAccepted,
+				start:           warmer.Progress{Total: 1, Done: 1},
+			},
+			want: want{output: ""},
+		},
+		{
+			name: "Running",
+			args: args{startStatusCode: fasthttp.StatusConflict},
+			want: want{err: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var job warmer.Job
+
+			url := testAdminAPI(t, func(ctx *fasthttp.RequestCtx) {
+				progress := tt.args.finished
+
+				if ctx.IsPost() {
+					if err := json.Unmarshal(ctx.PostBody(), &job); err != nil {
+						t.Errorf("runWarm() invalid job: %v", err)
+					}
+
+					ctx.SetStatusCode(tt.args.startStatusCode)
+					progress = tt.args.start
+				}
+
+				body, _ := json.Marshal(progress)
+				ctx.SetBody(body)
+			})
+
+			wantJob := warmer.Job{URLs: []string{"http://www.kratgo.com/a/"}, Rate: 10}
+			out := new(bytes.Buffer)
+
+			err := runWarm(url, wantJob, out)
+			if (err != nil) != tt.want.err {
+				t.Fatalf("runWarm() unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(job, wantJob) {
+				t.Errorf("runWarm() job == '%+v', want '%+v'", job, wantJob)
+			}
+
+			if output := out.String(); output != tt.want.output {
+				t.Errorf("runWarm() output == '%s', want '%s'", output, tt.want.output)
+			}
+		})
+	}
+
+	if err := runWarm("http://127.0.0.1:0/warm/", warmer.Job{}, new(bytes.Buffer)); err == nil ||
+		!strings.Contains(err.Error(), "admin api") {
+		t.Errorf("runWarm() error == '%v', want a connection error", err)
+	}
+}
@@ -4,6 +4,8 @@ go 1.20
 
 require (
 	github.com/allegro/bigcache/v3 v3.1.0
+	github.com/andybalholm/brotli v1.0.5
+	github.com/klauspost/compress v1.16.3
 	github.com/savsgio/atreugo/v11 v11.9.12
 	github.com/savsgio/go-logger/v4 v4.2.0
 	github.com/savsgio/gotils v0.0.0-20230208104028-c358bd845dee
@@ -14,9 +16,7 @@ require (
 )
 
 require (
-	github.com/andybalholm/brotli v1.0.5 // indirect
 	github.com/fasthttp/router v1.4.18 // indirect
-	github.com/klauspost/compress v1.16.3 // indirect
 	github.com/philhofer/fwd v1.1.2 // indirect
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
 	github.com/valyala/tcplisten v1.0.0 // indirect
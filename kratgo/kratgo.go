@@ -1,12 +1,17 @@
 package kratgo
 
 import (
+	"os"
+	"os/signal"
+	"syscall"
+
 	"github.com/savsgio/go-logger/v4"
 	"github.com/savsgio/kratgo/modules/admin"
 	"github.com/savsgio/kratgo/modules/cache"
 	"github.com/savsgio/kratgo/modules/config"
 	"github.com/savsgio/kratgo/modules/invalidator"
 	"github.com/savsgio/kratgo/modules/proxy"
+	"github.com/savsgio/kratgo/modules/warmer"
 )
 
 // New ...
@@ -23,6 +28,7 @@ func New(cfg config.Config) (*Kratgo, error) {
 		return nil, err
 	}
 	k.logFile = logFile
+	k.log = logger.New(logLevel, logFile, logger.Field{Key: "type", Value: "kratgo"})
 
 	c, err := cache.New(cache.Config{
 		FileConfig: cfg.Cache,
@@ -32,16 +38,24 @@ func New(cfg config.Config) (*Kratgo, error) {
 	if err != nil {
 		return nil, err
 	}
+	k.cache = c
+
+	// A broken snapshot must not prevent the start, the cache is filled again from the backends
+	if _, err := c.LoadSnapshot(); err != nil {
+		k.log.Error(err)
+	}
 
-	if k.Proxy, err = proxy.New(proxy.Config{
+	p, err := proxy.New(proxy.Config{
 		FileConfig: cfg.Proxy,
 		Cache:      c,
 		HTTPScheme: defaultHTTPScheme,
 		LogLevel:   logLevel,
 		LogOutput:  logFile,
-	}); err != nil {
+	})
+	if err != nil {
 		return nil, err
 	}
+	k.Proxy = p
 
 	i, err := invalidator.New(invalidator.Config{
 		FileConfig: cfg.Invalidator,
@@ -53,10 +67,22 @@ func New(cfg config.Config) (*Kratgo, error) {
 		return nil, err
 	}
 
+	w, err := warmer.New(warmer.Config{
+		FileConfig: cfg.Warmer,
+		Handler:    p.Handler(),
+		LogLevel:   logLevel,
+		LogOutput:  logFile,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	if k.Admin, err = admin.New(admin.Config{
 		FileConfig:  cfg.Admin,
 		Cache:       c,
 		Invalidator: i,
+		Warmer:      w,
+		Proxy:       p,
 		HTTPScheme:  defaultHTTPScheme,
 		LogLevel:    logLevel,
 		LogOutput:   logFile,
@@ -67,19 +93,38 @@ func New(cfg config.Config) (*Kratgo, error) {
 	return k, nil
 }
 
-// ListenAndServe ...
+// ListenAndServe serves the proxy and admin until one of them fails or the process is stopped,
+// saving then the cache snapshot if configured.
 func (k *Kratgo) ListenAndServe() error {
 	defer k.logFile.Close()
 
-	err := make(chan error, 1)
+	errCh := make(chan error, 2)
 
 	go func() {
-		err <- k.Admin.ListenAndServe()
+		errCh <- k.Admin.ListenAndServe()
 	}()
 
 	go func() {
-		err <- k.Proxy.ListenAndServe()
+		errCh <- k.Proxy.ListenAndServe()
 	}()
 
-	return <-err
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	var err error
+
+	select {
+	case err = <-errCh:
+	case sig := <-signals:
+		k.log.Infof("Received signal '%s', shutting down", sig)
+	}
+
+	if k.cache != nil {
+		if snapshotErr := k.cache.SaveSnapshot(); snapshotErr != nil {
+			k.log.Error(snapshotErr)
+		}
+	}
+
+	return err
 }
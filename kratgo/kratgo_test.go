@@ -1,11 +1,14 @@
 package kratgo
 
 import (
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
 
 	logger "github.com/savsgio/go-logger/v4"
+	"github.com/savsgio/kratgo/modules/cache"
 	"github.com/savsgio/kratgo/modules/config"
 )
 
@@ -122,6 +125,23 @@ func TestKratgo_New(t *testing.T) {
 				err: true,
 			},
 		},
+		{
+			name: "InvalidWarmer",
+			args: args{
+				cfg: config.Config{
+					Admin:       cfgAdmin,
+					Cache:       cfgCache,
+					Invalidator: cfgInvalidator,
+					Warmer:      config.Warmer{Concurrency: -1},
+					Proxy:       cfgProxy,
+					LogLevel:    logLevel,
+					LogOutput:   logFileName,
+				},
+			},
+			want: want{
+				err: true,
+			},
+		},
 		{
 			name: "InvalidProxy",
 			args: args{
@@ -205,3 +225,32 @@ func TestKratgo_ListenAndServe(t *testing.T) {
 		t.Error("Kratgo.ListenAndServe() admin server is not listening")
 	}
 }
+
+func TestKratgo_ListenAndServeSnapshot(t *testing.T) {
+	dir := t.TempDir()
+
+	c, err := cache.New(cache.Config{
+		FileConfig: config.Cache{
+			TTL:            10,
+			CleanFrequency: 5,
+			Snapshot:       config.CacheSnapshot{Dir: dir},
+		},
+		LogLevel:  logger.FATAL,
+		LogOutput: os.Stderr,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k := new(Kratgo)
+	k.Proxy = new(mockServer)
+	k.Admin = new(mockServer)
+	k.cache = c
+	k.log = logger.New(logger.FATAL, os.Stderr)
+
+	k.ListenAndServe()
+
+	if _, err := os.Stat(filepath.Join(dir, "kratgo.snapshot")); err != nil {
+		t.Errorf("Kratgo.ListenAndServe() the cache snapshot has not been saved: %v", err)
+	}
+}
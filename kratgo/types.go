@@ -2,6 +2,9 @@ package kratgo
 
 import (
 	"os"
+
+	"github.com/savsgio/go-logger/v4"
+	"github.com/savsgio/kratgo/modules/cache"
 )
 
 // Kratgo ...
@@ -9,6 +12,9 @@ type Kratgo struct {
 	Proxy Server
 	Admin Server
 
+	cache *cache.Cache
+
+	log     *logger.Logger
 	logFile *os.File
 }
 
@@ -15,11 +15,16 @@ func New(cfg Config) (*Admin, error) {
 	a.server = atreugo.New(atreugo.Config{
 		Addr:   cfg.FileConfig.Addr,
 		Logger: log,
+
+		// The cache snapshots could exceed the max request body size, so they are streamed
+		StreamRequestBody: true,
 	})
 
 	a.httpScheme = cfg.HTTPScheme
 	a.cache = cfg.Cache
 	a.invalidator = cfg.Invalidator
+	a.warmer = cfg.Warmer
+	a.proxy = cfg.Proxy
 	a.log = log
 
 	a.init()
@@ -29,6 +34,12 @@ func New(cfg Config) (*Admin, error) {
 
 func (a *Admin) init() {
 	a.server.Path("POST", "/invalidate/", a.invalidateView)
+	a.server.Path("GET", "/snapshot/", a.snapshotExportView)
+	a.server.Path("POST", "/snapshot/", a.snapshotImportView)
+	a.server.Path("POST", "/warm/", a.warmView)
+	a.server.Path("GET", "/warm/", a.warmProgressView)
+	a.server.Path("GET", "/stats/", a.statsView)
+	a.server.Path("GET", "/backends/", a.backendsView)
 }
 
 // ListenAndServe ...
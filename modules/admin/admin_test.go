@@ -13,6 +13,8 @@ import (
 	"github.com/savsgio/kratgo/modules/cache"
 	"github.com/savsgio/kratgo/modules/config"
 	"github.com/savsgio/kratgo/modules/invalidator"
+	"github.com/savsgio/kratgo/modules/proxy"
+	"github.com/savsgio/kratgo/modules/warmer"
 )
 
 var testCache *cache.Cache
@@ -72,6 +74,7 @@ func (mock *mockServer) SetLogOutput(output io.Writer) {
 type mockInvalidator struct {
 	addCalled   bool
 	startCalled bool
+	entries     []invalidator.Entry
 	err         error
 
 	mu sync.RWMutex
@@ -86,11 +89,41 @@ func (mock *mockInvalidator) Start() {
 func (mock *mockInvalidator) Add(e invalidator.Entry) error {
 	mock.mu.Lock()
 	mock.addCalled = true
+	mock.entries = append(mock.entries, e)
 	mock.mu.Unlock()
 
 	return mock.err
 }
 
+type mockWarmer struct {
+	job      warmer.Job
+	progress warmer.Progress
+	err      error
+}
+
+func (mock *mockWarmer) Start(job warmer.Job) error {
+	mock.job = job
+
+	return mock.err
+}
+
+func (mock *mockWarmer) Progress() warmer.Progress {
+	return mock.progress
+}
+
+type mockProxy struct {
+	stats    proxy.Stats
+	backends []proxy.BackendStatus
+}
+
+func (mock *mockProxy) Stats() proxy.Stats {
+	return mock.stats
+}
+
+func (mock *mockProxy) Backends() []proxy.BackendStatus {
+	return mock.backends
+}
+
 func getMockPath(paths []mockPath, url, method string) *mockPath {
 	for _, v := range paths {
 		if v.url == url && v.method == method {
@@ -219,6 +252,36 @@ func TestAdmin_init(t *testing.T) {
 			url:    "/invalidate/",
 			view:   admin.invalidateView,
 		},
+		{
+			method: "GET",
+			url:    "/snapshot/",
+			view:   admin.snapshotExportView,
+		},
+		{
+			method: "POST",
+			url:    "/snapshot/",
+			view:   admin.snapshotImportView,
+		},
+		{
+			method: "POST",
+			url:    "/warm/",
+			view:   admin.warmView,
+		},
+		{
+			method: "GET",
+			url:    "/warm/",
+			view:   admin.warmProgressView,
+		},
+		{
+			method: "GET",
+			url:    "/stats/",
+			view:   admin.statsView,
+		},
+		{
+			method: "GET",
+			url:    "/backends/",
+			view:   admin.backendsView,
+		},
 	}
 
 	if len(expectedPaths) != len(serverMock.paths) {
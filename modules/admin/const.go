@@ -0,0 +1,5 @@
+package admin
+
+const snapshotContentType = "application/octet-stream"
+
+const snapshotContentDisposition = "attachment; filename=\"kratgo.snapshot\""
@@ -1,10 +1,14 @@
 package admin
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 
 	"github.com/savsgio/atreugo/v11"
+	"github.com/savsgio/kratgo/modules/cache"
 	"github.com/savsgio/kratgo/modules/invalidator"
+	"github.com/savsgio/kratgo/modules/warmer"
 )
 
 func (a *Admin) invalidateView(ctx *atreugo.RequestCtx) error {
@@ -29,3 +33,81 @@ func (a *Admin) invalidateView(ctx *atreugo.RequestCtx) error {
 
 	return ctx.TextResponse("OK")
 }
+
+func (a *Admin) snapshotExportView(ctx *atreugo.RequestCtx) error {
+	ctx.SetContentType(snapshotContentType)
+	ctx.Response.Header.Set("Content-Disposition", snapshotContentDisposition)
+
+	ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		count, err := a.cache.WriteSnapshot(w)
+		if err != nil {
+			a.log.Errorf("Could not export the cache snapshot: %v", err)
+			return
+		}
+
+		a.log.Infof("Exported %d cache entries", count)
+	})
+
+	return nil
+}
+
+func (a *Admin) snapshotImportView(ctx *atreugo.RequestCtx) error {
+	mode := string(ctx.QueryArgs().Peek("mode"))
+	if mode == "" {
+		mode = cache.SnapshotMerge
+	}
+
+	// The body is only streamed if it's larger than the max request body size
+	body := ctx.RequestBodyStream()
+	if body == nil {
+		body = bytes.NewReader(ctx.PostBody())
+	}
+
+	report, err := a.cache.ImportSnapshot(body, mode)
+	if err != nil {
+		a.log.Errorf("Could not import the cache snapshot: %v", err)
+		return ctx.TextResponse(err.Error(), 400)
+	}
+
+	a.log.Infof("Imported cache snapshot (%s): %d accepted, %d expired, %d too large, %d corrupted entries",
+		mode, report.Accepted, report.Expired, report.TooLarge, report.Corrupted)
+
+	return ctx.JSONResponse(report)
+}
+
+func (a *Admin) warmView(ctx *atreugo.RequestCtx) error {
+	job := warmer.Job{}
+	body := ctx.PostBody()
+
+	a.log.Debugf("Warming job received: %s", body)
+
+	if err := json.Unmarshal(body, &job); err != nil {
+		return err
+	}
+
+	if err := a.warmer.Start(job); err == warmer.ErrRunning {
+		return ctx.TextResponse(err.Error(), 409)
+	} else if err != nil {
+		a.log.Errorf("Could not start the warming job '%s': %v", body, err)
+		return ctx.TextResponse(err.Error(), 400)
+	}
+
+	return ctx.JSONResponse(a.warmer.Progress(), 202)
+}
+
+func (a *Admin) warmProgressView(ctx *atreugo.RequestCtx) error {
+	return ctx.JSONResponse(a.warmer.Progress())
+}
+
+func (a *Admin) statsView(ctx *atreugo.RequestCtx) error {
+	stats := Stats{
+		Cache: a.cache.Stats(),
+		Proxy: a.proxy.Stats(),
+	}
+
+	return ctx.JSONResponse(stats)
+}
+
+func (a *Admin) backendsView(ctx *atreugo.RequestCtx) error {
+	return ctx.JSONResponse(a.proxy.Backends())
+}
@@ -1,11 +1,22 @@
 package admin
 
 import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/savsgio/atreugo/v11"
+	logger "github.com/savsgio/go-logger/v4"
+	"github.com/savsgio/kratgo/modules/cache"
 	"github.com/savsgio/kratgo/modules/invalidator"
+	"github.com/savsgio/kratgo/modules/proxy"
+	"github.com/savsgio/kratgo/modules/warmer"
 	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
 )
 
 func TestAdmin_invalidateView(t *testing.T) {
@@ -108,3 +119,515 @@ func TestAdmin_invalidateView(t *testing.T) {
 		})
 	}
 }
+
+func TestAdmin_invalidateViewTags(t *testing.T) {
+	invalidatorMock := new(mockInvalidator)
+
+	admin, err := New(testConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	admin.invalidator = invalidatorMock
+
+	bodies := []string{"{\"tags\": [\"product\"]}", "{\"tags\": [\"homepage\"]}"}
+
+	// The queued invalidations keep their tags, even if the next request reuses the pooled entry
+	for _, body := range bodies {
+		actx := new(atreugo.RequestCtx)
+		actx.RequestCtx = new(fasthttp.RequestCtx)
+
+		actx.Request.Header.SetMethod("POST")
+		actx.Request.SetBodyString(body)
+
+		if err := admin.invalidateView(actx); err != nil {
+			t.Fatalf("Admin.invalidateView() unexpected error: %v", err)
+		}
+	}
+
+	want := [][]string{{"product"}, {"homepage"}}
+
+	if len(invalidatorMock.entries) != len(want) {
+		t.Fatalf("Admin.invalidateView() invalidations == '%d', want '%d'", len(invalidatorMock.entries), len(want))
+	}
+
+	for i, e := range invalidatorMock.entries {
+		if !reflect.DeepEqual(e.Tags, want[i]) {
+			t.Errorf("Admin.invalidateView() invalidation %d tags == '%v', want '%v'", i, e.Tags, want[i])
+		}
+	}
+}
+
+func TestAdmin_snapshotViews(t *testing.T) {
+	fileConfig := fileConfigCache()
+	fileConfig.MaxEntrySize = 500
+
+	c, err := cache.New(cache.Config{
+		FileConfig: fileConfig,
+		LogLevel:   logger.FATAL,
+		LogOutput:  os.Stderr,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cfg := testConfig()
+	cfg.Cache = c
+
+	admin, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	host := "www.kratgo.com"
+	entry := cache.Entry{
+		Responses: []cache.Response{
+			{Host: []byte(host), Path: []byte("/"), StoredAt: time.Now().Unix(), TTL: 3600},
+		},
+	}
+	admin.cache.Set(host+"/", entry)
+
+	actx := new(atreugo.RequestCtx)
+	actx.RequestCtx = new(fasthttp.RequestCtx)
+
+	if err := admin.snapshotExportView(actx); err != nil {
+		t.Fatalf("Admin.snapshotExportView() unexpected error: %v", err)
+	}
+
+	if contentType := string(actx.Response.Header.ContentType()); contentType != snapshotContentType {
+		t.Errorf("Admin.snapshotExportView() content type == '%s', want '%s'", contentType, snapshotContentType)
+	}
+
+	snapshot := append([]byte{}, actx.Response.Body()...)
+
+	type args struct {
+		mode     string
+		snapshot []byte
+	}
+
+	type want struct {
+		report     cache.SnapshotReport
+		statusCode int
+		len        int
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "DefaultMode",
+			args: args{
+				snapshot: snapshot,
+			},
+			want: want{
+				report:     cache.SnapshotReport{Accepted: 1},
+				statusCode: 200,
+				len:        2,
+			},
+		},
+		{
+			name: "Replace",
+			args: args{
+				mode:     cache.SnapshotReplace,
+				snapshot: snapshot,
+			},
+			want: want{
+				report:     cache.SnapshotReport{Accepted: 1},
+				statusCode: 200,
+				len:        1,
+			},
+		},
+		{
+			name: "InvalidMode",
+			args: args{
+				mode:     "append",
+				snapshot: snapshot,
+			},
+			want: want{
+				statusCode: 400,
+				len:        2,
+			},
+		},
+		{
+			name: "InvalidSnapshot",
+			args: args{
+				snapshot: []byte("invalid"),
+			},
+			want: want{
+				statusCode: 400,
+				len:        2,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			admin.cache.Reset()
+			admin.cache.Set(host+"/other/", entry)
+			admin.cache.Set(host+"/", entry)
+
+			actx := new(atreugo.RequestCtx)
+			actx.RequestCtx = new(fasthttp.RequestCtx)
+
+			actx.Request.Header.SetMethod("POST")
+			actx.Request.SetRequestURI("/snapshot/?mode=" + tt.args.mode)
+			actx.Request.SetBody(tt.args.snapshot)
+
+			if err := admin.snapshotImportView(actx); err != nil {
+				t.Fatalf("Admin.snapshotImportView() unexpected error: %v", err)
+			}
+
+			if statusCode := actx.Response.StatusCode(); statusCode != tt.want.statusCode {
+				t.Errorf("Admin.snapshotImportView() status code == '%d', want '%d'", statusCode, tt.want.statusCode)
+			}
+
+			if n := admin.cache.Len(); n != tt.want.len {
+				t.Errorf("Admin.snapshotImportView() cache length == '%d', want '%d'", n, tt.want.len)
+			}
+
+			if tt.want.statusCode != 200 {
+				return
+			}
+
+			report := cache.SnapshotReport{}
+			if err := json.Unmarshal(actx.Response.Body(), &report); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if report != tt.want.report {
+				t.Errorf("Admin.snapshotImportView() report == '%+v', want '%+v'", report, tt.want.report)
+			}
+		})
+	}
+}
+
+func TestAdmin_snapshotImportViewLarge(t *testing.T) {
+	fileConfig := fileConfigCache()
+	fileConfig.MaxEntrySize = 500
+
+	c, err := cache.New(cache.Config{
+		FileConfig: fileConfig,
+		LogLevel:   logger.FATAL,
+		LogOutput:  os.Stderr,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cfg := testConfig()
+	cfg.Cache = c
+
+	admin, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	host := "www.kratgo.com"
+
+	// A snapshot over the default max request body size, with a too large entry and a small one
+	srcConfig := fileConfigCache()
+	srcConfig.Storage = cache.LRUStorage
+
+	src, err := cache.New(cache.Config{
+		FileConfig: srcConfig,
+		LogLevel:   logger.FATAL,
+		LogOutput:  os.Stderr,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	src.Set(host+"/big/", cache.Entry{
+		Responses: []cache.Response{
+			{
+				Host:     []byte(host),
+				Path:     []byte("/big/"),
+				Body:     bytes.Repeat([]byte("k"), 5*1024*1024),
+				StoredAt: time.Now().Unix(),
+				TTL:      3600,
+			},
+		},
+	})
+	src.Set(host+"/", cache.Entry{
+		Responses: []cache.Response{
+			{Host: []byte(host), Path: []byte("/"), StoredAt: time.Now().Unix(), TTL: 3600},
+		},
+	})
+
+	snapshot := new(bytes.Buffer)
+	if _, err := src.WriteSnapshot(snapshot); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if snapshot.Len() <= fasthttp.DefaultMaxRequestBodySize {
+		t.Fatalf("The snapshot size '%d' is not over the max request body size", snapshot.Len())
+	}
+
+	ln := fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+
+	go admin.server.(*atreugo.Atreugo).Serve(ln)
+
+	client := &fasthttp.Client{
+		Dial: func(addr string) (net.Conn, error) {
+			return ln.Dial()
+		},
+	}
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.Header.SetMethod("POST")
+	req.SetRequestURI("http://" + host + "/snapshot/")
+	req.SetBody(snapshot.Bytes())
+
+	if err := client.Do(req, resp); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if statusCode := resp.StatusCode(); statusCode != 200 {
+		t.Fatalf("Admin.snapshotImportView() status code == '%d', want '%d'", statusCode, 200)
+	}
+
+	report := cache.SnapshotReport{}
+	if err := json.Unmarshal(resp.Body(), &report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if want := (cache.SnapshotReport{Accepted: 1, TooLarge: 1}); report != want {
+		t.Errorf("Admin.snapshotImportView() report == '%+v', want '%+v'", report, want)
+	}
+
+	if n := c.Len(); n != 1 {
+		t.Errorf("Admin.snapshotImportView() cache length == '%d', want '%d'", n, 1)
+	}
+}
+
+func TestAdmin_warmView(t *testing.T) {
+	type args struct {
+		body     string
+		startErr error
+	}
+
+	type want struct {
+		statusCode int
+		err        bool
+		job        warmer.Job
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Ok",
+			args: args{
+				body: "{\"urls\": [\"http://www.kratgo.com/\"], \"sitemap\": \"http://www.kratgo.com/sitemap.xml\", \"rate\": 10}",
+			},
+			want: want{
+				statusCode: 202,
+				job: warmer.Job{
+					URLs:    []string{"http://www.kratgo.com/"},
+					Sitemap: "http://www.kratgo.com/sitemap.xml",
+					Rate:    10,
+				},
+			},
+		},
+		{
+			name: "Running",
+			args: args{
+				body:     "{\"urls\": [\"http://www.kratgo.com/\"]}",
+				startErr: warmer.ErrRunning,
+			},
+			want: want{
+				statusCode: 409,
+				job:        warmer.Job{URLs: []string{"http://www.kratgo.com/"}},
+			},
+		},
+		{
+			name: "EmptyJob",
+			args: args{
+				body:     "{}",
+				startErr: warmer.ErrEmptyJob,
+			},
+			want: want{
+				statusCode: 400,
+			},
+		},
+		{
+			name: "InvalidJSONBody",
+			args: args{
+				body: "\"",
+			},
+			want: want{
+				statusCode: 200, // 500 is setted by Atreugo when is returned the error
+				err:        true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warmerMock := &mockWarmer{
+				err:      tt.args.startErr,
+				progress: warmer.Progress{Running: true, Total: 2},
+			}
+
+			admin, err := New(testConfig())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			admin.warmer = warmerMock
+
+			actx := new(atreugo.RequestCtx)
+			actx.RequestCtx = new(fasthttp.RequestCtx)
+			actx.Request.Header.SetMethod("POST")
+			actx.Request.SetBodyString(tt.args.body)
+
+			err = admin.warmView(actx)
+			if (err != nil) != tt.want.err {
+				t.Fatalf("Admin.warmView() error == '%v', want '%v'", err, tt.want.err)
+			}
+
+			if statusCode := actx.Response.StatusCode(); statusCode != tt.want.statusCode {
+				t.Errorf("Admin.warmView() status code == '%d', want '%d'", statusCode, tt.want.statusCode)
+			}
+
+			if !reflect.DeepEqual(warmerMock.job, tt.want.job) {
+				t.Errorf("Admin.warmView() job == '%v', want '%v'", warmerMock.job, tt.want.job)
+			}
+
+			if tt.want.statusCode != 202 {
+				return
+			}
+
+			progress := warmer.Progress{}
+			if err := json.Unmarshal(actx.Response.Body(), &progress); err != nil {
+				t.Fatalf("Admin.warmView() invalid json response: %v", err)
+			}
+
+			if !reflect.DeepEqual(progress, warmerMock.progress) {
+				t.Errorf("Admin.warmView() progress == '%v', want '%v'", progress, warmerMock.progress)
+			}
+		})
+	}
+}
+
+func TestAdmin_warmProgressView(t *testing.T) {
+	warmerMock := &mockWarmer{
+		progress: warmer.Progress{
+			Total:    2,
+			Done:     2,
+			Failed:   1,
+			Failures: []warmer.Failure{{URL: "http://www.kratgo.com/", Error: "Unexpected status code '502'"}},
+		},
+	}
+
+	admin, err := New(testConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	admin.warmer = warmerMock
+
+	actx := new(atreugo.RequestCtx)
+	actx.RequestCtx = new(fasthttp.RequestCtx)
+
+	if err := admin.warmProgressView(actx); err != nil {
+		t.Fatalf("Admin.warmProgressView() unexpected error: %v", err)
+	}
+
+	progress := warmer.Progress{}
+	if err := json.Unmarshal(actx.Response.Body(), &progress); err != nil {
+		t.Fatalf("Admin.warmProgressView() invalid json response: %v", err)
+	}
+
+	if !reflect.DeepEqual(progress, warmerMock.progress) {
+		t.Errorf("Admin.warmProgressView() progress == '%v', want '%v'", progress, warmerMock.progress)
+	}
+}
+
+func TestAdmin_statsView(t *testing.T) {
+	proxyMock := &mockProxy{
+		stats: proxy.Stats{
+			Hits:            3,
+			Misses:          1,
+			BackendFetches:  2,
+			BackendErrors:   1,
+			NocacheBypasses: 1,
+			Hosts:           map[string]proxy.HostStats{"www.kratgo.com": {Hits: 3, Misses: 1, HitRatio: 0.75}},
+			Admission:       []proxy.AdmissionStats{{Policy: "frequency", Admitted: 1, Rejected: 2}},
+		},
+	}
+
+	admin, err := New(testConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	admin.proxy = proxyMock
+
+	if err := admin.cache.Set("www.kratgo.com", cache.Entry{
+		Responses: []cache.Response{{Path: []byte("/"), Body: []byte("Kratgo")}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	actx := new(atreugo.RequestCtx)
+	actx.RequestCtx = new(fasthttp.RequestCtx)
+
+	if err := admin.statsView(actx); err != nil {
+		t.Fatalf("Admin.statsView() unexpected error: %v", err)
+	}
+
+	stats := Stats{}
+	if err := json.Unmarshal(actx.Response.Body(), &stats); err != nil {
+		t.Fatalf("Admin.statsView() invalid json response: %v", err)
+	}
+
+	if !reflect.DeepEqual(stats.Proxy, proxyMock.stats) {
+		t.Errorf("Admin.statsView() proxy stats == '%v', want '%v'", stats.Proxy, proxyMock.stats)
+	}
+
+	if wantCache := admin.cache.Stats(); stats.Cache != wantCache {
+		t.Errorf("Admin.statsView() cache stats == '%v', want '%v'", stats.Cache, wantCache)
+	}
+
+	if stats.Cache.Entries != 1 {
+		t.Errorf("Admin.statsView() cache entries == '%d', want '%d'", stats.Cache.Entries, 1)
+	}
+}
+
+func TestAdmin_backendsView(t *testing.T) {
+	proxyMock := &mockProxy{
+		backends: []proxy.BackendStatus{
+			{Addr: "localhost:9990", Healthy: true, Successes: 10, LastCheck: 1700000000},
+			{Addr: "localhost:9991", Healthy: false, Failures: 3, LastCheck: 1700000000, LastError: "timeout"},
+		},
+	}
+
+	admin, err := New(testConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	admin.proxy = proxyMock
+
+	actx := new(atreugo.RequestCtx)
+	actx.RequestCtx = new(fasthttp.RequestCtx)
+
+	if err := admin.backendsView(actx); err != nil {
+		t.Fatalf("Admin.backendsView() unexpected error: %v", err)
+	}
+
+	var backends []proxy.BackendStatus
+	if err := json.Unmarshal(actx.Response.Body(), &backends); err != nil {
+		t.Fatalf("Admin.backendsView() invalid json response: %v", err)
+	}
+
+	if !reflect.DeepEqual(backends, proxyMock.backends) {
+		t.Errorf("Admin.backendsView() backends == '%v', want '%v'", backends, proxyMock.backends)
+	}
+}
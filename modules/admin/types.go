@@ -8,6 +8,8 @@ import (
 	"github.com/savsgio/kratgo/modules/cache"
 	"github.com/savsgio/kratgo/modules/config"
 	"github.com/savsgio/kratgo/modules/invalidator"
+	"github.com/savsgio/kratgo/modules/proxy"
+	"github.com/savsgio/kratgo/modules/warmer"
 )
 
 // Config ...
@@ -15,6 +17,8 @@ type Config struct {
 	FileConfig  config.Admin
 	Cache       *cache.Cache
 	Invalidator Invalidator
+	Warmer      Warmer
+	Proxy       Proxy
 
 	HTTPScheme string
 
@@ -29,12 +33,20 @@ type Admin struct {
 	server      Server
 	cache       *cache.Cache
 	invalidator Invalidator
+	warmer      Warmer
+	proxy       Proxy
 
 	httpScheme string
 
 	log *logger.Logger
 }
 
+// Stats ...
+type Stats struct {
+	Cache cache.Stats `json:"cache"`
+	Proxy proxy.Stats `json:"proxy"`
+}
+
 // ###### INTERFACES ######
 
 // Invalidator ...
@@ -43,6 +55,18 @@ type Invalidator interface {
 	Add(e invalidator.Entry) error
 }
 
+// Warmer ...
+type Warmer interface {
+	Start(job warmer.Job) error
+	Progress() warmer.Progress
+}
+
+// Proxy ...
+type Proxy interface {
+	Stats() proxy.Stats
+	Backends() []proxy.BackendStatus
+}
+
 // Server ...
 type Server interface {
 	ListenAndServe() error
@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+	logger "github.com/savsgio/go-logger/v4"
+	"github.com/savsgio/kratgo/modules/config"
+)
+
+func bigcacheConfig(cfg config.Cache) bigcache.Config {
+	return bigcache.Config{
+		Shards:             defaultBigcacheShards,
+		LifeWindow:         time.Duration(cfg.TTL) * time.Minute,
+		CleanWindow:        time.Duration(cfg.CleanFrequency) * time.Minute,
+		MaxEntriesInWindow: cfg.MaxEntries,
+		MaxEntrySize:       cfg.MaxEntrySize,
+		Verbose:            false,
+		HardMaxCacheSize:   cfg.HardMaxCacheSize,
+	}
+}
+
+func newBigcacheStorage(cfg config.Cache, log *logger.Logger, onRemove func(key string)) (*bigcacheStorage, error) {
+	bigcacheCFG := bigcacheConfig(cfg)
+	bigcacheCFG.Logger = log
+	bigcacheCFG.Verbose = log.IsLevelEnabled(logger.DEBUG)
+	bigcacheCFG.OnRemoveWithReason = func(key string, _ []byte, _ bigcache.RemoveReason) {
+		onRemove(key)
+	}
+
+	bc, err := bigcache.NewBigCache(bigcacheCFG)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &bigcacheStorage{bc: bc}
+	if cfg.HardMaxCacheSize > 0 {
+		s.maxEntrySize = cfg.HardMaxCacheSize * 1024 * 1024 / defaultBigcacheShards
+	}
+
+	return s, nil
+}
+
+func (s *bigcacheStorage) Get(key string) ([]byte, error) {
+	data, err := s.bc.Get(key)
+	if err == bigcache.ErrEntryNotFound {
+		return nil, ErrEntryNotFound
+	}
+
+	return data, err
+}
+
+// Set saves the data under the key. The ttl is ignored, since bigcache
+// expires all entries after the same life window.
+func (s *bigcacheStorage) Set(key string, data []byte, _ time.Duration) error {
+	if s.maxEntrySize > 0 && bigcacheHeadersSize+len(key)+len(data) > s.maxEntrySize {
+		return ErrEntryTooLarge
+	}
+
+	return s.bc.Set(key, data)
+}
+
+// Update never replaces the data, since bigcache restarts the life window of the saved entries.
+// Returns ErrEntryTooLarge if the data exceeds the max entry size, or ErrExpirationNotKept.
+func (s *bigcacheStorage) Update(key string, data []byte) error {
+	if s.maxEntrySize > 0 && bigcacheHeadersSize+len(key)+len(data) > s.maxEntrySize {
+		return ErrEntryTooLarge
+	}
+
+	return ErrExpirationNotKept
+}
+
+func (s *bigcacheStorage) Delete(key string) error {
+	if err := s.bc.Delete(key); err == bigcache.ErrEntryNotFound {
+		return ErrEntryNotFound
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *bigcacheStorage) Iterator() Iterator {
+	return &bigcacheIterator{iter: s.bc.Iterator()}
+}
+
+func (s *bigcacheStorage) Len() int {
+	return s.bc.Len()
+}
+
+func (s *bigcacheStorage) Capacity() int {
+	return s.bc.Capacity()
+}
+
+func (s *bigcacheStorage) Reset() error {
+	return s.bc.Reset()
+}
+
+func (s *bigcacheStorage) Stats() Stats {
+	stats := s.bc.Stats()
+
+	return Stats{
+		Hits:       stats.Hits,
+		Misses:     stats.Misses,
+		DelHits:    stats.DelHits,
+		DelMisses:  stats.DelMisses,
+		Collisions: stats.Collisions,
+	}
+}
+
+func (it *bigcacheIterator) SetNext() bool {
+	return it.iter.SetNext()
+}
+
+func (it *bigcacheIterator) Value() (string, []byte, error) {
+	v, err := it.iter.Value()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return v.Key(), v.Value(), nil
+}
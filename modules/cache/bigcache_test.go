@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_bigcacheConfig(t *testing.T) {
+	cfg := fileConfigCache()
+	bcConfig := bigcacheConfig(cfg)
+
+	if bcConfig.Shards != defaultBigcacheShards {
+		t.Errorf("bigcacheConfig() Shards == '%d', want '%d'", bcConfig.Shards, defaultBigcacheShards)
+	}
+
+	lifeWindoow := time.Duration(cfg.TTL) * time.Minute
+	if bcConfig.LifeWindow != lifeWindoow {
+		t.Errorf("bigcacheConfig() LifeWindow == '%d', want '%d'", bcConfig.LifeWindow, lifeWindoow)
+	}
+
+	cleanWindow := time.Duration(cfg.CleanFrequency) * time.Minute
+	if bcConfig.CleanWindow != cleanWindow {
+		t.Errorf("bigcacheConfig() CleanWindow == '%d', want '%d'", bcConfig.CleanWindow, cleanWindow)
+	}
+
+	maxEntriesInWindow := cfg.MaxEntries
+	if bcConfig.MaxEntriesInWindow != maxEntriesInWindow {
+		t.Errorf("bigcacheConfig() MaxEntriesInWindow == '%d', want '%d'", bcConfig.MaxEntriesInWindow, maxEntriesInWindow)
+	}
+
+	maxEntriesSize := cfg.MaxEntrySize
+	if bcConfig.MaxEntrySize != maxEntriesSize {
+		t.Errorf("bigcacheConfig() MaxEntrySize == '%d', want '%d'", bcConfig.MaxEntrySize, maxEntriesSize)
+	}
+
+	verbose := false
+	if bcConfig.Verbose != verbose {
+		t.Errorf("bigcacheConfig() Verbose == '%v', want '%v'", bcConfig.Verbose, verbose)
+	}
+
+	hardMaxCacheSize := cfg.HardMaxCacheSize
+	if bcConfig.HardMaxCacheSize != hardMaxCacheSize {
+		t.Errorf("bigcacheConfig() HardMaxCacheSize == '%d', want '%d'", bcConfig.HardMaxCacheSize, hardMaxCacheSize)
+	}
+}
+
+func TestBigcacheStorage(t *testing.T) {
+	s := testCache.storage
+
+	if err := s.Reset(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := s.Get("www.kratgo.com"); err != ErrEntryNotFound {
+		t.Errorf("bigcacheStorage.Get() error == '%v', want '%v'", err, ErrEntryNotFound)
+	}
+
+	if err := s.Delete("www.kratgo.com"); err != ErrEntryNotFound {
+		t.Errorf("bigcacheStorage.Delete() error == '%v', want '%v'", err, ErrEntryNotFound)
+	}
+
+	if err := s.Set("www.kratgo.com", []byte("data"), time.Second); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, err := s.Get("www.kratgo.com")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if string(data) != "data" {
+		t.Errorf("bigcacheStorage.Get() == '%s', want '%s'", data, "data")
+	}
+
+	if err := s.Update("www.kratgo.com", []byte("data2")); err != ErrExpirationNotKept {
+		t.Errorf("bigcacheStorage.Update() error == '%v', want '%v'", err, ErrExpirationNotKept)
+	}
+
+	if err := s.Update("www.kratgo.com", make([]byte, 2*1024*1024)); err != ErrEntryTooLarge {
+		t.Errorf("bigcacheStorage.Update() error == '%v', want '%v'", err, ErrEntryTooLarge)
+	}
+
+	iter := s.Iterator()
+	if !iter.SetNext() {
+		t.Fatal("bigcacheStorage.Iterator() has not entries")
+	}
+
+	if key, data, err := iter.Value(); err != nil || key != "www.kratgo.com" || string(data) != "data" {
+		t.Errorf("bigcacheIterator.Value() == '%s, %s, %v', want '%s, %s, %v'", key, data, err, "www.kratgo.com", "data", nil)
+	}
+}
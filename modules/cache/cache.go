@@ -2,26 +2,13 @@ package cache
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
-	"github.com/allegro/bigcache/v3"
 	logger "github.com/savsgio/go-logger/v4"
 	"github.com/savsgio/gotils/strconv"
-	"github.com/savsgio/kratgo/modules/config"
 )
 
-func bigcacheConfig(cfg config.Cache) bigcache.Config {
-	return bigcache.Config{
-		Shards:             defaultBigcacheShards,
-		LifeWindow:         time.Duration(cfg.TTL) * time.Minute,
-		CleanWindow:        time.Duration(cfg.CleanFrequency) * time.Minute,
-		MaxEntriesInWindow: cfg.MaxEntries,
-		MaxEntrySize:       cfg.MaxEntrySize,
-		Verbose:            false,
-		HardMaxCacheSize:   cfg.HardMaxCacheSize,
-	}
-}
-
 // New ...
 func New(cfg Config) (*Cache, error) {
 	if cfg.FileConfig.CleanFrequency == 0 {
@@ -30,23 +17,175 @@ func New(cfg Config) (*Cache, error) {
 
 	c := new(Cache)
 	c.fileConfig = cfg.FileConfig
+	c.hosts = make(map[string]map[string]struct{})
+	c.keys = make(map[string]string)
+	c.tags = make(map[string]map[string]struct{})
+	c.keyTags = make(map[string][]string)
 
 	log := logger.New(cfg.LogLevel, cfg.LogOutput, logger.Field{Key: "type", Value: "cache"})
+	c.log = log
 
-	bigcacheCFG := bigcacheConfig(c.fileConfig)
-	bigcacheCFG.Logger = log
-	bigcacheCFG.Verbose = cfg.LogLevel == logger.DEBUG
+	switch c.fileConfig.Storage {
+	case "", BigcacheStorage:
+		storage, err := newBigcacheStorage(c.fileConfig, log, c.onRemove)
+		if err != nil {
+			return nil, fmt.Errorf("Could not create the cache storage: %v", err)
+		}
+
+		c.storage = storage
+	case LRUStorage:
+		c.storage = newLRUStorage(c.fileConfig, c.onRemove)
+	default:
+		return nil, fmt.Errorf("Invalid Cache.Storage configuration '%s'", c.fileConfig.Storage)
+	}
 
-	c.bc, _ = bigcache.NewBigCache(bigcacheCFG)
+	if c.fileConfig.Snapshot.Dir != "" && c.fileConfig.Snapshot.Interval > 0 {
+		go c.snapshotter(c.fileConfig.Snapshot.Interval)
+	}
 
 	return c, nil
 }
 
-// Set ...
+// indexWithoutLock indexes the key by the host and the tags, replacing its previous index.
+func (c *Cache) indexWithoutLock(key, host string, tags []string) {
+	if _, ok := c.keys[key]; ok {
+		c.unindexWithoutLock(key)
+	}
+
+	keys, ok := c.hosts[host]
+	if !ok {
+		keys = make(map[string]struct{})
+		c.hosts[host] = keys
+	}
+
+	keys[key] = struct{}{}
+	c.keys[key] = host
+
+	for _, tag := range tags {
+		tagKeys, ok := c.tags[tag]
+		if !ok {
+			tagKeys = make(map[string]struct{})
+			c.tags[tag] = tagKeys
+		}
+
+		tagKeys[key] = struct{}{}
+	}
+
+	if len(tags) > 0 {
+		c.keyTags[key] = tags
+	}
+}
+
+// index indexes the key by the host and the tags, and returns the previous index of the key,
+// so it could be restored.
+func (c *Cache) index(key, host string, tags []string) (string, []string, bool) {
+	c.mu.Lock()
+
+	prevHost, indexed := c.keys[key]
+	prevTags := c.keyTags[key]
+
+	c.indexWithoutLock(key, host, tags)
+
+	c.mu.Unlock()
+
+	return prevHost, prevTags, indexed
+}
+
+func (c *Cache) unindexWithoutLock(key string) {
+	host, ok := c.keys[key]
+	if !ok {
+		return
+	}
+
+	delete(c.keys, key)
+
+	if keys := c.hosts[host]; keys != nil {
+		delete(keys, key)
+
+		if len(keys) == 0 {
+			delete(c.hosts, host)
+		}
+	}
+
+	for _, tag := range c.keyTags[key] {
+		if keys := c.tags[tag]; keys != nil {
+			delete(keys, key)
+
+			if len(keys) == 0 {
+				delete(c.tags, tag)
+			}
+		}
+	}
+
+	delete(c.keyTags, key)
+}
+
+func (c *Cache) unindex(key string) {
+	c.mu.Lock()
+	c.unindexWithoutLock(key)
+	c.mu.Unlock()
+}
+
+// onRemove keeps the host and tag indexes in sync when the storage removes a key,
+// either explicitly, by expiration or to make room for new entries.
+func (c *Cache) onRemove(key string) {
+	c.unindex(key)
+}
+
+// Set saves the entry under the given key and indexes the key by the host and the cache tags of its responses.
+// If the entry could not be saved, the previous index of the key is restored.
 func (c *Cache) Set(key string, entry Entry) error {
 	data, _ := Marshal(entry)
 
-	return c.bc.Set(key, data)
+	ttl := time.Duration(0)
+	if expiresAt := entry.expiresAt(); expiresAt > 0 {
+		ttl = time.Until(time.Unix(expiresAt, 0))
+		if ttl < time.Second {
+			ttl = time.Second
+		}
+	}
+
+	// The key is indexed before saving it, so the removal of the saved key is never followed
+	// by a late index of a key which is not stored anymore
+	indexKey := strings.Clone(key)
+	prevHost, prevTags, indexed := c.index(indexKey, string(entry.host()), entry.tags())
+
+	if err := c.storage.Set(key, data, ttl); err != nil {
+		c.mu.Lock()
+
+		c.unindexWithoutLock(indexKey)
+		if indexed {
+			c.indexWithoutLock(indexKey, prevHost, prevTags)
+		}
+
+		c.mu.Unlock()
+
+		return err
+	}
+
+	return nil
+}
+
+// Update replaces the entry saved under the key keeping its expiration, so the entry is never
+// stored for longer than it would have been. Returns ErrEntryNotFound if the key has been removed,
+// or ErrEntryTooLarge if the entry exceeds the max cache size.
+//
+// The storages which could not keep the expiration only save the entries whose responses
+// have their own lifetime, otherwise ErrExpirationNotKept is returned.
+func (c *Cache) Update(key string, entry Entry) error {
+	data, _ := Marshal(entry)
+
+	err := c.storage.Update(key, data)
+	if err == ErrExpirationNotKept && entry.expiresAt() > 0 {
+		return c.Set(key, entry)
+	}
+
+	return err
+}
+
+// UpdateBytes ...
+func (c *Cache) UpdateBytes(key []byte, entry Entry) error {
+	return c.Update(strconv.B2S(key), entry)
 }
 
 // SetBytes ...
@@ -56,8 +195,8 @@ func (c *Cache) SetBytes(key []byte, entry Entry) error {
 
 // Get ...
 func (c *Cache) Get(key string, dst *Entry) error {
-	data, err := c.bc.Get(key)
-	if err == bigcache.ErrEntryNotFound {
+	data, err := c.storage.Get(key)
+	if err == ErrEntryNotFound {
 		return nil
 	} else if err != nil {
 		return err
@@ -73,7 +212,9 @@ func (c *Cache) GetBytes(key []byte, dst *Entry) error {
 
 // Del ...
 func (c *Cache) Del(key string) error {
-	return c.bc.Delete(key)
+	c.unindex(key)
+
+	return c.storage.Delete(key)
 }
 
 // DelBytes ...
@@ -81,17 +222,71 @@ func (c *Cache) DelBytes(key []byte) error {
 	return c.Del(strconv.B2S(key))
 }
 
+// HostKeys returns the keys of all cached entries of the given host.
+func (c *Cache) HostKeys(host string) []string {
+	c.mu.RLock()
+
+	keys := make([]string, 0, len(c.hosts[host]))
+	for k := range c.hosts[host] {
+		keys = append(keys, k)
+	}
+
+	c.mu.RUnlock()
+
+	return keys
+}
+
+// HostKeysBytes ...
+func (c *Cache) HostKeysBytes(host []byte) []string {
+	return c.HostKeys(strconv.B2S(host))
+}
+
+// TagKeys returns the keys of all cached entries with a response tagged with the given tag.
+func (c *Cache) TagKeys(tag string) []string {
+	c.mu.RLock()
+
+	keys := make([]string, 0, len(c.tags[tag]))
+	for k := range c.tags[tag] {
+		keys = append(keys, k)
+	}
+
+	c.mu.RUnlock()
+
+	return keys
+}
+
+// TagKeysBytes ...
+func (c *Cache) TagKeysBytes(tag []byte) []string {
+	return c.TagKeys(strconv.B2S(tag))
+}
+
 // Iterator ...
-func (c *Cache) Iterator() *bigcache.EntryInfoIterator {
-	return c.bc.Iterator()
+func (c *Cache) Iterator() Iterator {
+	return c.storage.Iterator()
 }
 
 // Len ...
 func (c *Cache) Len() int {
-	return c.bc.Len()
+	return c.storage.Len()
+}
+
+// Stats ...
+func (c *Cache) Stats() Stats {
+	stats := c.storage.Stats()
+	stats.Entries = c.storage.Len()
+	stats.Capacity = c.storage.Capacity()
+
+	return stats
 }
 
 // Reset ...
 func (c *Cache) Reset() error {
-	return c.bc.Reset()
+	c.mu.Lock()
+	c.hosts = make(map[string]map[string]struct{})
+	c.keys = make(map[string]string)
+	c.tags = make(map[string]map[string]struct{})
+	c.keyTags = make(map[string][]string)
+	c.mu.Unlock()
+
+	return c.storage.Reset()
 }
@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"reflect"
 	"testing"
@@ -35,45 +37,6 @@ func fileConfigCache() config.Cache {
 	}
 }
 
-func Test_bigcacheConfig(t *testing.T) {
-	cfg := fileConfigCache()
-	bcConfig := bigcacheConfig(cfg)
-
-	if bcConfig.Shards != defaultBigcacheShards {
-		t.Errorf("bigcacheConfig() Shards == '%d', want '%d'", bcConfig.Shards, defaultBigcacheShards)
-	}
-
-	lifeWindoow := time.Duration(cfg.TTL) * time.Minute
-	if bcConfig.LifeWindow != lifeWindoow {
-		t.Errorf("bigcacheConfig() LifeWindow == '%d', want '%d'", bcConfig.LifeWindow, lifeWindoow)
-	}
-
-	cleanWindow := time.Duration(cfg.CleanFrequency) * time.Minute
-	if bcConfig.CleanWindow != cleanWindow {
-		t.Errorf("bigcacheConfig() CleanWindow == '%d', want '%d'", bcConfig.CleanWindow, cleanWindow)
-	}
-
-	maxEntriesInWindow := cfg.MaxEntries
-	if bcConfig.MaxEntriesInWindow != maxEntriesInWindow {
-		t.Errorf("bigcacheConfig() MaxEntriesInWindow == '%d', want '%d'", bcConfig.MaxEntriesInWindow, maxEntriesInWindow)
-	}
-
-	maxEntriesSize := cfg.MaxEntrySize
-	if bcConfig.MaxEntrySize != maxEntriesSize {
-		t.Errorf("bigcacheConfig() MaxEntrySize == '%d', want '%d'", bcConfig.MaxEntrySize, maxEntriesSize)
-	}
-
-	verbose := false
-	if bcConfig.Verbose != verbose {
-		t.Errorf("bigcacheConfig() Verbose == '%v', want '%v'", bcConfig.Verbose, verbose)
-	}
-
-	hardMaxCacheSize := cfg.HardMaxCacheSize
-	if bcConfig.HardMaxCacheSize != hardMaxCacheSize {
-		t.Errorf("bigcacheConfig() HardMaxCacheSize == '%d', want '%d'", bcConfig.HardMaxCacheSize, hardMaxCacheSize)
-	}
-}
-
 func TestNew(t *testing.T) {
 	type args struct {
 		cfg Config
@@ -107,6 +70,41 @@ func TestNew(t *testing.T) {
 				err: false,
 			},
 		},
+		{
+			name: "LRUStorage",
+			args: args{
+				cfg: Config{
+					FileConfig: config.Cache{
+						Storage:          LRUStorage,
+						TTL:              1,
+						CleanFrequency:   1,
+						HardMaxCacheSize: 10,
+					},
+					LogLevel:  logger.FATAL,
+					LogOutput: os.Stderr,
+				},
+			},
+			want: want{
+				err: false,
+			},
+		},
+		{
+			name: "InvalidStorage",
+			args: args{
+				cfg: Config{
+					FileConfig: config.Cache{
+						Storage:        "memcached",
+						TTL:            1,
+						CleanFrequency: 1,
+					},
+					LogLevel:  logger.FATAL,
+					LogOutput: os.Stderr,
+				},
+			},
+			want: want{
+				err: true,
+			},
+		},
 		{
 			name: "InvalidCleanFrequency",
 			args: args{
@@ -143,8 +141,8 @@ func TestNew(t *testing.T) {
 				t.Errorf("New() fileConfig == '%v', want '%v'", c.fileConfig, tt.args.cfg.FileConfig)
 			}
 
-			if c.bc == nil {
-				t.Errorf("New() bc is '%v'", nil)
+			if c.storage == nil {
+				t.Errorf("New() storage is '%v'", nil)
 			}
 		})
 	}
@@ -187,6 +185,52 @@ func TestCache_SetAndGetAndDel(t *testing.T) {
 	}
 }
 
+func TestCache_Update(t *testing.T) {
+	k := "www.kratgo.com/update/"
+	entry := AcquireEntry()
+
+	if err := testCache.Set(k, getEntryTest()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The responses without their own lifetime expire after the bigcache life window, which is not kept
+	e := getEntryTest()
+	e.Responses[0].SetEncodedBody([]byte("gzip"), []byte("Encoded body"))
+
+	if err := testCache.Update(k, e); err != ErrExpirationNotKept {
+		t.Errorf("Cache.Update() error == '%v', want '%v'", err, ErrExpirationNotKept)
+	}
+
+	if err := testCache.Get(k, entry); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if reflect.DeepEqual(e, *entry) {
+		t.Errorf("Cache.Update() the entry has been saved without keeping its expiration")
+	}
+
+	entry.Reset()
+
+	for i := range e.Responses {
+		e.Responses[i].StoredAt = time.Now().Unix()
+		e.Responses[i].TTL = 60
+	}
+
+	if err := testCache.Update(k, e); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := testCache.Get(k, entry); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(e, *entry) {
+		t.Errorf("Cache.Update() the entry with its own lifetime has not been saved")
+	}
+
+	testCache.Del(k)
+}
+
 func TestCache_SetAndGetAndDel_Bytes(t *testing.T) {
 	e := getEntryTest()
 	entry := AcquireEntry()
@@ -224,6 +268,201 @@ func TestCache_SetAndGetAndDel_Bytes(t *testing.T) {
 	}
 }
 
+func TestCache_HostKeys(t *testing.T) {
+	testCache.Reset()
+
+	host := "www.kratgo.com"
+	keys := []string{host + "/fast/", host + "/cache/"}
+
+	for _, k := range keys {
+		e := Entry{Responses: []Response{{Host: []byte(host), Path: []byte(k[len(host):])}}}
+
+		if err := testCache.Set(k, e); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	otherHost := "www.other.com"
+	if err := testCache.Set(otherHost+"/", Entry{Responses: []Response{{Host: []byte(otherHost)}}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	hostKeys := testCache.HostKeys(host)
+	if len(hostKeys) != len(keys) {
+		t.Fatalf("Cache.HostKeys() == '%v', want '%v'", hostKeys, keys)
+	}
+
+	for _, k := range keys {
+		if !stringSliceInclude(hostKeys, k) {
+			t.Errorf("Cache.HostKeys() key '%s' not found in '%v'", k, hostKeys)
+		}
+	}
+
+	if err := testCache.Del(keys[0]); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	hostKeys = testCache.HostKeysBytes([]byte(host))
+	if len(hostKeys) != 1 || hostKeys[0] != keys[1] {
+		t.Errorf("Cache.HostKeys() == '%v', want '%v'", hostKeys, keys[1:])
+	}
+
+	testCache.Reset()
+
+	if hostKeys = testCache.HostKeys(host); len(hostKeys) != 0 {
+		t.Errorf("Cache.HostKeys() == '%v', want '%v'", hostKeys, []string{})
+	}
+}
+
+func TestCache_TagKeys(t *testing.T) {
+	testCache.Reset()
+
+	host := "www.kratgo.com"
+	tag := "product-42"
+	keys := []string{host + "/product/", host + "/products/"}
+
+	for _, k := range keys {
+		e := Entry{Responses: []Response{{Host: []byte(host), Tags: [][]byte{[]byte(tag)}}}}
+
+		if err := testCache.Set(k, e); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if err := testCache.Set(host+"/", Entry{Responses: []Response{{Host: []byte(host)}}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tagKeys := testCache.TagKeys(tag)
+	if len(tagKeys) != len(keys) {
+		t.Fatalf("Cache.TagKeys() == '%v', want '%v'", tagKeys, keys)
+	}
+
+	for _, k := range keys {
+		if !stringSliceInclude(tagKeys, k) {
+			t.Errorf("Cache.TagKeys() key '%s' not found in '%v'", k, tagKeys)
+		}
+	}
+
+	// Replace the entry with an untagged one
+	if err := testCache.Set(keys[0], Entry{Responses: []Response{{Host: []byte(host)}}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tagKeys = testCache.TagKeysBytes([]byte(tag))
+	if len(tagKeys) != 1 || tagKeys[0] != keys[1] {
+		t.Errorf("Cache.TagKeys() == '%v', want '%v'", tagKeys, keys[1:])
+	}
+
+	if err := testCache.Del(keys[1]); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if tagKeys = testCache.TagKeys(tag); len(tagKeys) != 0 {
+		t.Errorf("Cache.TagKeys() == '%v', want '%v'", tagKeys, []string{})
+	}
+
+	if hostKeys := testCache.HostKeys(host); len(hostKeys) != 2 {
+		t.Errorf("Cache.HostKeys() == '%v', want '%d' keys", hostKeys, 2)
+	}
+
+	testCache.Reset()
+}
+
+// evictingStorage removes the keys right after saving them, as if they had been evicted.
+type evictingStorage struct {
+	*lruStorage
+}
+
+func (s evictingStorage) Set(key string, data []byte, ttl time.Duration) error {
+	if err := s.lruStorage.Set(key, data, ttl); err != nil {
+		return err
+	}
+
+	return s.lruStorage.Delete(key)
+}
+
+func newTestIndexCache() *Cache {
+	c := new(Cache)
+	c.hosts = make(map[string]map[string]struct{})
+	c.keys = make(map[string]string)
+	c.tags = make(map[string]map[string]struct{})
+	c.keyTags = make(map[string][]string)
+
+	return c
+}
+
+func TestCache_SetIndex(t *testing.T) {
+	host := "www.kratgo.com"
+	k := host + "/product/"
+
+	c := newTestIndexCache()
+	c.storage = evictingStorage{newLRUStorage(fileConfigCache(), c.onRemove)}
+
+	if err := c.Set(k, Entry{Responses: []Response{{Host: []byte(host), Tags: [][]byte{[]byte("product")}}}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if keys := c.HostKeys(host); len(keys) != 0 {
+		t.Errorf("Cache.HostKeys() evicted keys == '%v', want '%v'", keys, []string{})
+	}
+
+	if keys := c.TagKeys("product"); len(keys) != 0 {
+		t.Errorf("Cache.TagKeys() evicted keys == '%v', want '%v'", keys, []string{})
+	}
+
+	// The previous index is restored if the entry could not be saved
+	c = newTestIndexCache()
+	storage := newLRUStorage(fileConfigCache(), c.onRemove)
+	storage.maxSize = 1024
+	c.storage = storage
+
+	if err := c.Set(k, Entry{Responses: []Response{{Host: []byte(host), Tags: [][]byte{[]byte("product")}}}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tooLarge := Entry{Responses: []Response{{Host: []byte(host), Body: make([]byte, 1024), Tags: [][]byte{[]byte("other")}}}}
+	if err := c.Set(k, tooLarge); err != ErrEntryTooLarge {
+		t.Fatalf("Cache.Set() error == '%v', want '%v'", err, ErrEntryTooLarge)
+	}
+
+	if keys := c.TagKeys("product"); len(keys) != 1 || keys[0] != k {
+		t.Errorf("Cache.TagKeys() == '%v', want '%v'", keys, []string{k})
+	}
+
+	if keys := c.TagKeys("other"); len(keys) != 0 {
+		t.Errorf("Cache.TagKeys() unsaved keys == '%v', want '%v'", keys, []string{})
+	}
+
+	if err := c.Set(host+"/other/", tooLarge); err != ErrEntryTooLarge {
+		t.Fatalf("Cache.Set() error == '%v', want '%v'", err, ErrEntryTooLarge)
+	}
+
+	if keys := c.HostKeys(host); len(keys) != 1 || keys[0] != k {
+		t.Errorf("Cache.HostKeys() == '%v', want '%v'", keys, []string{k})
+	}
+}
+
+func TestCache_onRemove(t *testing.T) {
+	testCache.Reset()
+
+	host := "www.kratgo.com"
+	k := host + "/fast/"
+
+	if err := testCache.Set(k, Entry{Responses: []Response{{Host: []byte(host)}}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Delete directly from the storage to simulate an eviction
+	if err := testCache.storage.Delete(k); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if hostKeys := testCache.HostKeys(host); len(hostKeys) != 0 {
+		t.Errorf("Cache.onRemove() host keys == '%v', want '%v'", hostKeys, []string{})
+	}
+}
+
 func TestCache_Iterator(t *testing.T) {
 	e := getEntryTest()
 
@@ -236,11 +475,29 @@ func TestCache_Iterator(t *testing.T) {
 
 	iter := testCache.Iterator()
 	if iter == nil {
-		t.Errorf("Could not get iterator from cache")
+		t.Fatal("Could not get iterator from cache")
+	}
+
+	found := false
+	for iter.SetNext() {
+		key, _, err := iter.Value()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if key == k {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("Cache.Iterator() the key '%s' has not been iterated", k)
 	}
 }
 
 func TestCache_Len(t *testing.T) {
+	testCache.Reset()
+
 	e := getEntryTest()
 
 	k := "www.kratgo.com"
@@ -257,6 +514,37 @@ func TestCache_Len(t *testing.T) {
 	}
 }
 
+func TestCache_Stats(t *testing.T) {
+	testCache.Reset()
+
+	entry := AcquireEntry()
+	before := testCache.Stats()
+
+	if err := testCache.Set("www.kratgo.com", getEntryTest()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	testCache.Get("www.kratgo.com", entry)
+	testCache.Get("www.kratgo.com/miss/", entry)
+
+	stats := testCache.Stats()
+	if stats.Hits != before.Hits+1 {
+		t.Errorf("Cache.Stats() hits == '%d', want '%d'", stats.Hits, before.Hits+1)
+	}
+
+	if stats.Misses != before.Misses+1 {
+		t.Errorf("Cache.Stats() misses == '%d', want '%d'", stats.Misses, before.Misses+1)
+	}
+
+	if stats.Entries != 1 {
+		t.Errorf("Cache.Stats() entries == '%d', want '%d'", stats.Entries, 1)
+	}
+
+	if stats.Capacity <= 0 {
+		t.Errorf("Cache.Stats() capacity == '%d', want greater than 0", stats.Capacity)
+	}
+}
+
 func TestCache_Reset(t *testing.T) {
 	e := getEntryTest()
 
@@ -275,3 +563,149 @@ func TestCache_Reset(t *testing.T) {
 		t.Errorf("Cache.Len() == '%d', want '%d'", length, wantLength)
 	}
 }
+
+func stringSliceInclude(vs []string, t string) bool {
+	for _, v := range vs {
+		if v == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+func benchmarkCache(b *testing.B) (*Cache, string, [][]byte, []byte) {
+	c, err := New(Config{
+		FileConfig: config.Cache{
+			TTL:            10,
+			CleanFrequency: 5,
+			MaxEntries:     1000,
+			MaxEntrySize:   500,
+		},
+		LogLevel:  logger.ERROR,
+		LogOutput: os.Stderr,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	host := "www.kratgo.com"
+	paths := make([][]byte, 200)
+	for i := range paths {
+		paths[i] = []byte(fmt.Sprintf("/page/%d/", i))
+	}
+
+	return c, host, paths, bytes.Repeat([]byte("k"), 512)
+}
+
+// BenchmarkCache_GetHostLayout stores every page of the host in a single entry,
+// so each hit decodes all the pages of the host.
+func BenchmarkCache_GetHostLayout(b *testing.B) {
+	c, host, paths, body := benchmarkCache(b)
+
+	entry := Entry{}
+	for _, path := range paths {
+		entry.SetResponse(Response{Host: []byte(host), Path: path, Body: body})
+	}
+
+	if err := c.Set(host, entry); err != nil {
+		b.Fatal(err)
+	}
+
+	dst := AcquireEntry()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		path := paths[i%len(paths)]
+
+		if err := c.Get(host, dst); err != nil {
+			b.Fatal(err)
+		}
+
+		if r := dst.GetResponse(path); r == nil {
+			b.Fatalf("Path '%s' not found", path)
+		}
+
+		dst.Reset()
+	}
+}
+
+// BenchmarkCache_GetURLLayout stores every page of the host under its own key.
+func BenchmarkCache_GetURLLayout(b *testing.B) {
+	c, host, paths, body := benchmarkCache(b)
+
+	keys := make([]string, len(paths))
+	for i, path := range paths {
+		keys[i] = host + string(path)
+
+		entry := Entry{Responses: []Response{{Host: []byte(host), Path: path, Body: body}}}
+		if err := c.Set(keys[i], entry); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	dst := AcquireEntry()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n := i % len(paths)
+
+		if err := c.Get(keys[n], dst); err != nil {
+			b.Fatal(err)
+		}
+
+		if r := dst.GetResponse(paths[n]); r == nil {
+			b.Fatalf("Path '%s' not found", paths[n])
+		}
+
+		dst.Reset()
+	}
+}
+
+// BenchmarkCache_SetHostLayout adds a page to the entry of the host,
+// so each miss re-encodes and rewrites all the pages of the host.
+func BenchmarkCache_SetHostLayout(b *testing.B) {
+	c, host, paths, body := benchmarkCache(b)
+
+	entry := AcquireEntry()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := c.Get(host, entry); err != nil {
+			b.Fatal(err)
+		}
+
+		entry.SetResponse(Response{Host: []byte(host), Path: paths[i%len(paths)], Body: body})
+
+		if err := c.Set(host, *entry); err != nil {
+			b.Fatal(err)
+		}
+
+		entry.Reset()
+	}
+}
+
+// BenchmarkCache_SetURLLayout saves each page under its own key.
+func BenchmarkCache_SetURLLayout(b *testing.B) {
+	c, host, paths, body := benchmarkCache(b)
+
+	keys := make([]string, len(paths))
+	for i, path := range paths {
+		keys[i] = host + string(path)
+	}
+
+	entry := AcquireEntry()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n := i % len(paths)
+
+		entry.SetResponse(Response{Host: []byte(host), Path: paths[n], Body: body})
+
+		if err := c.Set(keys[n], *entry); err != nil {
+			b.Fatal(err)
+		}
+
+		entry.Reset()
+	}
+}
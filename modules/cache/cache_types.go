@@ -8,14 +8,34 @@ type ResponseHeader struct {
 	Value []byte
 }
 
+// EncodedBody ...
+type EncodedBody struct {
+	Encoding []byte
+	Body     []byte
+}
+
 // Response ...
 type Response struct {
+	Host    []byte
 	Path    []byte
 	Body    []byte
 	Headers []ResponseHeader
+	Vary    []ResponseHeader // Request headers, and their normalized values, which select this variant
+	Encoded []EncodedBody    // Compressed versions of the body
+	Tags    [][]byte         // Cache tags, used to invalidate the response
+
+	StatusCode int // Status code of the backend response, 0 if saved when only 200 OK responses were cached
+
+	StoredAt int64 // Unix time in seconds when the response was saved
+	TTL      int64 // Freshness lifetime in seconds, 0 if the response has not an explicit lifetime
+	Grace    int64 // Seconds after the expiration in which the response is served while it's revalidated
+	Keep     int64 // Seconds after the expiration in which the response is served if the backend fails
+	Retain   int64 // Seconds after the expiration in which the response is kept to be revalidated with the backend
+
+	Revalidate bool // The response must be revalidated with the backend before each use (Cache-Control: no-cache)
 }
 
-//Entry ...
+// Entry ...
 type Entry struct {
 	Responses []Response
 }
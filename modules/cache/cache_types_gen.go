@@ -6,6 +6,134 @@ import (
 	"github.com/tinylib/msgp/msgp"
 )
 
+// DecodeMsg implements msgp.Decodable
+func (z *EncodedBody) DecodeMsg(dc *msgp.Reader) (err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, err = dc.ReadMapHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, err = dc.ReadMapKeyPtr()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "Encoding":
+			z.Encoding, err = dc.ReadBytes(z.Encoding)
+			if err != nil {
+				err = msgp.WrapError(err, "Encoding")
+				return
+			}
+		case "Body":
+			z.Body, err = dc.ReadBytes(z.Body)
+			if err != nil {
+				err = msgp.WrapError(err, "Body")
+				return
+			}
+		default:
+			err = dc.Skip()
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *EncodedBody) EncodeMsg(en *msgp.Writer) (err error) {
+	// map header, size 2
+	// write "Encoding"
+	err = en.Append(0x82, 0xa8, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67)
+	if err != nil {
+		return
+	}
+	err = en.WriteBytes(z.Encoding)
+	if err != nil {
+		err = msgp.WrapError(err, "Encoding")
+		return
+	}
+	// write "Body"
+	err = en.Append(0xa4, 0x42, 0x6f, 0x64, 0x79)
+	if err != nil {
+		return
+	}
+	err = en.WriteBytes(z.Body)
+	if err != nil {
+		err = msgp.WrapError(err, "Body")
+		return
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *EncodedBody) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// map header, size 2
+	// string "Encoding"
+	o = append(o, 0x82, 0xa8, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67)
+	o = msgp.AppendBytes(o, z.Encoding)
+	// string "Body"
+	o = append(o, 0xa4, 0x42, 0x6f, 0x64, 0x79)
+	o = msgp.AppendBytes(o, z.Body)
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *EncodedBody) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "Encoding":
+			z.Encoding, bts, err = msgp.ReadBytesBytes(bts, z.Encoding)
+			if err != nil {
+				err = msgp.WrapError(err, "Encoding")
+				return
+			}
+		case "Body":
+			z.Body, bts, err = msgp.ReadBytesBytes(bts, z.Body)
+			if err != nil {
+				err = msgp.WrapError(err, "Body")
+				return
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *EncodedBody) Msgsize() (s int) {
+	s = 1 + 9 + msgp.BytesPrefixSize + len(z.Encoding) + 5 + msgp.BytesPrefixSize + len(z.Body)
+	return
+}
+
 // DecodeMsg implements msgp.Decodable
 func (z *Entry) DecodeMsg(dc *msgp.Reader) (err error) {
 	var field []byte
@@ -170,6 +298,12 @@ func (z *Response) DecodeMsg(dc *msgp.Reader) (err error) {
 			return
 		}
 		switch msgp.UnsafeString(field) {
+		case "Host":
+			z.Host, err = dc.ReadBytes(z.Host)
+			if err != nil {
+				err = msgp.WrapError(err, "Host")
+				return
+			}
 		case "Path":
 			z.Path, err = dc.ReadBytes(z.Path)
 			if err != nil {
@@ -230,6 +364,163 @@ func (z *Response) DecodeMsg(dc *msgp.Reader) (err error) {
 					}
 				}
 			}
+		case "Vary":
+			var zb0004 uint32
+			zb0004, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Vary")
+				return
+			}
+			if cap(z.Vary) >= int(zb0004) {
+				z.Vary = (z.Vary)[:zb0004]
+			} else {
+				z.Vary = make([]ResponseHeader, zb0004)
+			}
+			for za0002 := range z.Vary {
+				var zb0005 uint32
+				zb0005, err = dc.ReadMapHeader()
+				if err != nil {
+					err = msgp.WrapError(err, "Vary", za0002)
+					return
+				}
+				for zb0005 > 0 {
+					zb0005--
+					field, err = dc.ReadMapKeyPtr()
+					if err != nil {
+						err = msgp.WrapError(err, "Vary", za0002)
+						return
+					}
+					switch msgp.UnsafeString(field) {
+					case "Key":
+						z.Vary[za0002].Key, err = dc.ReadBytes(z.Vary[za0002].Key)
+						if err != nil {
+							err = msgp.WrapError(err, "Vary", za0002, "Key")
+							return
+						}
+					case "Value":
+						z.Vary[za0002].Value, err = dc.ReadBytes(z.Vary[za0002].Value)
+						if err != nil {
+							err = msgp.WrapError(err, "Vary", za0002, "Value")
+							return
+						}
+					default:
+						err = dc.Skip()
+						if err != nil {
+							err = msgp.WrapError(err, "Vary", za0002)
+							return
+						}
+					}
+				}
+			}
+		case "Encoded":
+			var zb0006 uint32
+			zb0006, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Encoded")
+				return
+			}
+			if cap(z.Encoded) >= int(zb0006) {
+				z.Encoded = (z.Encoded)[:zb0006]
+			} else {
+				z.Encoded = make([]EncodedBody, zb0006)
+			}
+			for za0003 := range z.Encoded {
+				var zb0007 uint32
+				zb0007, err = dc.ReadMapHeader()
+				if err != nil {
+					err = msgp.WrapError(err, "Encoded", za0003)
+					return
+				}
+				for zb0007 > 0 {
+					zb0007--
+					field, err = dc.ReadMapKeyPtr()
+					if err != nil {
+						err = msgp.WrapError(err, "Encoded", za0003)
+						return
+					}
+					switch msgp.UnsafeString(field) {
+					case "Encoding":
+						z.Encoded[za0003].Encoding, err = dc.ReadBytes(z.Encoded[za0003].Encoding)
+						if err != nil {
+							err = msgp.WrapError(err, "Encoded", za0003, "Encoding")
+							return
+						}
+					case "Body":
+						z.Encoded[za0003].Body, err = dc.ReadBytes(z.Encoded[za0003].Body)
+						if err != nil {
+							err = msgp.WrapError(err, "Encoded", za0003, "Body")
+							return
+						}
+					default:
+						err = dc.Skip()
+						if err != nil {
+							err = msgp.WrapError(err, "Encoded", za0003)
+							return
+						}
+					}
+				}
+			}
+		case "Tags":
+			var zb0008 uint32
+			zb0008, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Tags")
+				return
+			}
+			if cap(z.Tags) >= int(zb0008) {
+				z.Tags = (z.Tags)[:zb0008]
+			} else {
+				z.Tags = make([][]byte, zb0008)
+			}
+			for za0004 := range z.Tags {
+				z.Tags[za0004], err = dc.ReadBytes(z.Tags[za0004])
+				if err != nil {
+					err = msgp.WrapError(err, "Tags", za0004)
+					return
+				}
+			}
+		case "StatusCode":
+			z.StatusCode, err = dc.ReadInt()
+			if err != nil {
+				err = msgp.WrapError(err, "StatusCode")
+				return
+			}
+		case "StoredAt":
+			z.StoredAt, err = dc.ReadInt64()
+			if err != nil {
+				err = msgp.WrapError(err, "StoredAt")
+				return
+			}
+		case "TTL":
+			z.TTL, err = dc.ReadInt64()
+			if err != nil {
+				err = msgp.WrapError(err, "TTL")
+				return
+			}
+		case "Grace":
+			z.Grace, err = dc.ReadInt64()
+			if err != nil {
+				err = msgp.WrapError(err, "Grace")
+				return
+			}
+		case "Keep":
+			z.Keep, err = dc.ReadInt64()
+			if err != nil {
+				err = msgp.WrapError(err, "Keep")
+				return
+			}
+		case "Retain":
+			z.Retain, err = dc.ReadInt64()
+			if err != nil {
+				err = msgp.WrapError(err, "Retain")
+				return
+			}
+		case "Revalidate":
+			z.Revalidate, err = dc.ReadBool()
+			if err != nil {
+				err = msgp.WrapError(err, "Revalidate")
+				return
+			}
 		default:
 			err = dc.Skip()
 			if err != nil {
@@ -243,9 +534,19 @@ func (z *Response) DecodeMsg(dc *msgp.Reader) (err error) {
 
 // EncodeMsg implements msgp.Encodable
 func (z *Response) EncodeMsg(en *msgp.Writer) (err error) {
-	// map header, size 3
+	// map header, size 14
+	// write "Host"
+	err = en.Append(0x8e, 0xa4, 0x48, 0x6f, 0x73, 0x74)
+	if err != nil {
+		return
+	}
+	err = en.WriteBytes(z.Host)
+	if err != nil {
+		err = msgp.WrapError(err, "Host")
+		return
+	}
 	// write "Path"
-	err = en.Append(0x83, 0xa4, 0x50, 0x61, 0x74, 0x68)
+	err = en.Append(0xa4, 0x50, 0x61, 0x74, 0x68)
 	if err != nil {
 		return
 	}
@@ -297,15 +598,171 @@ func (z *Response) EncodeMsg(en *msgp.Writer) (err error) {
 			return
 		}
 	}
+	// write "Vary"
+	err = en.Append(0xa4, 0x56, 0x61, 0x72, 0x79)
+	if err != nil {
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.Vary)))
+	if err != nil {
+		err = msgp.WrapError(err, "Vary")
+		return
+	}
+	for za0002 := range z.Vary {
+		// map header, size 2
+		// write "Key"
+		err = en.Append(0x82, 0xa3, 0x4b, 0x65, 0x79)
+		if err != nil {
+			return
+		}
+		err = en.WriteBytes(z.Vary[za0002].Key)
+		if err != nil {
+			err = msgp.WrapError(err, "Vary", za0002, "Key")
+			return
+		}
+		// write "Value"
+		err = en.Append(0xa5, 0x56, 0x61, 0x6c, 0x75, 0x65)
+		if err != nil {
+			return
+		}
+		err = en.WriteBytes(z.Vary[za0002].Value)
+		if err != nil {
+			err = msgp.WrapError(err, "Vary", za0002, "Value")
+			return
+		}
+	}
+	// write "Encoded"
+	err = en.Append(0xa7, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x65, 0x64)
+	if err != nil {
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.Encoded)))
+	if err != nil {
+		err = msgp.WrapError(err, "Encoded")
+		return
+	}
+	for za0003 := range z.Encoded {
+		// map header, size 2
+		// write "Encoding"
+		err = en.Append(0x82, 0xa8, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67)
+		if err != nil {
+			return
+		}
+		err = en.WriteBytes(z.Encoded[za0003].Encoding)
+		if err != nil {
+			err = msgp.WrapError(err, "Encoded", za0003, "Encoding")
+			return
+		}
+		// write "Body"
+		err = en.Append(0xa4, 0x42, 0x6f, 0x64, 0x79)
+		if err != nil {
+			return
+		}
+		err = en.WriteBytes(z.Encoded[za0003].Body)
+		if err != nil {
+			err = msgp.WrapError(err, "Encoded", za0003, "Body")
+			return
+		}
+	}
+	// write "Tags"
+	err = en.Append(0xa4, 0x54, 0x61, 0x67, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.Tags)))
+	if err != nil {
+		err = msgp.WrapError(err, "Tags")
+		return
+	}
+	for za0004 := range z.Tags {
+		err = en.WriteBytes(z.Tags[za0004])
+		if err != nil {
+			err = msgp.WrapError(err, "Tags", za0004)
+			return
+		}
+	}
+	// write "StatusCode"
+	err = en.Append(0xaa, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x43, 0x6f, 0x64, 0x65)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt(z.StatusCode)
+	if err != nil {
+		err = msgp.WrapError(err, "StatusCode")
+		return
+	}
+	// write "StoredAt"
+	err = en.Append(0xa8, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x64, 0x41, 0x74)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt64(z.StoredAt)
+	if err != nil {
+		err = msgp.WrapError(err, "StoredAt")
+		return
+	}
+	// write "TTL"
+	err = en.Append(0xa3, 0x54, 0x54, 0x4c)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt64(z.TTL)
+	if err != nil {
+		err = msgp.WrapError(err, "TTL")
+		return
+	}
+	// write "Grace"
+	err = en.Append(0xa5, 0x47, 0x72, 0x61, 0x63, 0x65)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt64(z.Grace)
+	if err != nil {
+		err = msgp.WrapError(err, "Grace")
+		return
+	}
+	// write "Keep"
+	err = en.Append(0xa4, 0x4b, 0x65, 0x65, 0x70)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt64(z.Keep)
+	if err != nil {
+		err = msgp.WrapError(err, "Keep")
+		return
+	}
+	// write "Retain"
+	err = en.Append(0xa6, 0x52, 0x65, 0x74, 0x61, 0x69, 0x6e)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt64(z.Retain)
+	if err != nil {
+		err = msgp.WrapError(err, "Retain")
+		return
+	}
+	// write "Revalidate"
+	err = en.Append(0xaa, 0x52, 0x65, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65)
+	if err != nil {
+		return
+	}
+	err = en.WriteBool(z.Revalidate)
+	if err != nil {
+		err = msgp.WrapError(err, "Revalidate")
+		return
+	}
 	return
 }
 
 // MarshalMsg implements msgp.Marshaler
 func (z *Response) MarshalMsg(b []byte) (o []byte, err error) {
 	o = msgp.Require(b, z.Msgsize())
-	// map header, size 3
+	// map header, size 14
+	// string "Host"
+	o = append(o, 0x8e, 0xa4, 0x48, 0x6f, 0x73, 0x74)
+	o = msgp.AppendBytes(o, z.Host)
 	// string "Path"
-	o = append(o, 0x83, 0xa4, 0x50, 0x61, 0x74, 0x68)
+	o = append(o, 0xa4, 0x50, 0x61, 0x74, 0x68)
 	o = msgp.AppendBytes(o, z.Path)
 	// string "Body"
 	o = append(o, 0xa4, 0x42, 0x6f, 0x64, 0x79)
@@ -322,6 +779,57 @@ func (z *Response) MarshalMsg(b []byte) (o []byte, err error) {
 		o = append(o, 0xa5, 0x56, 0x61, 0x6c, 0x75, 0x65)
 		o = msgp.AppendBytes(o, z.Headers[za0001].Value)
 	}
+	// string "Vary"
+	o = append(o, 0xa4, 0x56, 0x61, 0x72, 0x79)
+	o = msgp.AppendArrayHeader(o, uint32(len(z.Vary)))
+	for za0002 := range z.Vary {
+		// map header, size 2
+		// string "Key"
+		o = append(o, 0x82, 0xa3, 0x4b, 0x65, 0x79)
+		o = msgp.AppendBytes(o, z.Vary[za0002].Key)
+		// string "Value"
+		o = append(o, 0xa5, 0x56, 0x61, 0x6c, 0x75, 0x65)
+		o = msgp.AppendBytes(o, z.Vary[za0002].Value)
+	}
+	// string "Encoded"
+	o = append(o, 0xa7, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x65, 0x64)
+	o = msgp.AppendArrayHeader(o, uint32(len(z.Encoded)))
+	for za0003 := range z.Encoded {
+		// map header, size 2
+		// string "Encoding"
+		o = append(o, 0x82, 0xa8, 0x45, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67)
+		o = msgp.AppendBytes(o, z.Encoded[za0003].Encoding)
+		// string "Body"
+		o = append(o, 0xa4, 0x42, 0x6f, 0x64, 0x79)
+		o = msgp.AppendBytes(o, z.Encoded[za0003].Body)
+	}
+	// string "Tags"
+	o = append(o, 0xa4, 0x54, 0x61, 0x67, 0x73)
+	o = msgp.AppendArrayHeader(o, uint32(len(z.Tags)))
+	for za0004 := range z.Tags {
+		o = msgp.AppendBytes(o, z.Tags[za0004])
+	}
+	// string "StatusCode"
+	o = append(o, 0xaa, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x43, 0x6f, 0x64, 0x65)
+	o = msgp.AppendInt(o, z.StatusCode)
+	// string "StoredAt"
+	o = append(o, 0xa8, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x64, 0x41, 0x74)
+	o = msgp.AppendInt64(o, z.StoredAt)
+	// string "TTL"
+	o = append(o, 0xa3, 0x54, 0x54, 0x4c)
+	o = msgp.AppendInt64(o, z.TTL)
+	// string "Grace"
+	o = append(o, 0xa5, 0x47, 0x72, 0x61, 0x63, 0x65)
+	o = msgp.AppendInt64(o, z.Grace)
+	// string "Keep"
+	o = append(o, 0xa4, 0x4b, 0x65, 0x65, 0x70)
+	o = msgp.AppendInt64(o, z.Keep)
+	// string "Retain"
+	o = append(o, 0xa6, 0x52, 0x65, 0x74, 0x61, 0x69, 0x6e)
+	o = msgp.AppendInt64(o, z.Retain)
+	// string "Revalidate"
+	o = append(o, 0xaa, 0x52, 0x65, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65)
+	o = msgp.AppendBool(o, z.Revalidate)
 	return
 }
 
@@ -343,6 +851,12 @@ func (z *Response) UnmarshalMsg(bts []byte) (o []byte, err error) {
 			return
 		}
 		switch msgp.UnsafeString(field) {
+		case "Host":
+			z.Host, bts, err = msgp.ReadBytesBytes(bts, z.Host)
+			if err != nil {
+				err = msgp.WrapError(err, "Host")
+				return
+			}
 		case "Path":
 			z.Path, bts, err = msgp.ReadBytesBytes(bts, z.Path)
 			if err != nil {
@@ -403,6 +917,163 @@ func (z *Response) UnmarshalMsg(bts []byte) (o []byte, err error) {
 					}
 				}
 			}
+		case "Vary":
+			var zb0004 uint32
+			zb0004, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Vary")
+				return
+			}
+			if cap(z.Vary) >= int(zb0004) {
+				z.Vary = (z.Vary)[:zb0004]
+			} else {
+				z.Vary = make([]ResponseHeader, zb0004)
+			}
+			for za0002 := range z.Vary {
+				var zb0005 uint32
+				zb0005, bts, err = msgp.ReadMapHeaderBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Vary", za0002)
+					return
+				}
+				for zb0005 > 0 {
+					zb0005--
+					field, bts, err = msgp.ReadMapKeyZC(bts)
+					if err != nil {
+						err = msgp.WrapError(err, "Vary", za0002)
+						return
+					}
+					switch msgp.UnsafeString(field) {
+					case "Key":
+						z.Vary[za0002].Key, bts, err = msgp.ReadBytesBytes(bts, z.Vary[za0002].Key)
+						if err != nil {
+							err = msgp.WrapError(err, "Vary", za0002, "Key")
+							return
+						}
+					case "Value":
+						z.Vary[za0002].Value, bts, err = msgp.ReadBytesBytes(bts, z.Vary[za0002].Value)
+						if err != nil {
+							err = msgp.WrapError(err, "Vary", za0002, "Value")
+							return
+						}
+					default:
+						bts, err = msgp.Skip(bts)
+						if err != nil {
+							err = msgp.WrapError(err, "Vary", za0002)
+							return
+						}
+					}
+				}
+			}
+		case "Encoded":
+			var zb0006 uint32
+			zb0006, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Encoded")
+				return
+			}
+			if cap(z.Encoded) >= int(zb0006) {
+				z.Encoded = (z.Encoded)[:zb0006]
+			} else {
+				z.Encoded = make([]EncodedBody, zb0006)
+			}
+			for za0003 := range z.Encoded {
+				var zb0007 uint32
+				zb0007, bts, err = msgp.ReadMapHeaderBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Encoded", za0003)
+					return
+				}
+				for zb0007 > 0 {
+					zb0007--
+					field, bts, err = msgp.ReadMapKeyZC(bts)
+					if err != nil {
+						err = msgp.WrapError(err, "Encoded", za0003)
+						return
+					}
+					switch msgp.UnsafeString(field) {
+					case "Encoding":
+						z.Encoded[za0003].Encoding, bts, err = msgp.ReadBytesBytes(bts, z.Encoded[za0003].Encoding)
+						if err != nil {
+							err = msgp.WrapError(err, "Encoded", za0003, "Encoding")
+							return
+						}
+					case "Body":
+						z.Encoded[za0003].Body, bts, err = msgp.ReadBytesBytes(bts, z.Encoded[za0003].Body)
+						if err != nil {
+							err = msgp.WrapError(err, "Encoded", za0003, "Body")
+							return
+						}
+					default:
+						bts, err = msgp.Skip(bts)
+						if err != nil {
+							err = msgp.WrapError(err, "Encoded", za0003)
+							return
+						}
+					}
+				}
+			}
+		case "Tags":
+			var zb0008 uint32
+			zb0008, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Tags")
+				return
+			}
+			if cap(z.Tags) >= int(zb0008) {
+				z.Tags = (z.Tags)[:zb0008]
+			} else {
+				z.Tags = make([][]byte, zb0008)
+			}
+			for za0004 := range z.Tags {
+				z.Tags[za0004], bts, err = msgp.ReadBytesBytes(bts, z.Tags[za0004])
+				if err != nil {
+					err = msgp.WrapError(err, "Tags", za0004)
+					return
+				}
+			}
+		case "StatusCode":
+			z.StatusCode, bts, err = msgp.ReadIntBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "StatusCode")
+				return
+			}
+		case "StoredAt":
+			z.StoredAt, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "StoredAt")
+				return
+			}
+		case "TTL":
+			z.TTL, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "TTL")
+				return
+			}
+		case "Grace":
+			z.Grace, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Grace")
+				return
+			}
+		case "Keep":
+			z.Keep, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Keep")
+				return
+			}
+		case "Retain":
+			z.Retain, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Retain")
+				return
+			}
+		case "Revalidate":
+			z.Revalidate, bts, err = msgp.ReadBoolBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Revalidate")
+				return
+			}
 		default:
 			bts, err = msgp.Skip(bts)
 			if err != nil {
@@ -417,10 +1088,23 @@ func (z *Response) UnmarshalMsg(bts []byte) (o []byte, err error) {
 
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
 func (z *Response) Msgsize() (s int) {
-	s = 1 + 5 + msgp.BytesPrefixSize + len(z.Path) + 5 + msgp.BytesPrefixSize + len(z.Body) + 8 + msgp.ArrayHeaderSize
+	s = 1 + 5 + msgp.BytesPrefixSize + len(z.Host) + 5 + msgp.BytesPrefixSize + len(z.Path) + 5 + msgp.BytesPrefixSize + len(z.Body) + 8 + msgp.ArrayHeaderSize
 	for za0001 := range z.Headers {
 		s += 1 + 4 + msgp.BytesPrefixSize + len(z.Headers[za0001].Key) + 6 + msgp.BytesPrefixSize + len(z.Headers[za0001].Value)
 	}
+	s += 5 + msgp.ArrayHeaderSize
+	for za0002 := range z.Vary {
+		s += 1 + 4 + msgp.BytesPrefixSize + len(z.Vary[za0002].Key) + 6 + msgp.BytesPrefixSize + len(z.Vary[za0002].Value)
+	}
+	s += 8 + msgp.ArrayHeaderSize
+	for za0003 := range z.Encoded {
+		s += 1 + 9 + msgp.BytesPrefixSize + len(z.Encoded[za0003].Encoding) + 5 + msgp.BytesPrefixSize + len(z.Encoded[za0003].Body)
+	}
+	s += 5 + msgp.ArrayHeaderSize
+	for za0004 := range z.Tags {
+		s += msgp.BytesPrefixSize + len(z.Tags[za0004])
+	}
+	s += 11 + msgp.IntSize + 9 + msgp.Int64Size + 4 + msgp.Int64Size + 6 + msgp.Int64Size + 5 + msgp.Int64Size + 7 + msgp.Int64Size + 11 + msgp.BoolSize
 	return
 }
 
@@ -1,3 +1,27 @@
 package cache
 
+const (
+	// BigcacheStorage saves the entries in bigcache, with the same lifetime for all of them.
+	BigcacheStorage = "bigcache"
+
+	// LRUStorage saves the entries in a size bounded LRU, with a lifetime per entry.
+	LRUStorage = "lru"
+)
+
 const defaultBigcacheShards = 1024 // power of two
+
+const bigcacheHeadersSize = 18 // timestamp + hash + key size
+
+const snapshotFileName = "kratgo.snapshot"
+
+const snapshotMaxRecordSize = 1 << 30
+
+var snapshotMagic = []byte("KRATGO-SNAPSHOT\x01") // name + format version
+
+const (
+	// SnapshotMerge imports the snapshot entries over the current ones.
+	SnapshotMerge = "merge"
+
+	// SnapshotReplace removes all current entries before importing the snapshot.
+	SnapshotReplace = "replace"
+)
@@ -46,16 +46,62 @@ func (e *Entry) allocResponse(data []Response) ([]Response, *Response) {
 	return data, &data[n]
 }
 
+// appendResponse appends a deep copy of the response, since it could be released to the pool
+// and reused while the entry is still used.
 func (e *Entry) appendResponse(data []Response, resp Response) []Response {
 	data, r := e.allocResponse(data)
-
-	r.Path = append(r.Path[:0], resp.Path...)
-	r.Body = append(r.Body[:0], resp.Body...)
-	r.Headers = resp.Headers
+	resp.CopyTo(r)
 
 	return data
 }
 
+func (e Entry) host() []byte {
+	if len(e.Responses) == 0 {
+		return nil
+	}
+
+	return e.Responses[0].Host
+}
+
+// tags returns the cache tags of all responses of the entry, without duplicates.
+func (e Entry) tags() []string {
+	var tags []string
+
+	for i := range e.Responses {
+	loop:
+		for _, tag := range e.Responses[i].Tags {
+			for _, t := range tags {
+				if t == string(tag) {
+					continue loop
+				}
+			}
+
+			tags = append(tags, string(tag))
+		}
+	}
+
+	return tags
+}
+
+// expiresAt returns the unix time until which any response of the entry could be served,
+// including its stale windows, or 0 if some response has not an explicit lifetime.
+func (e Entry) expiresAt() int64 {
+	expiresAt := int64(0)
+
+	for i, n := 0, len(e.Responses); i < n; i++ {
+		resp := &e.Responses[i]
+		if resp.TTL <= 0 {
+			return 0
+		}
+
+		if t := resp.StoredAt + resp.TTL + resp.window(); t > expiresAt {
+			expiresAt = t
+		}
+	}
+
+	return expiresAt
+}
+
 // HasResponse ...
 func (e Entry) HasResponse(path []byte) bool {
 	for i, n := 0, len(e.Responses); i < n; i++ {
@@ -91,12 +137,23 @@ func (e Entry) GetResponse(path []byte) *Response {
 	return nil
 }
 
-// SetResponse ...
+// GetVariant returns the response of the path which has been produced for the given vary headers.
+func (e Entry) GetVariant(path []byte, vary []ResponseHeader) *Response {
+	n := len(e.Responses)
+	for i := 0; i < n; i++ {
+		resp := &e.Responses[i]
+		if bytes.Equal(path, resp.Path) && resp.hasVary(vary) {
+			return resp
+		}
+	}
+
+	return nil
+}
+
+// SetResponse saves a copy of the response, replacing the previous variant of the same path and vary headers.
 func (e *Entry) SetResponse(resp Response) {
-	r := e.GetResponse(resp.Path)
-	if r != nil {
-		r.Body = append(r.Body[:0], resp.Body...)
-		r.Headers = resp.Headers
+	if r := e.GetVariant(resp.Path, resp.Vary); r != nil {
+		resp.CopyTo(r)
 
 		return
 	}
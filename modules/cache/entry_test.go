@@ -8,6 +8,7 @@ import (
 
 func getEntryTest() Entry {
 	r1 := Response{
+		Host: []byte("www.kratgo.com"),
 		Path: []byte("/cache/"),
 		Body: []byte("Response body"),
 		Headers: []ResponseHeader{
@@ -18,6 +19,7 @@ func getEntryTest() Entry {
 		},
 	}
 	r2 := Response{
+		Host: []byte("www.kratgo.com"),
 		Path: []byte("/cache/2/"),
 		Body: []byte("Response body 2"),
 		Headers: []ResponseHeader{
@@ -119,6 +121,96 @@ func TestEntry_appendResponse(t *testing.T) {
 	}
 }
 
+func TestEntry_host(t *testing.T) {
+	e := getEntryTest()
+
+	if host := e.host(); !bytes.Equal(host, e.Responses[0].Host) {
+		t.Errorf("Entry.host() == '%s', want '%s'", host, e.Responses[0].Host)
+	}
+
+	e.Reset()
+
+	if host := e.host(); host != nil {
+		t.Errorf("Entry.host() == '%s', want '%v'", host, nil)
+	}
+}
+
+func TestEntry_tags(t *testing.T) {
+	e := Entry{
+		Responses: []Response{
+			{Tags: [][]byte{[]byte("product-42"), []byte("category-7")}},
+			{Tags: [][]byte{[]byte("product-42")}},
+			{},
+		},
+	}
+
+	want := []string{"product-42", "category-7"}
+	if tags := e.tags(); !reflect.DeepEqual(tags, want) {
+		t.Errorf("Entry.tags() == '%v', want '%v'", tags, want)
+	}
+
+	e.Reset()
+
+	if tags := e.tags(); len(tags) != 0 {
+		t.Errorf("Entry.tags() == '%v', want '%v'", tags, []string{})
+	}
+}
+
+func TestEntry_expiresAt(t *testing.T) {
+	type args struct {
+		responses []Response
+	}
+
+	type want struct {
+		expiresAt int64
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "WithoutTTL",
+			args: args{
+				responses: []Response{{StoredAt: 100, TTL: 60}, {StoredAt: 100}},
+			},
+			want: want{expiresAt: 0},
+		},
+		{
+			name: "Longest",
+			args: args{
+				responses: []Response{{StoredAt: 100, TTL: 60}, {StoredAt: 120, TTL: 60}},
+			},
+			want: want{expiresAt: 180},
+		},
+		{
+			name: "StaleWindows",
+			args: args{
+				responses: []Response{{StoredAt: 100, TTL: 60, Grace: 30, Keep: 300}},
+			},
+			want: want{expiresAt: 460},
+		},
+		{
+			name: "RetainWindow",
+			args: args{
+				responses: []Response{{StoredAt: 100, TTL: 60, Grace: 30, Keep: 300, Retain: 600}},
+			},
+			want: want{expiresAt: 760},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Entry{Responses: tt.args.responses}
+
+			if expiresAt := e.expiresAt(); expiresAt != tt.want.expiresAt {
+				t.Errorf("Entry.expiresAt() == '%d', want '%d'", expiresAt, tt.want.expiresAt)
+			}
+		})
+	}
+}
+
 func TestEntry_HasResponse(t *testing.T) {
 	e := getEntryTest()
 	r1 := e.Responses[0]
@@ -169,6 +261,44 @@ func TestEntry_GetResponse(t *testing.T) {
 	}
 }
 
+func TestEntry_GetVariant(t *testing.T) {
+	path := []byte("/cache/")
+	varyEs := []ResponseHeader{{Key: []byte("Accept-Language"), Value: []byte("es")}}
+	varyEn := []ResponseHeader{{Key: []byte("Accept-Language"), Value: []byte("en")}}
+
+	e := Entry{}
+	e.SetResponse(Response{Path: path, Body: []byte("Hola"), Vary: varyEs})
+	e.SetResponse(Response{Path: path, Body: []byte("Hello"), Vary: varyEn})
+
+	if e.Len() != 2 {
+		t.Fatalf("Entry.SetResponse() variants == '%d', want '%d'", e.Len(), 2)
+	}
+
+	if r := e.GetVariant(path, varyEs); r == nil || string(r.Body) != "Hola" {
+		t.Errorf("Entry.GetVariant() == '%v', want body '%s'", r, "Hola")
+	}
+
+	if r := e.GetVariant(path, varyEn); r == nil || string(r.Body) != "Hello" {
+		t.Errorf("Entry.GetVariant() == '%v', want body '%s'", r, "Hello")
+	}
+
+	if r := e.GetVariant(path, nil); r != nil {
+		t.Errorf("Entry.GetVariant() == '%v', want '%v'", *r, nil)
+	}
+
+	e.SetResponse(Response{Path: path, Body: []byte("Hello again"), Vary: varyEn})
+
+	if e.Len() != 2 {
+		t.Errorf("Entry.SetResponse() has not been update the existing variant")
+	}
+
+	e.DelResponse(path)
+
+	if e.Len() != 0 {
+		t.Errorf("Entry.DelResponse() has not been delete all the variants")
+	}
+}
+
 func TestEntry_SetResponse(t *testing.T) {
 	e := getEntryTest()
 
@@ -197,6 +327,52 @@ func TestEntry_SetResponse(t *testing.T) {
 	}
 }
 
+func TestEntry_SetResponseCopy(t *testing.T) {
+	newResponse := func(encoding string) *Response {
+		r := AcquireResponse()
+		r.Path = []byte("/kratgo/fast")
+		r.SetHeader([]byte("Content-Encoding"), []byte(encoding))
+		r.SetVary([]byte("Accept-Encoding"), []byte(encoding))
+		r.SetEncodedBody([]byte(encoding), []byte("body"))
+		r.SetTag([]byte(encoding))
+
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		replace bool
+	}{
+		{name: "New"},
+		{name: "Replace", replace: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := AcquireEntry()
+			defer ReleaseEntry(e)
+
+			r := newResponse("gzip")
+			if tt.replace {
+				e.SetResponse(*r)
+			}
+
+			e.SetResponse(*r)
+
+			want := AcquireResponse()
+			r.CopyTo(want)
+
+			// The released response is reused, overwriting its buffers
+			ReleaseResponse(r)
+			ReleaseResponse(newResponse("br"))
+
+			if got := e.GetResponse(want.Path); !reflect.DeepEqual(got, want) {
+				t.Errorf("Entry.SetResponse() response == '%+v', want '%+v'", got, want)
+			}
+		})
+	}
+}
+
 func TestEntry_DelResponse(t *testing.T) {
 	e := getEntryTest()
 	r1 := e.Responses[0]
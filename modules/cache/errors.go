@@ -0,0 +1,21 @@
+package cache
+
+import "errors"
+
+// ErrEntryNotFound is returned by the storages when the key is not found.
+var ErrEntryNotFound = errors.New("Entry not found")
+
+// ErrEntryTooLarge is returned by the storages when the entry exceeds the max cache size.
+var ErrEntryTooLarge = errors.New("Entry is bigger than the max cache size")
+
+// ErrExpirationNotKept is returned by the storages which could not update an entry keeping its expiration.
+var ErrExpirationNotKept = errors.New("Entry expiration could not be kept")
+
+// ErrInvalidIteratorState is returned by the iterators when the value is read before calling SetNext.
+var ErrInvalidIteratorState = errors.New("Iterator is in invalid state, call SetNext() before Value()")
+
+// ErrInvalidSnapshot is returned when the data is not a snapshot, or has been truncated.
+var ErrInvalidSnapshot = errors.New("Invalid cache snapshot")
+
+// ErrSnapshotVersion is returned when the snapshot has been written with other format version.
+var ErrSnapshotVersion = errors.New("Unsupported cache snapshot version")
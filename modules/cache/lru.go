@@ -0,0 +1,276 @@
+package cache
+
+import (
+	"container/list"
+	"strings"
+	"time"
+
+	"github.com/savsgio/kratgo/modules/config"
+)
+
+func newLRUStorage(cfg config.Cache, onRemove func(key string)) *lruStorage {
+	s := &lruStorage{
+		items:      make(map[string]*list.Element),
+		ll:         list.New(),
+		maxSize:    cfg.HardMaxCacheSize * 1024 * 1024,
+		defaultTTL: time.Duration(cfg.TTL) * time.Minute,
+		onRemove:   onRemove,
+	}
+
+	go s.cleaner(time.Duration(cfg.CleanFrequency) * time.Minute)
+
+	return s
+}
+
+func (item *lruItem) size() int {
+	return len(item.key) + len(item.data)
+}
+
+func (item *lruItem) isExpired(now time.Time) bool {
+	return !now.Before(item.expiresAt)
+}
+
+// removeElementWithoutLock removes the element and notifies its removal.
+//
+// The removal is notified before releasing the lock, so a late notification never
+// unindexes the same key saved again meanwhile.
+func (s *lruStorage) removeElementWithoutLock(e *list.Element) {
+	item := s.ll.Remove(e).(*lruItem)
+
+	delete(s.items, item.key)
+	s.size -= item.size()
+
+	if s.onRemove != nil {
+		s.onRemove(item.key)
+	}
+}
+
+// cleaner removes the expired entries periodically.
+func (s *lruStorage) cleaner(frequency time.Duration) {
+	ticker := time.NewTicker(frequency)
+
+	for now := range ticker.C {
+		s.removeExpired(now)
+	}
+}
+
+func (s *lruStorage) removeExpired(now time.Time) {
+	s.mu.Lock()
+
+	for e := s.ll.Back(); e != nil; {
+		prev := e.Prev()
+
+		if e.Value.(*lruItem).isExpired(now) {
+			s.removeElementWithoutLock(e)
+		}
+
+		e = prev
+	}
+
+	s.mu.Unlock()
+}
+
+func (s *lruStorage) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+
+	e, ok := s.items[key]
+	if !ok {
+		s.stats.Misses++
+		s.mu.Unlock()
+
+		return nil, ErrEntryNotFound
+	}
+
+	item := e.Value.(*lruItem)
+	if item.isExpired(time.Now()) {
+		s.removeElementWithoutLock(e)
+		s.stats.Misses++
+		s.mu.Unlock()
+
+		return nil, ErrEntryNotFound
+	}
+
+	s.ll.MoveToFront(e)
+	s.stats.Hits++
+
+	data := item.data
+
+	s.mu.Unlock()
+
+	return data, nil
+}
+
+// Set saves a copy of the data under the key, which expires after the given ttl,
+// or after the default one if the ttl is not greater than 0.
+//
+// The least recently used entries are removed to make room for the new one.
+func (s *lruStorage) Set(key string, data []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = s.defaultTTL
+	}
+
+	item := &lruItem{
+		key:       strings.Clone(key),
+		data:      append([]byte(nil), data...),
+		expiresAt: time.Now().Add(ttl),
+	}
+
+	if s.maxSize > 0 && item.size() > s.maxSize {
+		return ErrEntryTooLarge
+	}
+
+	s.mu.Lock()
+
+	if e, ok := s.items[key]; ok {
+		s.size -= e.Value.(*lruItem).size()
+		e.Value = item
+		s.ll.MoveToFront(e)
+	} else {
+		s.items[item.key] = s.ll.PushFront(item)
+	}
+
+	s.size += item.size()
+
+	for s.maxSize > 0 && s.size > s.maxSize {
+		s.removeElementWithoutLock(s.ll.Back())
+	}
+
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Update replaces the data of the key keeping its expiration.
+//
+// The least recently used entries are removed to make room for the new data.
+func (s *lruStorage) Update(key string, data []byte) error {
+	if s.maxSize > 0 && len(key)+len(data) > s.maxSize {
+		return ErrEntryTooLarge
+	}
+
+	data = append([]byte(nil), data...)
+
+	s.mu.Lock()
+
+	e, ok := s.items[key]
+	if !ok || e.Value.(*lruItem).isExpired(time.Now()) {
+		s.mu.Unlock()
+
+		return ErrEntryNotFound
+	}
+
+	item := e.Value.(*lruItem)
+	s.size += len(data) - len(item.data)
+	e.Value = &lruItem{key: item.key, data: data, expiresAt: item.expiresAt}
+	s.ll.MoveToFront(e)
+
+	for s.maxSize > 0 && s.size > s.maxSize {
+		s.removeElementWithoutLock(s.ll.Back())
+	}
+
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *lruStorage) Delete(key string) error {
+	s.mu.Lock()
+
+	e, ok := s.items[key]
+	if !ok {
+		s.stats.DelMisses++
+		s.mu.Unlock()
+
+		return ErrEntryNotFound
+	}
+
+	s.removeElementWithoutLock(e)
+	s.stats.DelHits++
+
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Iterator returns an iterator over the entries stored at the moment of the call.
+func (s *lruStorage) Iterator() Iterator {
+	s.mu.RLock()
+
+	keys := make([]string, 0, len(s.items))
+	for e := s.ll.Front(); e != nil; e = e.Next() {
+		keys = append(keys, e.Value.(*lruItem).key)
+	}
+
+	s.mu.RUnlock()
+
+	return &lruIterator{storage: s, keys: keys, pos: -1}
+}
+
+func (s *lruStorage) Len() int {
+	s.mu.RLock()
+	n := s.ll.Len()
+	s.mu.RUnlock()
+
+	return n
+}
+
+func (s *lruStorage) Capacity() int {
+	s.mu.RLock()
+	size := s.size
+	s.mu.RUnlock()
+
+	return size
+}
+
+func (s *lruStorage) Reset() error {
+	s.mu.Lock()
+
+	s.items = make(map[string]*list.Element)
+	s.ll.Init()
+	s.size = 0
+	s.stats = Stats{}
+
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *lruStorage) Stats() Stats {
+	s.mu.RLock()
+	stats := s.stats
+	s.mu.RUnlock()
+
+	return stats
+}
+
+// SetNext moves the iterator to the next entry which is still stored.
+func (it *lruIterator) SetNext() bool {
+	now := time.Now()
+
+	for it.pos+1 < len(it.keys) {
+		it.pos++
+
+		it.storage.mu.RLock()
+		e, ok := it.storage.items[it.keys[it.pos]]
+		if ok && !e.Value.(*lruItem).isExpired(now) {
+			it.data = e.Value.(*lruItem).data
+		} else {
+			ok = false
+		}
+		it.storage.mu.RUnlock()
+
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (it *lruIterator) Value() (string, []byte, error) {
+	if it.pos < 0 || it.pos >= len(it.keys) {
+		return "", nil, ErrInvalidIteratorState
+	}
+
+	return it.keys[it.pos], it.data, nil
+}
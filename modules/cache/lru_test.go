@@ -0,0 +1,288 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/savsgio/gotils/strconv"
+	"github.com/savsgio/kratgo/modules/config"
+)
+
+func newTestLRUStorage(maxSize int) (*lruStorage, *[]string) {
+	removed := []string{}
+
+	s := newLRUStorage(config.Cache{TTL: 10, CleanFrequency: 5}, func(key string) {
+		removed = append(removed, key)
+	})
+	s.maxSize = maxSize
+
+	return s, &removed
+}
+
+func TestLRUStorage_SetAndGetAndDelete(t *testing.T) {
+	s, removed := newTestLRUStorage(0)
+
+	data := []byte("data")
+
+	if err := s.Set("key", data, 0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data[0] = 'X'
+
+	result, err := s.Get("key")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if string(result) != "data" {
+		t.Errorf("lruStorage.Get() == '%s', want '%s'", result, "data")
+	}
+
+	if err := s.Delete("key"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := s.Get("key"); err != ErrEntryNotFound {
+		t.Errorf("lruStorage.Get() error == '%v', want '%v'", err, ErrEntryNotFound)
+	}
+
+	if err := s.Delete("key"); err != ErrEntryNotFound {
+		t.Errorf("lruStorage.Delete() error == '%v', want '%v'", err, ErrEntryNotFound)
+	}
+
+	if len(*removed) != 1 || (*removed)[0] != "key" {
+		t.Errorf("lruStorage.Delete() removed keys == '%v', want '%v'", *removed, []string{"key"})
+	}
+
+	stats := s.Stats()
+	wantStats := Stats{Hits: 1, Misses: 1, DelHits: 1, DelMisses: 1}
+	if stats != wantStats {
+		t.Errorf("lruStorage.Stats() == '%+v', want '%+v'", stats, wantStats)
+	}
+}
+
+func TestLRUStorage_SetKeyCopy(t *testing.T) {
+	s, _ := newTestLRUStorage(0)
+
+	key := []byte("key1")
+	s.Set(strconv.B2S(key), []byte("value"), 0)
+
+	key[3] = '2'
+
+	if _, err := s.Get("key1"); err != nil {
+		t.Errorf("lruStorage.Set() the key has not been copied")
+	}
+}
+
+func TestLRUStorage_Eviction(t *testing.T) {
+	s, removed := newTestLRUStorage(20)
+
+	// Every entry has a size of 10 bytes (key + data)
+	s.Set("key1", []byte("value1"), 0)
+	s.Set("key2", []byte("value2"), 0)
+
+	// Use the first key, so the second one is the least recently used
+	s.Get("key1")
+
+	s.Set("key3", []byte("value3"), 0)
+
+	if _, err := s.Get("key2"); err != ErrEntryNotFound {
+		t.Errorf("lruStorage.Set() the least recently used key has not been evicted")
+	}
+
+	for _, key := range []string{"key1", "key3"} {
+		if _, err := s.Get(key); err != nil {
+			t.Errorf("lruStorage.Set() the key '%s' has been evicted", key)
+		}
+	}
+
+	if len(*removed) != 1 || (*removed)[0] != "key2" {
+		t.Errorf("lruStorage.Set() removed keys == '%v', want '%v'", *removed, []string{"key2"})
+	}
+
+	if s.size != 20 {
+		t.Errorf("lruStorage.size == '%d', want '%d'", s.size, 20)
+	}
+
+	if capacity := s.Capacity(); capacity != 20 {
+		t.Errorf("lruStorage.Capacity() == '%d', want '%d'", capacity, 20)
+	}
+
+	if err := s.Set("key4", make([]byte, 20), 0); err != ErrEntryTooLarge {
+		t.Errorf("lruStorage.Set() error == '%v', want '%v'", err, ErrEntryTooLarge)
+	}
+}
+
+func TestLRUStorage_TTL(t *testing.T) {
+	s, removed := newTestLRUStorage(0)
+
+	s.Set("short", []byte("value"), time.Millisecond)
+	s.Set("long", []byte("value"), 0)
+
+	time.Sleep(5 * time.Millisecond)
+
+	if _, err := s.Get("short"); err != ErrEntryNotFound {
+		t.Errorf("lruStorage.Get() the expired key has been found")
+	}
+
+	if _, err := s.Get("long"); err != nil {
+		t.Errorf("lruStorage.Get() unexpected error: %v", err)
+	}
+
+	if len(*removed) != 1 || (*removed)[0] != "short" {
+		t.Errorf("lruStorage.Get() removed keys == '%v', want '%v'", *removed, []string{"short"})
+	}
+
+	s.Set("short", []byte("value"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	s.removeExpired(time.Now())
+
+	if len(*removed) != 2 || (*removed)[1] != "short" {
+		t.Errorf("lruStorage.removeExpired() removed keys == '%v', want '%v'", *removed, []string{"short", "short"})
+	}
+
+	if n := s.Len(); n != 1 {
+		t.Errorf("lruStorage.Len() == '%d', want '%d'", n, 1)
+	}
+}
+
+func TestLRUStorage_Update(t *testing.T) {
+	s, removed := newTestLRUStorage(20)
+
+	if err := s.Update("key1", []byte("value1")); err != ErrEntryNotFound {
+		t.Errorf("lruStorage.Update() error == '%v', want '%v'", err, ErrEntryNotFound)
+	}
+
+	s.Set("key1", []byte("value1"), time.Minute)
+	s.Set("key2", []byte("value2"), 0)
+
+	expiresAt := s.items["key1"].Value.(*lruItem).expiresAt
+
+	// The updated key grows to 14 bytes, so the other one is evicted
+	if err := s.Update("key1", []byte("value1gzip")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	item := s.items["key1"].Value.(*lruItem)
+	if string(item.data) != "value1gzip" {
+		t.Errorf("lruStorage.Update() data == '%s', want '%s'", item.data, "value1gzip")
+	}
+
+	if !item.expiresAt.Equal(expiresAt) {
+		t.Errorf("lruStorage.Update() expiresAt == '%v', want '%v'", item.expiresAt, expiresAt)
+	}
+
+	if len(*removed) != 1 || (*removed)[0] != "key2" {
+		t.Errorf("lruStorage.Update() removed keys == '%v', want '%v'", *removed, []string{"key2"})
+	}
+
+	if s.size != 14 {
+		t.Errorf("lruStorage.size == '%d', want '%d'", s.size, 14)
+	}
+
+	if err := s.Update("key1", make([]byte, 20)); err != ErrEntryTooLarge {
+		t.Errorf("lruStorage.Update() error == '%v', want '%v'", err, ErrEntryTooLarge)
+	}
+
+	if _, err := s.Get("key1"); err != nil {
+		t.Errorf("lruStorage.Update() the too large data has removed the key")
+	}
+}
+
+func TestLRUStorage_onRemoveLocked(t *testing.T) {
+	var s *lruStorage
+
+	notified := 0
+
+	// The removals are notified before any other access to the storage, like a new Set of the same key
+	s = newLRUStorage(config.Cache{TTL: 10, CleanFrequency: 5}, func(key string) {
+		notified++
+
+		if s.mu.TryRLock() {
+			s.mu.RUnlock()
+			t.Errorf("lruStorage.onRemove() of '%s' called without the lock", key)
+		}
+	})
+	s.maxSize = 2 * len("key1value")
+
+	s.Set("key1", []byte("value"), 0)
+	s.Set("key2", []byte("value"), 0)
+	s.Set("key3", []byte("value"), 0) // Evicts key1
+	s.Delete("key2")
+
+	s.Set("key4", []byte("value"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	s.Get("key4")
+
+	s.Set("key5", []byte("value"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	s.removeExpired(time.Now())
+
+	if notified != 4 {
+		t.Errorf("lruStorage.onRemove() calls == '%d', want '%d'", notified, 4)
+	}
+}
+
+func TestLRUStorage_Iterator(t *testing.T) {
+	s, _ := newTestLRUStorage(0)
+
+	keys := map[string]string{"key1": "value1", "key2": "value2", "key3": "value3"}
+	for k, v := range keys {
+		s.Set(k, []byte(v), 0)
+	}
+
+	iter := s.Iterator()
+
+	if _, _, err := iter.Value(); err != ErrInvalidIteratorState {
+		t.Errorf("lruIterator.Value() error == '%v', want '%v'", err, ErrInvalidIteratorState)
+	}
+
+	// Entries removed after the creation of the iterator are skipped
+	s.Delete("key2")
+	delete(keys, "key2")
+
+	iterated := 0
+	for iter.SetNext() {
+		key, data, err := iter.Value()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if keys[key] != string(data) {
+			t.Errorf("lruIterator.Value() == '%s, %s', want '%s, %s'", key, data, key, keys[key])
+		}
+
+		iterated++
+	}
+
+	if iterated != len(keys) {
+		t.Errorf("lruStorage.Iterator() iterated entries == '%d', want '%d'", iterated, len(keys))
+	}
+}
+
+func TestLRUStorage_Reset(t *testing.T) {
+	s, _ := newTestLRUStorage(0)
+
+	s.Set("key", []byte("value"), 0)
+	s.Get("key")
+
+	if err := s.Reset(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if n := s.Len(); n != 0 {
+		t.Errorf("lruStorage.Len() == '%d', want '%d'", n, 0)
+	}
+
+	if s.size != 0 {
+		t.Errorf("lruStorage.size == '%d', want '%d'", s.size, 0)
+	}
+
+	if stats := s.Stats(); stats != (Stats{}) {
+		t.Errorf("lruStorage.Stats() == '%+v', want '%+v'", stats, Stats{})
+	}
+}
@@ -55,14 +55,188 @@ func (r *Response) HasHeader(k, v []byte) bool {
 	return false
 }
 
+// GetHeader returns the value of the header with the given key, or nil if not found.
+func (r *Response) GetHeader(k []byte) []byte {
+	for i, n := 0, len(r.Headers); i < n; i++ {
+		h := &r.Headers[i]
+		if bytes.EqualFold(h.Key, k) {
+			return h.Value
+		}
+	}
+
+	return nil
+}
+
+func (r *Response) hasVary(vary []ResponseHeader) bool {
+	if len(r.Vary) != len(vary) {
+		return false
+	}
+
+	for i := range vary {
+		if !bytes.Equal(r.Vary[i].Key, vary[i].Key) || !bytes.Equal(r.Vary[i].Value, vary[i].Value) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // SetHeader ...
 func (r *Response) SetHeader(k, v []byte) {
 	r.Headers = r.appendHeader(r.Headers, k, v)
 }
 
+// IsExpired returns true if the freshness lifetime of the response has been exceeded at the given unix time.
+// The responses without an explicit lifetime never expire, they are removed by the cache itself,
+// and the ones which must be revalidated before each use are always expired.
+func (r *Response) IsExpired(now int64) bool {
+	return r.Revalidate || (r.TTL > 0 && now >= r.StoredAt+r.TTL)
+}
+
+// InGrace returns true if the response has expired at the given unix time,
+// but it could be served while it's revalidated.
+func (r *Response) InGrace(now int64) bool {
+	return r.IsExpired(now) && now < r.StoredAt+r.TTL+r.Grace
+}
+
+// InKeep returns true if the response has expired at the given unix time,
+// but it could be served if the backend fails.
+func (r *Response) InKeep(now int64) bool {
+	return r.IsExpired(now) && now < r.StoredAt+r.TTL+r.Keep
+}
+
+// InRetain returns true if the response has expired at the given unix time,
+// but it could be revalidated with the backend.
+// The responses which must be revalidated before each use could be revalidated while they are in cache.
+func (r *Response) InRetain(now int64) bool {
+	return r.IsExpired(now) && (r.Revalidate || now < r.StoredAt+r.TTL+r.Retain)
+}
+
+// window returns the seconds after the expiration in which the response is kept in cache.
+func (r *Response) window() int64 {
+	window := r.Grace
+	if r.Keep > window {
+		window = r.Keep
+	}
+
+	if r.Retain > window {
+		window = r.Retain
+	}
+
+	return window
+}
+
+// GetEncodedBody returns the body compressed with the given encoding, or nil if it has not been saved.
+func (r *Response) GetEncodedBody(encoding []byte) []byte {
+	for i := range r.Encoded {
+		if bytes.Equal(r.Encoded[i].Encoding, encoding) {
+			return r.Encoded[i].Body
+		}
+	}
+
+	return nil
+}
+
+// SetEncodedBody saves the body compressed with the given encoding.
+func (r *Response) SetEncodedBody(encoding, body []byte) {
+	for i := range r.Encoded {
+		if bytes.Equal(r.Encoded[i].Encoding, encoding) {
+			r.Encoded[i].Body = append(r.Encoded[i].Body[:0], body...)
+			return
+		}
+	}
+
+	n := len(r.Encoded)
+	if cap(r.Encoded) > n {
+		r.Encoded = r.Encoded[:n+1]
+	} else {
+		r.Encoded = append(r.Encoded, EncodedBody{})
+	}
+
+	e := &r.Encoded[n]
+	e.Encoding = append(e.Encoding[:0], encoding...)
+	e.Body = append(e.Body[:0], body...)
+}
+
+// HasTag returns true if the response has the given cache tag.
+func (r *Response) HasTag(tag []byte) bool {
+	for i := range r.Tags {
+		if bytes.Equal(r.Tags[i], tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetTag adds a cache tag to the response, if it has not it yet.
+func (r *Response) SetTag(tag []byte) {
+	if r.HasTag(tag) {
+		return
+	}
+
+	n := len(r.Tags)
+	if cap(r.Tags) > n {
+		r.Tags = r.Tags[:n+1]
+	} else {
+		r.Tags = append(r.Tags, nil)
+	}
+
+	r.Tags[n] = append(r.Tags[n][:0], tag...)
+}
+
+// SetVary adds a request header, and its normalized value, which selects this response variant.
+func (r *Response) SetVary(k, v []byte) {
+	r.Vary = r.appendHeader(r.Vary, k, v)
+}
+
 // Reset reset response
 func (r *Response) Reset() {
+	r.Host = r.Host[:0]
 	r.Path = r.Path[:0]
 	r.Body = r.Body[:0]
 	r.Headers = r.Headers[:0]
+	r.Vary = r.Vary[:0]
+	r.Encoded = r.Encoded[:0]
+	r.Tags = r.Tags[:0]
+	r.StatusCode = 0
+	r.StoredAt = 0
+	r.TTL = 0
+	r.Grace = 0
+	r.Keep = 0
+	r.Retain = 0
+	r.Revalidate = false
+}
+
+// CopyTo copies the response to dst.
+func (r *Response) CopyTo(dst *Response) {
+	dst.Reset()
+
+	dst.Host = append(dst.Host, r.Host...)
+	dst.Path = append(dst.Path, r.Path...)
+	dst.Body = append(dst.Body, r.Body...)
+
+	for _, h := range r.Headers {
+		dst.SetHeader(h.Key, h.Value)
+	}
+
+	for _, h := range r.Vary {
+		dst.SetVary(h.Key, h.Value)
+	}
+
+	for _, e := range r.Encoded {
+		dst.SetEncodedBody(e.Encoding, e.Body)
+	}
+
+	for _, tag := range r.Tags {
+		dst.SetTag(tag)
+	}
+
+	dst.StatusCode = r.StatusCode
+	dst.StoredAt = r.StoredAt
+	dst.TTL = r.TTL
+	dst.Grace = r.Grace
+	dst.Keep = r.Keep
+	dst.Retain = r.Retain
+	dst.Revalidate = r.Revalidate
 }
@@ -7,6 +7,7 @@ import (
 
 func getResponseTest() Response {
 	return Response{
+		Host: []byte("www.kratgo.com"),
 		Path: []byte("/cache/"),
 		Body: []byte("Response body"),
 		Headers: []ResponseHeader{
@@ -27,13 +28,14 @@ func TestAcquireResponse(t *testing.T) {
 
 func TestReleaseResponse(t *testing.T) {
 	r := AcquireResponse()
+	r.Host = []byte("www.kratgo.com")
 	r.Path = []byte("/kratgo")
 	r.Body = []byte("Kratgo is ultra fast")
 	r.SetHeader([]byte("key"), []byte("value"))
 
 	ReleaseResponse(r)
 
-	if len(r.Path) > 0 || len(r.Body) > 0 || len(r.Headers) > 0 {
+	if len(r.Host) > 0 || len(r.Path) > 0 || len(r.Body) > 0 || len(r.Headers) > 0 {
 		t.Errorf("ReleaseResponse() response has not been reset")
 	}
 }
@@ -113,11 +115,308 @@ func TestResponse_HasHeader(t *testing.T) {
 	}
 }
 
+func TestResponse_GetHeader(t *testing.T) {
+	r := getResponseTest()
+
+	k := []byte("ETag")
+	v := []byte("\"kratgo\"")
+
+	r.SetHeader(k, v)
+
+	if value := r.GetHeader([]byte("etag")); !bytes.Equal(value, v) {
+		t.Errorf("Response.GetHeader() == '%s', want '%s'", value, v)
+	}
+
+	if value := r.GetHeader([]byte("Last-Modified")); value != nil {
+		t.Errorf("Response.GetHeader() == '%s', want '%v'", value, nil)
+	}
+}
+
+func TestResponse_SetVary(t *testing.T) {
+	r := getResponseTest()
+
+	k := []byte("Accept-Language")
+	v := []byte("es")
+
+	r.SetVary(k, v)
+
+	if !r.hasVary([]ResponseHeader{{Key: k, Value: v}}) {
+		t.Errorf("The vary header '%s = %s' has not been set", k, v)
+	}
+
+	if r.hasVary([]ResponseHeader{{Key: k, Value: []byte("en")}}) {
+		t.Errorf("Response.hasVary() matches other vary value")
+	}
+
+	if r.hasVary(nil) {
+		t.Errorf("Response.hasVary() matches without vary headers")
+	}
+}
+
+func TestResponse_IsExpired(t *testing.T) {
+	type args struct {
+		storedAt   int64
+		ttl        int64
+		revalidate bool
+		now        int64
+	}
+
+	type want struct {
+		expired bool
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Fresh",
+			args: args{storedAt: 100, ttl: 60, now: 159},
+			want: want{expired: false},
+		},
+		{
+			name: "Expired",
+			args: args{storedAt: 100, ttl: 60, now: 160},
+			want: want{expired: true},
+		},
+		{
+			name: "WithoutTTL",
+			args: args{storedAt: 100, ttl: 0, now: 100000},
+			want: want{expired: false},
+		},
+		{
+			name: "Revalidate",
+			args: args{storedAt: 100, ttl: 0, revalidate: true, now: 100},
+			want: want{expired: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := getResponseTest()
+			r.StoredAt = tt.args.storedAt
+			r.TTL = tt.args.ttl
+			r.Revalidate = tt.args.revalidate
+
+			if expired := r.IsExpired(tt.args.now); expired != tt.want.expired {
+				t.Errorf("Response.IsExpired() == '%v', want '%v'", expired, tt.want.expired)
+			}
+		})
+	}
+}
+
+func TestResponse_InGraceKeepAndRetain(t *testing.T) {
+	type args struct {
+		grace  int64
+		keep   int64
+		retain int64
+		now    int64
+
+		revalidate bool
+	}
+
+	type want struct {
+		inGrace  bool
+		inKeep   bool
+		inRetain bool
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Fresh",
+			args: args{grace: 30, keep: 60, retain: 120, now: 159},
+			want: want{inGrace: false, inKeep: false},
+		},
+		{
+			name: "InGraceAndKeep",
+			args: args{grace: 30, keep: 60, retain: 120, now: 189},
+			want: want{inGrace: true, inKeep: true, inRetain: true},
+		},
+		{
+			name: "InKeep",
+			args: args{grace: 30, keep: 60, retain: 120, now: 190},
+			want: want{inGrace: false, inKeep: true, inRetain: true},
+		},
+		{
+			name: "InRetain",
+			args: args{grace: 30, keep: 60, retain: 120, now: 220},
+			want: want{inGrace: false, inKeep: false, inRetain: true},
+		},
+		{
+			name: "OutOfWindows",
+			args: args{grace: 30, keep: 60, retain: 120, now: 280},
+			want: want{inGrace: false, inKeep: false},
+		},
+		{
+			name: "WithoutWindows",
+			args: args{now: 160},
+			want: want{inGrace: false, inKeep: false},
+		},
+		{
+			name: "Revalidate",
+			args: args{revalidate: true, now: 100000},
+			want: want{inGrace: false, inKeep: false, inRetain: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := getResponseTest()
+			r.StoredAt = 100
+			r.TTL = 60
+			r.Grace = tt.args.grace
+			r.Keep = tt.args.keep
+			r.Retain = tt.args.retain
+			r.Revalidate = tt.args.revalidate
+
+			if r.Revalidate {
+				r.TTL = 0
+			}
+
+			if inGrace := r.InGrace(tt.args.now); inGrace != tt.want.inGrace {
+				t.Errorf("Response.InGrace() == '%v', want '%v'", inGrace, tt.want.inGrace)
+			}
+
+			if inKeep := r.InKeep(tt.args.now); inKeep != tt.want.inKeep {
+				t.Errorf("Response.InKeep() == '%v', want '%v'", inKeep, tt.want.inKeep)
+			}
+
+			if inRetain := r.InRetain(tt.args.now); inRetain != tt.want.inRetain {
+				t.Errorf("Response.InRetain() == '%v', want '%v'", inRetain, tt.want.inRetain)
+			}
+		})
+	}
+}
+
+func TestResponse_EncodedBody(t *testing.T) {
+	r := getResponseTest()
+
+	gzip, br := []byte("gzip"), []byte("br")
+
+	if body := r.GetEncodedBody(gzip); body != nil {
+		t.Errorf("Response.GetEncodedBody() == '%s', want '%v'", body, nil)
+	}
+
+	r.SetEncodedBody(gzip, []byte("gzip body"))
+	r.SetEncodedBody(br, []byte("br body"))
+	r.SetEncodedBody(gzip, []byte("new gzip body"))
+
+	if len(r.Encoded) != 2 {
+		t.Errorf("Response.Encoded length == '%d', want '%d'", len(r.Encoded), 2)
+	}
+
+	if body := r.GetEncodedBody(gzip); string(body) != "new gzip body" {
+		t.Errorf("Response.GetEncodedBody() == '%s', want '%s'", body, "new gzip body")
+	}
+
+	if body := r.GetEncodedBody(br); string(body) != "br body" {
+		t.Errorf("Response.GetEncodedBody() == '%s', want '%s'", body, "br body")
+	}
+}
+
+func TestResponse_Tags(t *testing.T) {
+	r := getResponseTest()
+
+	if r.HasTag([]byte("product-42")) {
+		t.Errorf("Response.HasTag() == '%v', want '%v'", true, false)
+	}
+
+	r.SetTag([]byte("product-42"))
+	r.SetTag([]byte("category-7"))
+	r.SetTag([]byte("product-42"))
+
+	if len(r.Tags) != 2 {
+		t.Errorf("Response.Tags length == '%d', want '%d'", len(r.Tags), 2)
+	}
+
+	if !r.HasTag([]byte("product-42")) || !r.HasTag([]byte("category-7")) {
+		t.Errorf("Response.Tags == '%s', want '%s'", r.Tags, []string{"product-42", "category-7"})
+	}
+}
+
+func TestResponse_CopyTo(t *testing.T) {
+	r := getResponseTest()
+	r.SetVary([]byte("Accept-Language"), []byte("es"))
+	r.SetEncodedBody([]byte("gzip"), []byte("gzip body"))
+	r.SetTag([]byte("product-42"))
+	r.StatusCode = 404
+	r.StoredAt = 100
+	r.TTL = 60
+	r.Grace = 30
+	r.Keep = 120
+	r.Retain = 600
+	r.Revalidate = true
+
+	dst := AcquireResponse()
+	dst.SetHeader([]byte("Old"), []byte("Header"))
+
+	r.CopyTo(dst)
+
+	if !bytes.Equal(dst.Host, r.Host) || !bytes.Equal(dst.Path, r.Path) || !bytes.Equal(dst.Body, r.Body) {
+		t.Errorf("Response.CopyTo() == '%s%s %s', want '%s%s %s'", dst.Host, dst.Path, dst.Body, r.Host, r.Path, r.Body)
+	}
+
+	if len(dst.Headers) != len(r.Headers) || !dst.HasHeader(r.Headers[0].Key, r.Headers[0].Value) {
+		t.Errorf("Response.CopyTo() headers == '%s', want '%s'", dst.Headers, r.Headers)
+	}
+
+	if !dst.hasVary(r.Vary) {
+		t.Errorf("Response.CopyTo() vary == '%s', want '%s'", dst.Vary, r.Vary)
+	}
+
+	if body := dst.GetEncodedBody([]byte("gzip")); string(body) != "gzip body" {
+		t.Errorf("Response.CopyTo() encoded body == '%s', want '%s'", body, "gzip body")
+	}
+
+	if len(dst.Tags) != 1 || !dst.HasTag([]byte("product-42")) {
+		t.Errorf("Response.CopyTo() tags == '%s', want '%s'", dst.Tags, r.Tags)
+	}
+
+	if dst.StatusCode != r.StatusCode {
+		t.Errorf("Response.CopyTo() status code == '%d', want '%d'", dst.StatusCode, r.StatusCode)
+	}
+
+	if dst.StoredAt != r.StoredAt || dst.TTL != r.TTL || dst.Grace != r.Grace || dst.Keep != r.Keep || dst.Retain != r.Retain {
+		t.Errorf("Response.CopyTo() lifetime == '%d %d %d %d', want '%d %d %d %d'",
+			dst.StoredAt, dst.TTL, dst.Grace, dst.Keep, r.StoredAt, r.TTL, r.Grace, r.Keep)
+	}
+
+	if dst.Revalidate != r.Revalidate {
+		t.Errorf("Response.CopyTo() revalidate == '%v', want '%v'", dst.Revalidate, r.Revalidate)
+	}
+
+	r.Body[0] = 'X'
+	if bytes.Equal(dst.Body, r.Body) {
+		t.Error("Response.CopyTo() body shares memory with the source response")
+	}
+
+	ReleaseResponse(dst)
+}
+
 func TestResponse_Reset(t *testing.T) {
 	r := getResponseTest()
+	r.SetVary([]byte("Accept-Language"), []byte("es"))
+	r.SetEncodedBody([]byte("gzip"), []byte("gzip body"))
+	r.SetTag([]byte("product-42"))
+	r.StatusCode = 404
+	r.StoredAt = 100
+	r.TTL = 60
+	r.Grace = 30
+	r.Keep = 120
+	r.Retain = 600
+	r.Revalidate = true
 
 	r.Reset()
 
+	if len(r.Host) > 0 {
+		t.Errorf("Response.Host has not been reset")
+	}
+
 	if len(r.Path) > 0 {
 		t.Errorf("Response.Path has not been reset")
 	}
@@ -129,4 +428,28 @@ func TestResponse_Reset(t *testing.T) {
 	if len(r.Headers) > 0 {
 		t.Errorf("Response.Headers has not been reset")
 	}
+
+	if len(r.Vary) > 0 {
+		t.Errorf("Response.Vary has not been reset")
+	}
+
+	if len(r.Encoded) > 0 {
+		t.Errorf("Response.Encoded has not been reset")
+	}
+
+	if len(r.Tags) > 0 {
+		t.Errorf("Response.Tags has not been reset")
+	}
+
+	if r.StatusCode != 0 {
+		t.Errorf("Response.StatusCode has not been reset")
+	}
+
+	if r.StoredAt != 0 || r.TTL != 0 || r.Grace != 0 || r.Keep != 0 || r.Retain != 0 {
+		t.Errorf("Response.StoredAt, Response.TTL, Response.Grace, Response.Keep and Response.Retain have not been reset")
+	}
+
+	if r.Revalidate {
+		t.Errorf("Response.Revalidate has not been reset")
+	}
 }
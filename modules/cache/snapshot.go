@@ -0,0 +1,368 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/savsgio/gotils/strconv"
+)
+
+// The snapshot starts with snapshotMagic followed by a record per cache entry:
+//
+//	uvarint key size | key | uvarint expires at (unix) | uvarint data size | data (msgp encoded Entry) | crc32
+//
+// The crc32 covers the key, the expiration and the data.
+
+var snapshotCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+func snapshotChecksum(key []byte, expiresAt int64, data []byte) uint32 {
+	var buf [binary.MaxVarintLen64]byte
+
+	crc := crc32.Update(0, snapshotCRCTable, key)
+	crc = crc32.Update(crc, snapshotCRCTable, binary.AppendUvarint(buf[:0], uint64(expiresAt)))
+
+	return crc32.Update(crc, snapshotCRCTable, data)
+}
+
+func appendSnapshotRecord(dst []byte, key string, expiresAt int64, data []byte) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(key)))
+	dst = append(dst, key...)
+	dst = binary.AppendUvarint(dst, uint64(expiresAt))
+	dst = binary.AppendUvarint(dst, uint64(len(data)))
+	dst = append(dst, data...)
+
+	return binary.BigEndian.AppendUint32(dst, snapshotChecksum(strconv.S2B(key), expiresAt, data))
+}
+
+// readSnapshotField reads the next field into dst. The fields bigger than maxSize, if greater than 0,
+// are discarded without reading them into memory, and false is returned.
+func readSnapshotField(r *bufio.Reader, dst []byte, maxSize int) ([]byte, bool, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return dst, false, err
+	} else if n > snapshotMaxRecordSize {
+		return dst, false, ErrInvalidSnapshot
+	}
+
+	if maxSize > 0 && n > uint64(maxSize) {
+		_, err = r.Discard(int(n))
+
+		return dst[:0], false, unexpectedEOF(err)
+	}
+
+	if uint64(cap(dst)) < n {
+		dst = make([]byte, n)
+	}
+	dst = dst[:n]
+
+	_, err = io.ReadFull(r, dst)
+
+	return dst, true, unexpectedEOF(err)
+}
+
+// readSnapshotRecord reads the next record into rec, and returns false if its checksum does not match.
+// The key and the data bigger than maxSize are not read, and the record is flagged as too large.
+func readSnapshotRecord(r *bufio.Reader, rec *snapshotRecord, maxSize int) (bool, error) {
+	var keyRead, dataRead bool
+	var err error
+
+	if rec.key, keyRead, err = readSnapshotField(r, rec.key, maxSize); err != nil {
+		return false, err
+	}
+
+	expiresAt, err := binary.ReadUvarint(r)
+	if err != nil {
+		return false, unexpectedEOF(err)
+	}
+	rec.expiresAt = int64(expiresAt)
+
+	if rec.data, dataRead, err = readSnapshotField(r, rec.data, maxSize); err != nil {
+		return false, unexpectedEOF(err)
+	}
+
+	var crc [4]byte
+	if _, err = io.ReadFull(r, crc[:]); err != nil {
+		return false, unexpectedEOF(err)
+	}
+
+	rec.tooLarge = !keyRead || !dataRead
+	if rec.tooLarge {
+		return true, nil
+	}
+
+	return binary.BigEndian.Uint32(crc[:]) == snapshotChecksum(rec.key, rec.expiresAt, rec.data), nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+// expiresAt returns the unix time when all responses of the entry expire, including their stale windows.
+// The responses without an explicit lifetime expire after the ttl of the cache.
+func (c *Cache) expiresAt(entry Entry) int64 {
+	defaultTTL := int64(c.fileConfig.TTL) * 60
+	result := int64(0)
+
+	for i, n := 0, len(entry.Responses); i < n; i++ {
+		resp := &entry.Responses[i]
+
+		expiresAt := resp.StoredAt + defaultTTL
+		if resp.TTL > 0 {
+			expiresAt = resp.StoredAt + resp.TTL + resp.window()
+		}
+
+		if expiresAt > result {
+			result = expiresAt
+		}
+	}
+
+	return result
+}
+
+// WriteSnapshot writes all cache entries to w, and returns the number of written entries.
+func (c *Cache) WriteSnapshot(w io.Writer) (int, error) {
+	bw := bufio.NewWriter(w)
+
+	if _, err := bw.Write(snapshotMagic); err != nil {
+		return 0, err
+	}
+
+	var record []byte
+	count := 0
+
+	entry := AcquireEntry()
+	defer ReleaseEntry(entry)
+
+	iter := c.storage.Iterator()
+	for iter.SetNext() {
+		key, data, err := iter.Value()
+		if err != nil {
+			c.log.Errorf("Could not get value from iterator: %v", err)
+			continue
+		}
+
+		entry.Reset()
+
+		if err := Unmarshal(entry, data); err != nil {
+			c.log.Errorf("Could not decode cache value of '%s': %v", key, err)
+			continue
+		}
+
+		record = appendSnapshotRecord(record[:0], key, c.expiresAt(*entry), data)
+		if _, err := bw.Write(record); err != nil {
+			return count, err
+		}
+
+		count++
+	}
+
+	return count, bw.Flush()
+}
+
+// ReadSnapshot saves in cache the entries of the snapshot read from r, over the current ones.
+// The expired, too large and corrupted entries are skipped.
+func (c *Cache) ReadSnapshot(r io.Reader) (SnapshotReport, error) {
+	return c.readSnapshot(r, SnapshotMerge, 0)
+}
+
+// ImportSnapshot saves in cache the entries of the snapshot read from r, according to the mode
+// (SnapshotMerge or SnapshotReplace). The expired, corrupted and too large entries,
+// bigger than Cache.MaxEntrySize, are skipped.
+func (c *Cache) ImportSnapshot(r io.Reader, mode string) (SnapshotReport, error) {
+	if mode != SnapshotMerge && mode != SnapshotReplace {
+		return SnapshotReport{}, fmt.Errorf("Invalid snapshot mode '%s'", mode)
+	}
+
+	return c.readSnapshot(r, mode, c.fileConfig.MaxEntrySize)
+}
+
+func (c *Cache) readSnapshot(r io.Reader, mode string, maxEntrySize int) (SnapshotReport, error) {
+	report := SnapshotReport{}
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(snapshotMagic))
+	version := len(magic) - 1
+
+	if _, err := io.ReadFull(br, magic); err != nil || !bytes.Equal(magic[:version], snapshotMagic[:version]) {
+		return report, ErrInvalidSnapshot
+	} else if magic[version] != snapshotMagic[version] {
+		return report, fmt.Errorf("%v: '%d', want '%d'", ErrSnapshotVersion, magic[version], snapshotMagic[version])
+	}
+
+	rec := &snapshotRecord{}
+
+	entry := AcquireEntry()
+	defer ReleaseEntry(entry)
+
+	// In replace mode, the entries are saved once the whole snapshot is read, so an invalid one never
+	// leaves the cache empty
+	var pending []snapshotRecord
+
+	now := time.Now().Unix()
+
+	for {
+		ok, err := readSnapshotRecord(br, rec, maxEntrySize)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return report, fmt.Errorf("%v: %v", ErrInvalidSnapshot, err)
+		}
+
+		if rec.tooLarge {
+			report.TooLarge++
+			continue
+		}
+
+		entry.Reset()
+
+		if !ok || Unmarshal(entry, rec.data) != nil {
+			report.Corrupted++
+			continue
+		}
+
+		if rec.expiresAt <= now {
+			report.Expired++
+			continue
+		}
+
+		if mode == SnapshotReplace {
+			pending = append(pending, snapshotRecord{
+				key:  append([]byte(nil), rec.key...),
+				data: append([]byte(nil), rec.data...),
+			})
+
+			continue
+		}
+
+		if err := c.saveSnapshotEntry(rec.key, entry, &report); err != nil {
+			return report, err
+		}
+	}
+
+	if mode != SnapshotReplace {
+		return report, nil
+	}
+
+	if err := c.Reset(); err != nil {
+		return report, fmt.Errorf("Could not reset the cache: %v", err)
+	}
+
+	for i := range pending {
+		entry.Reset()
+
+		if err := Unmarshal(entry, pending[i].data); err != nil {
+			return report, fmt.Errorf("Could not decode snapshot entry '%s': %v", pending[i].key, err)
+		}
+
+		if err := c.saveSnapshotEntry(pending[i].key, entry, &report); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// saveSnapshotEntry saves in cache the entry of a snapshot record, and counts it in the report.
+func (c *Cache) saveSnapshotEntry(key []byte, entry *Entry, report *SnapshotReport) error {
+	if err := c.SetBytes(key, *entry); err == ErrEntryTooLarge {
+		report.TooLarge++
+
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("Could not save snapshot entry '%s': %v", key, err)
+	}
+
+	report.Accepted++
+
+	return nil
+}
+
+// SaveSnapshot writes the snapshot of the cache to the configured directory.
+func (c *Cache) SaveSnapshot() error {
+	dir := c.fileConfig.Snapshot.Dir
+	if dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("Could not create the snapshot directory '%s': %v", dir, err)
+	}
+
+	// Write to a temporary file and rename it, so a failure never leaves a truncated snapshot
+	f, err := os.CreateTemp(dir, snapshotFileName+".*")
+	if err != nil {
+		return fmt.Errorf("Could not create the snapshot file: %v", err)
+	}
+
+	count, err := c.WriteSnapshot(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+
+		return fmt.Errorf("Could not write the snapshot file '%s': %v", f.Name(), err)
+	}
+
+	path := filepath.Join(dir, snapshotFileName)
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+
+		return fmt.Errorf("Could not save the snapshot file '%s': %v", path, err)
+	}
+
+	c.log.Infof("Saved %d entries in snapshot '%s'", count, path)
+
+	return nil
+}
+
+// LoadSnapshot saves in cache the entries of the snapshot in the configured directory, if exists.
+func (c *Cache) LoadSnapshot() (SnapshotReport, error) {
+	dir := c.fileConfig.Snapshot.Dir
+	if dir == "" {
+		return SnapshotReport{}, nil
+	}
+
+	path := filepath.Join(dir, snapshotFileName)
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return SnapshotReport{}, nil
+	} else if err != nil {
+		return SnapshotReport{}, fmt.Errorf("Could not open the snapshot file '%s': %v", path, err)
+	}
+	defer f.Close()
+
+	report, err := c.ReadSnapshot(f)
+	if err != nil {
+		return report, fmt.Errorf("Could not load the snapshot file '%s': %v", path, err)
+	}
+
+	c.log.Infof("Loaded snapshot '%s': %d accepted, %d expired, %d too large, %d corrupted entries",
+		path, report.Accepted, report.Expired, report.TooLarge, report.Corrupted)
+
+	return report, nil
+}
+
+// snapshotter saves the snapshot of the cache periodically.
+func (c *Cache) snapshotter(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+
+	for range ticker.C {
+		if err := c.SaveSnapshot(); err != nil {
+			c.log.Error(err)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package cache
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	logger "github.com/savsgio/go-logger/v4"
+	"github.com/savsgio/kratgo/modules/config"
+)
+
+func newSnapshotTestCache(t *testing.T, cfg config.Cache) *Cache {
+	cfg.TTL = 10
+	cfg.CleanFrequency = 5
+
+	c, err := New(Config{
+		FileConfig: cfg,
+		LogLevel:   logger.FATAL,
+		LogOutput:  os.Stderr,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func snapshotTestEntry(host, path string, storedAt, ttl int64) Entry {
+	return Entry{
+		Responses: []Response{
+			{
+				Host:     []byte(host),
+				Path:     []byte(path),
+				Body:     []byte("Response body"),
+				StoredAt: storedAt,
+				TTL:      ttl,
+			},
+		},
+	}
+}
+
+func TestCache_expiresAt(t *testing.T) {
+	c := newSnapshotTestCache(t, config.Cache{Storage: LRUStorage})
+
+	now := int64(10000)
+
+	tests := []struct {
+		name      string
+		entry     Entry
+		expiresAt int64
+	}{
+		{
+			name:      "TTL",
+			entry:     snapshotTestEntry("www.kratgo.com", "/", now, 60),
+			expiresAt: now + 60,
+		},
+		{
+			name:      "WithoutTTL",
+			entry:     snapshotTestEntry("www.kratgo.com", "/", now, 0),
+			expiresAt: now + 600,
+		},
+		{
+			name: "StaleWindows",
+			entry: Entry{Responses: []Response{
+				{StoredAt: now, TTL: 60, Grace: 30, Keep: 3600},
+			}},
+			expiresAt: now + 3660,
+		},
+		{
+			name: "Latest",
+			entry: Entry{Responses: []Response{
+				{StoredAt: now, TTL: 60},
+				{StoredAt: now + 100, TTL: 60},
+			}},
+			expiresAt: now + 160,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if expiresAt := c.expiresAt(tt.entry); expiresAt != tt.expiresAt {
+				t.Errorf("Cache.expiresAt() == '%d', want '%d'", expiresAt, tt.expiresAt)
+			}
+		})
+	}
+}
+
+func TestCache_WriteAndReadSnapshot(t *testing.T) {
+	src := newSnapshotTestCache(t, config.Cache{Storage: LRUStorage})
+
+	now := time.Now().Unix()
+	host := "www.kratgo.com"
+
+	fresh := snapshotTestEntry(host, "/fresh/", now, 3600)
+	src.Set(host+"/fresh/", fresh)
+	src.Set(host+"/expired/", snapshotTestEntry(host, "/expired/", now-7200, 60))
+
+	buf := bytes.NewBuffer(nil)
+
+	count, err := src.WriteSnapshot(buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if count != 2 {
+		t.Errorf("Cache.WriteSnapshot() count == '%d', want '%d'", count, 2)
+	}
+
+	dst := newSnapshotTestCache(t, config.Cache{})
+
+	report, err := dst.ReadSnapshot(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	wantReport := SnapshotReport{Accepted: 1, Expired: 1}
+	if report != wantReport {
+		t.Errorf("Cache.ReadSnapshot() == '%+v', want '%+v'", report, wantReport)
+	}
+
+	entry := AcquireEntry()
+	if err := dst.Get(host+"/fresh/", entry); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if r := entry.GetResponse([]byte("/fresh/")); r == nil || !bytes.Equal(r.Body, fresh.Responses[0].Body) {
+		t.Errorf("Cache.ReadSnapshot() the entry '%s' has not been loaded", host+"/fresh/")
+	}
+
+	if keys := dst.HostKeys(host); len(keys) != 1 {
+		t.Errorf("Cache.ReadSnapshot() host keys == '%v', want '%v'", keys, []string{host + "/fresh/"})
+	}
+}
+
+func TestCache_ReadSnapshot(t *testing.T) {
+	now := time.Now().Unix()
+	host := "www.kratgo.com"
+
+	entry := snapshotTestEntry(host, "/", now, 3600)
+	data, _ := Marshal(entry)
+
+	snapshot := append([]byte{}, snapshotMagic...)
+	snapshot = appendSnapshotRecord(snapshot, host+"/", now+3600, data)
+
+	corrupted := append([]byte{}, snapshot...)
+	corrupted[len(corrupted)-5] ^= 0xff
+
+	big := snapshotTestEntry(host, "/big/", now, 3600)
+	big.Responses[0].Body = bytes.Repeat([]byte("k"), 2*1024*1024)
+	bigData, _ := Marshal(big)
+
+	tests := []struct {
+		name     string
+		cfg      config.Cache
+		snapshot []byte
+		report   SnapshotReport
+		err      bool
+	}{
+		{
+			name:     "Ok",
+			snapshot: snapshot,
+			report:   SnapshotReport{Accepted: 1},
+		},
+		{
+			name:     "Corrupted",
+			snapshot: corrupted,
+			report:   SnapshotReport{Corrupted: 1},
+		},
+		{
+			name:     "TooLarge",
+			cfg:      config.Cache{Storage: LRUStorage, HardMaxCacheSize: 1},
+			snapshot: appendSnapshotRecord(append([]byte{}, snapshotMagic...), host+"/big/", now+3600, bigData),
+			report:   SnapshotReport{TooLarge: 1},
+		},
+		{
+			name:     "Expired",
+			snapshot: appendSnapshotRecord(append([]byte{}, snapshotMagic...), host+"/", now, data),
+			report:   SnapshotReport{Expired: 1},
+		},
+		{
+			name:     "Truncated",
+			snapshot: snapshot[:len(snapshot)-10],
+			err:      true,
+		},
+		{
+			name:     "InvalidMagic",
+			snapshot: []byte("KRATGO"),
+			err:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newSnapshotTestCache(t, tt.cfg)
+
+			report, err := c.ReadSnapshot(bytes.NewReader(tt.snapshot))
+			if (err != nil) != tt.err {
+				t.Fatalf("Cache.ReadSnapshot() error == '%v', want error '%v'", err, tt.err)
+			}
+
+			if report != tt.report {
+				t.Errorf("Cache.ReadSnapshot() == '%+v', want '%+v'", report, tt.report)
+			}
+		})
+	}
+
+	// The snapshots of other format versions are rejected, instead of reading their records as corrupted
+	other := append([]byte("KRATGO-SNAPSHOT\x02"), snapshot[len(snapshotMagic):]...)
+	if _, err := newSnapshotTestCache(t, config.Cache{}).ReadSnapshot(bytes.NewReader(other)); err == nil ||
+		!strings.HasPrefix(err.Error(), ErrSnapshotVersion.Error()) {
+		t.Errorf("Cache.ReadSnapshot() error == '%v', want '%v'", err, ErrSnapshotVersion)
+	}
+}
+
+func TestCache_ImportSnapshot(t *testing.T) {
+	now := time.Now().Unix()
+	host := "www.kratgo.com"
+
+	data, _ := Marshal(snapshotTestEntry(host, "/", now, 3600))
+	snapshot := appendSnapshotRecord(append([]byte{}, snapshotMagic...), host+"/", now+3600, data)
+
+	big := snapshotTestEntry(host, "/big/", now, 3600)
+	big.Responses[0].Body = bytes.Repeat([]byte("k"), 1024)
+	bigData, _ := Marshal(big)
+
+	withBig := appendSnapshotRecord(append([]byte{}, snapshotMagic...), host+"/big/", now+3600, bigData)
+	withBig = appendSnapshotRecord(withBig, host+"/", now+3600, data)
+
+	tests := []struct {
+		name         string
+		mode         string
+		snapshot     []byte
+		maxEntrySize int
+		report       SnapshotReport
+		len          int
+		err          bool
+	}{
+		{
+			name:     "Merge",
+			mode:     SnapshotMerge,
+			snapshot: snapshot,
+			report:   SnapshotReport{Accepted: 1},
+			len:      2,
+		},
+		{
+			name:     "Replace",
+			mode:     SnapshotReplace,
+			snapshot: snapshot,
+			report:   SnapshotReport{Accepted: 1},
+			len:      1,
+		},
+		{
+			name:     "ReplaceTruncated",
+			mode:     SnapshotReplace,
+			snapshot: withBig[:len(withBig)-10],
+			len:      1,
+			err:      true,
+		},
+		{
+			name:         "TooLargeForMaxEntrySize",
+			mode:         SnapshotMerge,
+			snapshot:     snapshot,
+			maxEntrySize: 10,
+			report:       SnapshotReport{TooLarge: 1},
+			len:          1,
+		},
+		{
+			name:         "TooLargeSkipped",
+			mode:         SnapshotReplace,
+			snapshot:     withBig,
+			maxEntrySize: len(data),
+			report:       SnapshotReport{Accepted: 1, TooLarge: 1},
+			len:          1,
+		},
+		{
+			name:     "InvalidMode",
+			mode:     "append",
+			snapshot: snapshot,
+			len:      1,
+			err:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newSnapshotTestCache(t, config.Cache{MaxEntrySize: tt.maxEntrySize})
+			c.Set(host+"/current/", snapshotTestEntry(host, "/current/", now, 3600))
+
+			report, err := c.ImportSnapshot(bytes.NewReader(tt.snapshot), tt.mode)
+			if (err != nil) != tt.err {
+				t.Fatalf("Cache.ImportSnapshot() error == '%v', want error '%v'", err, tt.err)
+			}
+
+			if report != tt.report {
+				t.Errorf("Cache.ImportSnapshot() == '%+v', want '%+v'", report, tt.report)
+			}
+
+			if n := c.Len(); n != tt.len {
+				t.Errorf("Cache.Len() == '%d', want '%d'", n, tt.len)
+			}
+		})
+	}
+}
+
+func TestCache_SaveAndLoadSnapshot(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "snapshots")
+	host := "www.kratgo.com"
+
+	src := newSnapshotTestCache(t, config.Cache{Snapshot: config.CacheSnapshot{Dir: dir}})
+	src.Set(host+"/", snapshotTestEntry(host, "/", time.Now().Unix(), 3600))
+
+	if err := src.SaveSnapshot(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 1 || filepath.Base(files[0]) != snapshotFileName {
+		t.Errorf("Cache.SaveSnapshot() files == '%v', want '%v'", files, []string{snapshotFileName})
+	}
+
+	dst := newSnapshotTestCache(t, config.Cache{Snapshot: config.CacheSnapshot{Dir: dir}})
+
+	report, err := dst.LoadSnapshot()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if report.Accepted != 1 || dst.Len() != 1 {
+		t.Errorf("Cache.LoadSnapshot() accepted == '%d', want '%d'", report.Accepted, 1)
+	}
+
+	empty := newSnapshotTestCache(t, config.Cache{Snapshot: config.CacheSnapshot{Dir: t.TempDir()}})
+	if report, err := empty.LoadSnapshot(); err != nil || report != (SnapshotReport{}) {
+		t.Errorf("Cache.LoadSnapshot() == '%+v, %v', want '%+v, %v'", report, err, SnapshotReport{}, nil)
+	}
+
+	os.WriteFile(filepath.Join(dir, snapshotFileName), []byte("invalid"), 0644)
+	if _, err := dst.LoadSnapshot(); err == nil {
+		t.Errorf("Cache.LoadSnapshot() error == '%v', want '%v'", err, ErrInvalidSnapshot)
+	}
+}
@@ -1,7 +1,10 @@
 package cache
 
 import (
+	"container/list"
 	"io"
+	"sync"
+	"time"
 
 	"github.com/allegro/bigcache/v3"
 	"github.com/savsgio/go-logger/v4"
@@ -20,5 +23,113 @@ type Config struct {
 type Cache struct {
 	fileConfig config.Cache
 
+	storage Storage
+
+	log *logger.Logger
+
+	hosts   map[string]map[string]struct{} // host -> keys
+	keys    map[string]string              // key -> host
+	tags    map[string]map[string]struct{} // tag -> keys
+	keyTags map[string][]string            // key -> tags
+	mu      sync.RWMutex
+}
+
+// Stats ...
+type Stats struct {
+	Hits       int64 `json:"hits"`
+	Misses     int64 `json:"misses"`
+	DelHits    int64 `json:"delete_hits"`
+	DelMisses  int64 `json:"delete_misses"`
+	Collisions int64 `json:"collisions"`
+
+	Entries  int `json:"entries"`
+	Capacity int `json:"capacity"` // Bytes
+}
+
+// SnapshotReport ...
+type SnapshotReport struct {
+	Accepted  int `json:"accepted"`
+	Expired   int `json:"expired"`
+	TooLarge  int `json:"too_large"`
+	Corrupted int `json:"corrupted"`
+}
+
+type snapshotRecord struct {
+	key       []byte
+	expiresAt int64
+	data      []byte
+	tooLarge  bool
+}
+
+type bigcacheStorage struct {
 	bc *bigcache.BigCache
+
+	maxEntrySize int // Bytes, 0 means unlimited size
+}
+
+type bigcacheIterator struct {
+	iter *bigcache.EntryInfoIterator
+}
+
+type lruStorage struct {
+	items map[string]*list.Element
+	ll    *list.List
+
+	size       int
+	maxSize    int // Bytes, 0 means unlimited size
+	defaultTTL time.Duration
+
+	stats Stats
+
+	// Called with the lock held, in the same order as the removals, so it must not use the storage
+	onRemove func(key string)
+
+	mu sync.RWMutex
+}
+
+type lruItem struct {
+	key       string
+	data      []byte
+	expiresAt time.Time
+}
+
+type lruIterator struct {
+	storage *lruStorage
+
+	keys []string
+	pos  int
+	data []byte
+}
+
+// ###### INTERFACES ######
+
+// Storage is where the cache saves the encoded entries.
+type Storage interface {
+	// Get returns the data of the key, or ErrEntryNotFound.
+	Get(key string) ([]byte, error)
+
+	// Set saves the data under the key, with a lifetime of ttl if the storage supports it.
+	Set(key string, data []byte, ttl time.Duration) error
+
+	// Update replaces the data of the key keeping its expiration, or returns ErrEntryNotFound.
+	// Returns ErrExpirationNotKept if the storage could not keep the expiration.
+	Update(key string, data []byte) error
+
+	// Delete removes the key, or returns ErrEntryNotFound.
+	Delete(key string) error
+
+	Iterator() Iterator
+	Len() int
+
+	// Capacity returns the bytes used by the storage.
+	Capacity() int
+
+	Reset() error
+	Stats() Stats
+}
+
+// Iterator walks over the entries of a storage.
+type Iterator interface {
+	SetNext() bool
+	Value() (key string, data []byte, err error)
 }
@@ -8,6 +8,8 @@ const configStatusCodeVar = "$(statusCode)"
 const configReqHeaderVar = "$(req.header::<NAME>)"
 const configRespHeaderVar = "$(resp.header::<NAME>)"
 const configCookieVar = "$(cookie::<NAME>)"
+const configQueryVar = "$(query)"
+const configQueryArgVar = "$(query::<NAME>)"
 
 // EvalVarPrefix ...
 const EvalVarPrefix = "Krat"
@@ -35,3 +37,9 @@ const EvalRespHeaderVar = EvalVarPrefix + "RESPHEADER"
 
 // EvalCookieVar ...
 const EvalCookieVar = EvalVarPrefix + "COOKIE"
+
+// EvalQueryVar ...
+const EvalQueryVar = EvalVarPrefix + "QUERY"
+
+// EvalQueryArgVar ...
+const EvalQueryArgVar = EvalVarPrefix + "QUERYARG"
@@ -18,6 +18,8 @@ var configEvaluationVars = map[string]string{
 	configReqHeaderVar:   EvalReqHeaderVar,
 	configRespHeaderVar:  EvalRespHeaderVar,
 	configCookieVar:      EvalCookieVar,
+	configQueryVar:       EvalQueryVar,
+	configQueryArgVar:    EvalQueryArgVar,
 }
 
 // ConfigVarRegex ...
@@ -32,6 +34,9 @@ var ConfigRespHeaderVarRegex = regexp.MustCompile("\\$\\(resp\\.header::([a-zA-Z
 // ConfigCookieVarRegex ...
 var ConfigCookieVarRegex = regexp.MustCompile("\\$\\(cookie::([a-zA-Z0-9\\-\\_]+)\\)")
 
+// ConfigQueryArgVarRegex ...
+var ConfigQueryArgVarRegex = regexp.MustCompile("\\$\\(query::([a-zA-Z0-9\\-\\_\\.]+)\\)")
+
 // Parse ...
 func Parse(path string) (*Config, error) {
 	data, err := ioutil.ReadFile(path)
@@ -53,7 +58,7 @@ func GetEvalParamName(k string) string {
 	if k == configReqHeaderVar || k == configRespHeaderVar {
 		return fmt.Sprintf("%s%d", configEvaluationVars[k], rand.Int31n(100))
 
-	} else if k == configCookieVar {
+	} else if k == configCookieVar || k == configQueryArgVar {
 		return fmt.Sprintf("%s%d", configEvaluationVars[k], rand.Int31n(100))
 	}
 
@@ -84,6 +89,12 @@ func ParseConfigKeys(s string) (string, string, string) {
 				return data[0], GetEvalParamName(k), data[1]
 			}
 
+		} else if k == configQueryArgVar {
+			data := ConfigQueryArgVarRegex.FindStringSubmatch(s)
+			if len(data) > 1 {
+				return data[0], GetEvalParamName(k), data[1]
+			}
+
 		} else {
 			data := ConfigVarRegex.FindStringSubmatch(s)
 			if len(data) > 0 && data[0] == k {
@@ -7,21 +7,30 @@ import (
 	"reflect"
 	"regexp"
 	"testing"
+	"time"
 )
 
 var yamlConfig = []byte(`logLevel: debug
 logOutput: console
 
 cache:
+  storage: lru
   ttl: 10
   cleanFrequency: 1
   maxEntries: 600000
   maxEntrySize: 500
   hardMaxCacheSize: 0
+  snapshot:
+    dir: /var/lib/kratgo
+    interval: 10m
 
 invalidator:
   maxWorkers: 5
 
+warmer:
+  concurrency: 4
+  rate: 50
+
 proxy:
   addr: 0.0.0.0:6081
   backendAddrs:
@@ -38,10 +47,93 @@ proxy:
         - name: Set-Cookie
           if: $(path) !~ '/preview/' && $(path) !~ '/exit_preview/' && $(cookie::is_preview) == 'True'
 
+    diagnostics:
+      xCache: true
+      age: true
+      cacheStatus: true
+      node: kratgo-1
+
   nocache:
     - $(method) == 'POST'
     - $(host) == 'www.kratgo.com'
 
+  cache:
+    key: $(host)$(path)
+    rules:
+      - if: $(path) =~ '^/search/'
+        key: $(host)$(path)?$(query::q)
+    maxObjectSize: 10485760
+    tagsHeader: Surrogate-Key
+    statuses:
+      301: 1h
+      404: 30s
+    admission:
+      - if: $(path) =~ '^/search/'
+        policy: nth
+        requests: 3
+        window: 1m
+      - policy: frequency
+
+  coalescing:
+    enabled: true
+    timeout: 5s
+
+  stale:
+    grace: 30s
+    keep: 1h
+
+  revalidation:
+    retain: 24h
+
+  healthCheck:
+    path: /health
+    host: www.kratgo.com
+    interval: 5s
+    timeout: 2s
+    status: 204
+    rise: 2
+    fall: 3
+
+  circuitBreaker:
+    enabled: true
+    failureRate: 0.25
+    minRequests: 20
+    window: 1m
+    coolDown: 15s
+    halfOpenRequests: 3
+    errorPage: /etc/kratgo/503.html
+
+  balancer:
+    strategy: hash
+    hashKey: $(cookie::session)
+    weights:
+      1.2.3.4:5678: 3
+
+  retry:
+    attempts: 3
+    statuses: [502, 504]
+    budget: 0.1
+    minRetries: 5
+
+  pools:
+    - name: api
+      backendAddrs: [127.0.0.1:8001, 127.0.0.1:8002]
+
+  routes:
+    - host: "*.kratgo.com"
+      path: /api/
+      if: $(method) == 'GET'
+      pool: api
+      ttl: 5m
+      nocache:
+        - $(query::nocache) == '1'
+      headers:
+        set:
+          - name: X-Route
+            value: api
+        unset:
+          - name: Set-Cookie
+
 admin:
   addr: 0.0.0.0:6082
 `)
@@ -123,6 +215,11 @@ func TestParse(t *testing.T) {
 				t.Fatalf("Parse() LogOutput == '%s', want '%s'", cfg.LogOutput, logOutput)
 			}
 
+			cacheStorage := "lru"
+			if cfg.Cache.Storage != cacheStorage {
+				t.Fatalf("Parse() Cache.Storage == '%s', want '%s'", cfg.Cache.Storage, cacheStorage)
+			}
+
 			cacheTTL := 10
 			if cfg.Cache.TTL != cacheTTL {
 				t.Fatalf("Parse() Cache.TTL == '%d', want '%d'", cfg.Cache.TTL, cacheTTL)
@@ -148,11 +245,21 @@ func TestParse(t *testing.T) {
 				t.Fatalf("Parse() Cache.HardMaxCacheSize == '%d', want '%d'", cfg.Cache.HardMaxCacheSize, cacheHardMaxCacheSize)
 			}
 
+			cacheSnapshot := CacheSnapshot{Dir: "/var/lib/kratgo", Interval: 10 * time.Minute}
+			if cfg.Cache.Snapshot != cacheSnapshot {
+				t.Fatalf("Parse() Cache.Snapshot == '%v', want '%v'", cfg.Cache.Snapshot, cacheSnapshot)
+			}
+
 			invalidatorMaxWorkers := int32(5)
 			if cfg.Invalidator.MaxWorkers != invalidatorMaxWorkers {
 				t.Fatalf("Parse() Invalidator.MaxWorkers == '%d', want '%d'", cfg.Invalidator.MaxWorkers, invalidatorMaxWorkers)
 			}
 
+			warmer := Warmer{Concurrency: 4, Rate: 50}
+			if cfg.Warmer != warmer {
+				t.Fatalf("Parse() Warmer == '%v', want '%v'", cfg.Warmer, warmer)
+			}
+
 			proxyAddr := "0.0.0.0:6081"
 			if cfg.Proxy.Addr != proxyAddr {
 				t.Fatalf("Parse() Proxy.Addr == '%s', want '%s'", cfg.Proxy.Addr, proxyAddr)
@@ -173,11 +280,116 @@ func TestParse(t *testing.T) {
 				t.Fatalf("Parse() Proxy.Response.Headers.Unset == '%v', want '%v'", cfg.Proxy.Response.Headers.Unset, proxyResponseHeadersUnset)
 			}
 
+			proxyResponseDiagnostics := Diagnostics{XCache: true, Age: true, CacheStatus: true, Node: "kratgo-1"}
+			if cfg.Proxy.Response.Diagnostics != proxyResponseDiagnostics {
+				t.Fatalf("Parse() Proxy.Response.Diagnostics == '%v', want '%v'", cfg.Proxy.Response.Diagnostics, proxyResponseDiagnostics)
+			}
+
 			proxyNocache := []string{"$(method) == 'POST'", "$(host) == 'www.kratgo.com'"}
 			if !reflect.DeepEqual(cfg.Proxy.Nocache, proxyNocache) {
 				t.Fatalf("Parse() Proxy.Nocache == '%v', want '%v'", cfg.Proxy.Nocache, proxyNocache)
 			}
 
+			proxyCache := ProxyCache{
+				Key:           "$(host)$(path)",
+				Rules:         []ProxyCacheRule{{When: "$(path) =~ '^/search/'", Key: "$(host)$(path)?$(query::q)"}},
+				MaxObjectSize: 10485760,
+				TagsHeader:    "Surrogate-Key",
+				Statuses:      map[int]time.Duration{301: time.Hour, 404: 30 * time.Second},
+				Admission: []AdmissionRule{
+					{When: "$(path) =~ '^/search/'", Policy: "nth", Requests: 3, Window: time.Minute},
+					{Policy: "frequency"},
+				},
+			}
+			if !reflect.DeepEqual(cfg.Proxy.Cache, proxyCache) {
+				t.Fatalf("Parse() Proxy.Cache == '%v', want '%v'", cfg.Proxy.Cache, proxyCache)
+			}
+
+			proxyCoalescing := Coalescing{Enabled: true, Timeout: 5 * time.Second}
+			if cfg.Proxy.Coalescing != proxyCoalescing {
+				t.Fatalf("Parse() Proxy.Coalescing == '%v', want '%v'", cfg.Proxy.Coalescing, proxyCoalescing)
+			}
+
+			proxyStale := Stale{Grace: 30 * time.Second, Keep: time.Hour}
+			if cfg.Proxy.Stale != proxyStale {
+				t.Fatalf("Parse() Proxy.Stale == '%v', want '%v'", cfg.Proxy.Stale, proxyStale)
+			}
+
+			proxyRevalidation := Revalidation{Retain: 24 * time.Hour}
+			if cfg.Proxy.Revalidation != proxyRevalidation {
+				t.Fatalf("Parse() Proxy.Revalidation == '%v', want '%v'", cfg.Proxy.Revalidation, proxyRevalidation)
+			}
+
+			proxyHealthCheck := HealthCheck{
+				Path:     "/health",
+				Host:     "www.kratgo.com",
+				Interval: 5 * time.Second,
+				Timeout:  2 * time.Second,
+				Status:   204,
+				Rise:     2,
+				Fall:     3,
+			}
+			if cfg.Proxy.HealthCheck != proxyHealthCheck {
+				t.Fatalf("Parse() Proxy.HealthCheck == '%v', want '%v'", cfg.Proxy.HealthCheck, proxyHealthCheck)
+			}
+
+			proxyCircuitBreaker := CircuitBreaker{
+				Enabled:          true,
+				FailureRate:      0.25,
+				MinRequests:      20,
+				Window:           time.Minute,
+				CoolDown:         15 * time.Second,
+				HalfOpenRequests: 3,
+				ErrorPage:        "/etc/kratgo/503.html",
+			}
+			if cfg.Proxy.CircuitBreaker != proxyCircuitBreaker {
+				t.Fatalf("Parse() Proxy.CircuitBreaker == '%v', want '%v'", cfg.Proxy.CircuitBreaker, proxyCircuitBreaker)
+			}
+
+			proxyBalancer := Balancer{
+				Strategy: "hash",
+				HashKey:  "$(cookie::session)",
+				Weights:  map[string]int{"1.2.3.4:5678": 3},
+			}
+			if !reflect.DeepEqual(cfg.Proxy.Balancer, proxyBalancer) {
+				t.Fatalf("Parse() Proxy.Balancer == '%v', want '%v'", cfg.Proxy.Balancer, proxyBalancer)
+			}
+
+			proxyRetry := Retry{
+				Attempts:   3,
+				Statuses:   []int{502, 504},
+				Budget:     0.1,
+				MinRetries: 5,
+			}
+			if !reflect.DeepEqual(cfg.Proxy.Retry, proxyRetry) {
+				t.Fatalf("Parse() Proxy.Retry == '%v', want '%v'", cfg.Proxy.Retry, proxyRetry)
+			}
+
+			proxyPools := []BackendPool{
+				{Name: "api", BackendAddrs: []string{"127.0.0.1:8001", "127.0.0.1:8002"}},
+			}
+			if !reflect.DeepEqual(cfg.Proxy.Pools, proxyPools) {
+				t.Fatalf("Parse() Proxy.Pools == '%v', want '%v'", cfg.Proxy.Pools, proxyPools)
+			}
+
+			proxyRoutes := []Route{
+				{
+					Host:    "*.kratgo.com",
+					Path:    "/api/",
+					When:    "$(method) == 'GET'",
+					Pool:    "api",
+					TTL:     5 * time.Minute,
+					Nocache: []string{"$(query::nocache) == '1'"},
+					Headers: ProxyResponseHeaders{
+						Set:   []Header{{Name: "X-Route", Value: "api"}},
+						Unset: []Header{{Name: "Set-Cookie"}},
+					},
+				},
+			}
+			if !reflect.DeepEqual(cfg.Proxy.Routes, proxyRoutes) {
+				t.Fatalf("Parse() Proxy.Routes == '%v', want '%v'", cfg.Proxy.Routes, proxyRoutes)
+			}
+
 			adminAddr := "0.0.0.0:6082"
 			if cfg.Admin.Addr != adminAddr {
 				t.Fatalf("Parse() Admin.Addr == '%s', want '%s'", cfg.Admin.Addr, adminAddr)
@@ -274,6 +486,24 @@ func TestGetEvalParamName(t *testing.T) {
 				regexEvalKey: regexp.MustCompile(fmt.Sprintf("%s([0-9]{1,2})", EvalCookieVar)),
 			},
 		},
+		{
+			name: "query",
+			args: args{
+				key: configQueryVar,
+			},
+			want: want{
+				evalKey: EvalQueryVar,
+			},
+		},
+		{
+			name: "$(query::<NAME>)",
+			args: args{
+				key: configQueryArgVar,
+			},
+			want: want{
+				regexEvalKey: regexp.MustCompile(fmt.Sprintf("%s([0-9]{1,2})", EvalQueryArgVar)),
+			},
+		},
 		{
 			name: "unknown",
 			args: args{
@@ -402,6 +632,27 @@ func TestParseConfigKeys(t *testing.T) {
 				regexEvalKey: regexp.MustCompile(fmt.Sprintf("%s([0-9]{1,2})", EvalCookieVar)),
 			},
 		},
+		{
+			name: "query",
+			args: args{
+				key: configQueryVar,
+			},
+			want: want{
+				configKey: configQueryVar,
+				evalKey:   EvalQueryVar,
+			},
+		},
+		{
+			name: "$(query::<NAME>)",
+			args: args{
+				key: "$(query::page)",
+			},
+			want: want{
+				configKey:    "$(query::page)",
+				evalSubKey:   "page",
+				regexEvalKey: regexp.MustCompile(fmt.Sprintf("%s([0-9]{1,2})", EvalQueryArgVar)),
+			},
+		},
 		{
 			name: "unknown",
 			args: args{
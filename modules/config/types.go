@@ -1,9 +1,12 @@
 package config
 
+import "time"
+
 // Config ...
 type Config struct {
 	Cache       Cache       `yaml:"cache"`
 	Invalidator Invalidator `yaml:"invalidator"`
+	Warmer      Warmer      `yaml:"warmer"`
 	Proxy       Proxy       `yaml:"proxy"`
 	Admin       Admin       `yaml:"admin"`
 
@@ -13,15 +16,58 @@ type Config struct {
 
 // Proxy ...
 type Proxy struct {
-	Addr         string        `yaml:"addr"`
-	BackendAddrs []string      `yaml:"backendAddrs"`
-	Response     ProxyResponse `yaml:"response"`
-	Nocache      []string      `yaml:"nocache"`
+	Addr           string         `yaml:"addr"`
+	BackendAddrs   []string       `yaml:"backendAddrs"`
+	Response       ProxyResponse  `yaml:"response"`
+	Nocache        []string       `yaml:"nocache"`
+	Cache          ProxyCache     `yaml:"cache"`
+	Coalescing     Coalescing     `yaml:"coalescing"`
+	Stale          Stale          `yaml:"stale"`
+	Revalidation   Revalidation   `yaml:"revalidation"`
+	HealthCheck    HealthCheck    `yaml:"healthCheck"`
+	CircuitBreaker CircuitBreaker `yaml:"circuitBreaker"`
+	Balancer       Balancer       `yaml:"balancer"`
+	Retry          Retry          `yaml:"retry"`
+	Pools          []BackendPool  `yaml:"pools"`
+	Routes         []Route        `yaml:"routes"`
+}
+
+// ProxyCache ...
+type ProxyCache struct {
+	Key           string                `yaml:"key"`
+	Rules         []ProxyCacheRule      `yaml:"rules"`
+	MaxObjectSize int                   `yaml:"maxObjectSize"`
+	TagsHeader    string                `yaml:"tagsHeader"`
+	Statuses      map[int]time.Duration `yaml:"statuses"`
+	Admission     []AdmissionRule       `yaml:"admission"`
+}
+
+// AdmissionRule ...
+type AdmissionRule struct {
+	When     string        `yaml:"if"`
+	Policy   string        `yaml:"policy"`
+	Requests int           `yaml:"requests"`
+	Window   time.Duration `yaml:"window"`
+}
+
+// ProxyCacheRule ...
+type ProxyCacheRule struct {
+	When string `yaml:"if"`
+	Key  string `yaml:"key"`
 }
 
 // ProxyResponse ...
 type ProxyResponse struct {
-	Headers ProxyResponseHeaders `yaml:"headers"`
+	Headers     ProxyResponseHeaders `yaml:"headers"`
+	Diagnostics Diagnostics          `yaml:"diagnostics"`
+}
+
+// Diagnostics ...
+type Diagnostics struct {
+	XCache      bool   `yaml:"xCache"`
+	Age         bool   `yaml:"age"`
+	CacheStatus bool   `yaml:"cacheStatus"`
+	Node        string `yaml:"node"`
 }
 
 // ProxyResponseHeaders ...
@@ -30,6 +76,77 @@ type ProxyResponseHeaders struct {
 	Unset []Header `yaml:"unset"`
 }
 
+// Coalescing ...
+type Coalescing struct {
+	Enabled bool          `yaml:"enabled"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Stale ...
+type Stale struct {
+	Grace time.Duration `yaml:"grace"`
+	Keep  time.Duration `yaml:"keep"`
+}
+
+// Revalidation ...
+type Revalidation struct {
+	Retain time.Duration `yaml:"retain"`
+}
+
+// HealthCheck ...
+type HealthCheck struct {
+	Path     string        `yaml:"path"`
+	Host     string        `yaml:"host"`
+	Interval time.Duration `yaml:"interval"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Status   int           `yaml:"status"`
+	Rise     int           `yaml:"rise"`
+	Fall     int           `yaml:"fall"`
+}
+
+// CircuitBreaker ...
+type CircuitBreaker struct {
+	Enabled          bool          `yaml:"enabled"`
+	FailureRate      float64       `yaml:"failureRate"`
+	MinRequests      int           `yaml:"minRequests"`
+	Window           time.Duration `yaml:"window"`
+	CoolDown         time.Duration `yaml:"coolDown"`
+	HalfOpenRequests int           `yaml:"halfOpenRequests"`
+	ErrorPage        string        `yaml:"errorPage"`
+}
+
+// Balancer ...
+type Balancer struct {
+	Strategy string         `yaml:"strategy"`
+	HashKey  string         `yaml:"hashKey"`
+	Weights  map[string]int `yaml:"weights"`
+}
+
+// Retry ...
+type Retry struct {
+	Attempts   int     `yaml:"attempts"`
+	Statuses   []int   `yaml:"statuses"`
+	Budget     float64 `yaml:"budget"`
+	MinRetries int     `yaml:"minRetries"`
+}
+
+// BackendPool ...
+type BackendPool struct {
+	Name         string   `yaml:"name"`
+	BackendAddrs []string `yaml:"backendAddrs"`
+}
+
+// Route ...
+type Route struct {
+	Host    string               `yaml:"host"`
+	Path    string               `yaml:"path"`
+	When    string               `yaml:"if"`
+	Pool    string               `yaml:"pool"`
+	TTL     time.Duration        `yaml:"ttl"`
+	Nocache []string             `yaml:"nocache"`
+	Headers ProxyResponseHeaders `yaml:"headers"`
+}
+
 // Header ...
 type Header struct {
 	Name  string `yaml:"name"`
@@ -39,11 +156,20 @@ type Header struct {
 
 // Cache ...
 type Cache struct {
-	TTL              int `yaml:"ttl"`
-	CleanFrequency   int `yaml:"cleanFrequency"`
-	MaxEntries       int `yaml:"maxEntries"`
-	MaxEntrySize     int `yaml:"maxEntrySize"`
-	HardMaxCacheSize int `yaml:"hardMaxCacheSize"`
+	Storage          string `yaml:"storage"`
+	TTL              int    `yaml:"ttl"`
+	CleanFrequency   int    `yaml:"cleanFrequency"`
+	MaxEntries       int    `yaml:"maxEntries"`
+	MaxEntrySize     int    `yaml:"maxEntrySize"`
+	HardMaxCacheSize int    `yaml:"hardMaxCacheSize"`
+
+	Snapshot CacheSnapshot `yaml:"snapshot"`
+}
+
+// CacheSnapshot ...
+type CacheSnapshot struct {
+	Dir      string        `yaml:"dir"`
+	Interval time.Duration `yaml:"interval"`
 }
 
 // Invalidator ...
@@ -51,6 +177,12 @@ type Invalidator struct {
 	MaxWorkers int32 `yaml:"maxWorkers"`
 }
 
+// Warmer ...
+type Warmer struct {
+	Concurrency int `yaml:"concurrency"`
+	Rate        int `yaml:"rate"`
+}
+
 // Admin ...
 type Admin struct {
 	Addr string `yaml:"addr"`
@@ -5,5 +5,6 @@ const (
 	invTypePath
 	invTypeHeader
 	invTypePathHeader
+	invTypeTags
 	invTypeInvalid
 )
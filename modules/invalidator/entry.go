@@ -29,6 +29,7 @@ func (h *EntryHeader) Reset() {
 func (e *Entry) Reset() {
 	e.Host = ""
 	e.Path = ""
+	e.Tags = nil // The tags could still be used by a queued invalidation
 
 	e.Header.Reset()
 }
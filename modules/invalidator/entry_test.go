@@ -28,6 +28,7 @@ func TestReleaseEntry(t *testing.T) {
 	e.Path = "/fast"
 	e.Header.Key = "X-Data"
 	e.Header.Value = "1"
+	e.Tags = append(e.Tags, "product-42")
 
 	ReleaseEntry(e)
 
@@ -43,6 +44,9 @@ func TestReleaseEntry(t *testing.T) {
 	if e.Header.Value != "" {
 		t.Errorf("ReleaseEntry() entry has not been reset")
 	}
+	if len(e.Tags) != 0 {
+		t.Errorf("ReleaseEntry() entry has not been reset")
+	}
 }
 
 func TestHeader_Reset(t *testing.T) {
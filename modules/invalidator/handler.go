@@ -3,13 +3,12 @@ package invalidator
 import (
 	"fmt"
 
-	"github.com/allegro/bigcache/v3"
 	"github.com/savsgio/gotils/strconv"
 	"github.com/savsgio/kratgo/modules/cache"
 )
 
 func (i *Invalidator) deleteCacheKey(cacheKey string) error {
-	if err := i.cache.Del(cacheKey); err != nil && err != bigcache.ErrEntryNotFound {
+	if err := i.cache.Del(cacheKey); err != nil && err != cache.ErrEntryNotFound {
 		return err
 	}
 
@@ -105,3 +104,49 @@ func (i *Invalidator) invalidateByPathHeader(cacheKey string, cacheEntry cache.E
 
 	return nil
 }
+
+func hasAnyTag(resp *cache.Response, tags []string) bool {
+	for _, tag := range tags {
+		if resp.HasTag(strconv.S2B(tag)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (i *Invalidator) invalidateByTags(cacheKey string, cacheEntry cache.Entry, e Entry) error {
+	responses := cacheEntry.GetAllResponses()
+	n := 0
+
+	// Keep only the untagged responses, including the untagged variants of a tagged path.
+	// They are swapped instead of copied, like Entry.DelResponse does, since the entry reuses
+	// the buffers of every response and they must not be shared
+	for j := range responses {
+		if !hasAnyTag(&responses[j], e.Tags) {
+			responses[n], responses[j] = responses[j], responses[n]
+			n++
+		}
+	}
+
+	if n == len(responses) {
+		return nil
+	}
+
+	if n == 0 {
+		// Only delete the cache data for current key if all responses are tagged, to free memory
+		if err := i.deleteCacheKey(cacheKey); err != nil {
+			return fmt.Errorf("Could not invalidate cache by tags '%v': %v", e.Tags, err)
+		}
+
+		return nil
+	}
+
+	cacheEntry.Responses = responses[:n]
+
+	if err := i.cache.Set(cacheKey, cacheEntry); err != nil {
+		return fmt.Errorf("Could not invalidate cache by tags '%v': %v", e.Tags, err)
+	}
+
+	return nil
+}
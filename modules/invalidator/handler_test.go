@@ -158,3 +158,48 @@ func TestInvalidator_invalidateByPathHeader(t *testing.T) {
 		t.Error("The cache has not been invalidate by path and header")
 	}
 }
+
+func TestInvalidator_invalidateByTags(t *testing.T) {
+	i, err := New(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := Entry{
+		Tags: []string{"a"},
+	}
+
+	key := "www.kratgo.com"
+
+	cacheEntry := cache.AcquireEntry()
+	cacheEntry.SetResponse(cache.Response{Path: []byte("/a"), Tags: [][]byte{[]byte("a")}})
+	cacheEntry.SetResponse(cache.Response{Path: []byte("/b"), Tags: [][]byte{[]byte("b")}})
+
+	i.cache.Set(key, *cacheEntry)
+
+	if err := i.invalidateByTags(key, *cacheEntry, e); err != nil {
+		t.Fatal(err)
+	}
+
+	cacheEntry.Reset()
+
+	if err := i.cache.Get(key, cacheEntry); err != nil {
+		t.Fatal(err)
+	}
+
+	if cacheEntry.HasResponse([]byte("/a")) {
+		t.Error("The cache has not been invalidate by tags")
+	}
+
+	if !cacheEntry.HasResponse([]byte("/b")) {
+		t.Error("The cache has been invalidate for an untagged response")
+	}
+
+	if err := i.invalidateByTags(key, *cacheEntry, Entry{Tags: []string{"b"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if keys := i.cache.TagKeys("b"); len(keys) != 0 {
+		t.Errorf("The cache key has not been deleted, tag keys == '%v'", keys)
+	}
+}
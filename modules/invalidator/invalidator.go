@@ -27,6 +27,10 @@ func New(cfg Config) (*Invalidator, error) {
 }
 
 func (i *Invalidator) invalidationType(e Entry) invType {
+	if len(e.Tags) > 0 {
+		return invTypeTags
+	}
+
 	if e.Host == "" && e.Path == "" && e.Header.Key == "" {
 		return invTypeInvalid
 	}
@@ -56,6 +60,8 @@ func (i *Invalidator) invalidate(invalidationType invType, key string, entry cac
 		return i.invalidateByHeader(key, entry, e)
 	case invTypePathHeader:
 		return i.invalidateByPathHeader(key, entry, e)
+	case invTypeTags:
+		return i.invalidateByTags(key, entry, e)
 	}
 
 	return nil
@@ -69,18 +75,18 @@ func (i *Invalidator) invalidateAll(invalidationType invType, e Entry) {
 	iter := i.cache.Iterator()
 
 	for iter.SetNext() {
-		v, err := iter.Value()
+		key, data, err := iter.Value()
 		if err != nil {
 			i.log.Errorf("Could not get value from iterator: %v", err)
 			continue
 		}
 
-		if err = cache.Unmarshal(entry, v.Value()); err != nil {
+		if err = cache.Unmarshal(entry, data); err != nil {
 			i.log.Errorf("Could not decode cache value: %v", err)
 			continue
 		}
 
-		if err = i.invalidate(invalidationType, v.Key(), *entry, e); err != nil {
+		if err = i.invalidate(invalidationType, key, *entry, e); err != nil {
 			i.log.Errorf("Could not invalidate '%v': %v", *entry, err)
 		}
 
@@ -94,18 +100,56 @@ func (i *Invalidator) invalidateHost(invalidationType invType, e Entry) {
 	atomic.AddInt32(&i.activeWorkers, 1)
 	defer atomic.AddInt32(&i.activeWorkers, -1)
 
-	key := e.Host
 	entry := cache.AcquireEntry()
 
-	err := i.cache.Get(key, entry)
-	if err != nil {
-		i.log.Errorf("Could not get responses from cache by key '%s': %v", key, err)
-	} else if entry.Len() == 0 {
-		return
+	for _, key := range i.cache.HostKeys(e.Host) {
+		if err := i.cache.Get(key, entry); err != nil {
+			i.log.Errorf("Could not get responses from cache by key '%s': %v", key, err)
+			continue
+		}
+
+		if entry.Len() > 0 {
+			if err := i.invalidate(invalidationType, key, *entry, e); err != nil {
+				i.log.Error(err)
+			}
+		}
+
+		entry.Reset()
 	}
 
-	if err = i.invalidate(invalidationType, key, *entry, e); err != nil {
-		i.log.Error(err)
+	cache.ReleaseEntry(entry)
+}
+
+// invalidateTags invalidates only the cache keys with a response tagged with any of the tags of the entry,
+// looked up in the tag index of the cache.
+func (i *Invalidator) invalidateTags(invalidationType invType, e Entry) {
+	atomic.AddInt32(&i.activeWorkers, 1)
+	defer atomic.AddInt32(&i.activeWorkers, -1)
+
+	entry := cache.AcquireEntry()
+	keys := make(map[string]struct{})
+
+	for _, tag := range e.Tags {
+		for _, key := range i.cache.TagKeys(tag) {
+			if _, ok := keys[key]; ok {
+				continue
+			}
+
+			keys[key] = struct{}{}
+
+			if err := i.cache.Get(key, entry); err != nil {
+				i.log.Errorf("Could not get responses from cache by key '%s': %v", key, err)
+				continue
+			}
+
+			if entry.Len() > 0 {
+				if err := i.invalidate(invalidationType, key, *entry, e); err != nil {
+					i.log.Error(err)
+				}
+			}
+
+			entry.Reset()
+		}
 	}
 
 	cache.ReleaseEntry(entry)
@@ -135,11 +179,13 @@ func (i *Invalidator) Start() {
 
 		i.waitAvailableWorkers()
 
-		if e.Host != "" {
+		switch {
+		case invalidationType == invTypeTags:
+			go i.invalidateTags(invalidationType, e)
+		case e.Host != "":
 			go i.invalidateHost(invalidationType, e)
-		} else {
+		default:
 			go i.invalidateAll(invalidationType, e)
 		}
-
 	}
 }
@@ -195,6 +195,18 @@ func TestInvalidator_invalidationType(t *testing.T) {
 				t: invTypePathHeader,
 			},
 		},
+		{
+			name: "Tags",
+			args: args{
+				e: Entry{
+					Host: "www.kratgo.com",
+					Tags: []string{"product-42"},
+				},
+			},
+			want: want{
+				t: invTypeTags,
+			},
+		},
 		{
 			name: "Invalid",
 			args: args{
@@ -441,6 +453,7 @@ func TestInvalidator_invalidateHost(t *testing.T) {
 	host1 := "www.kratgo.com"
 	responses1 := []cache.Response{
 		{
+			Host: []byte(host1),
 			Path: path,
 			Body: []byte("Kratgo is not slow"),
 			Headers: []cache.ResponseHeader{
@@ -452,6 +465,7 @@ func TestInvalidator_invalidateHost(t *testing.T) {
 	host2 := "www.cache-fast.com"
 	responses2 := []cache.Response{
 		{
+			Host: []byte(host2),
 			Path: path,
 			Body: []byte("Kratgo is not slow"),
 			Headers: []cache.ResponseHeader{
@@ -465,15 +479,43 @@ func TestInvalidator_invalidateHost(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	i.cache.Set(host1, cache.Entry{Responses: responses1})
-	i.cache.Set(host2, cache.Entry{Responses: responses2})
+	i.cache.Set(host1+string(path), cache.Entry{Responses: responses1})
+	i.cache.Set(host2+string(path), cache.Entry{Responses: responses2})
 
 	i.invalidateHost(invTypePath, Entry{Host: host1, Path: string(path)})
 
 	wantLength := 1
 	length := i.cache.Len()
 	if length != wantLength {
-		t.Errorf("Invalidator.invalidateAll() cache length == '%d', want '%d'", length, wantLength)
+		t.Errorf("Invalidator.invalidateHost() cache length == '%d', want '%d'", length, wantLength)
+	}
+}
+
+func TestInvalidator_invalidateTags(t *testing.T) {
+	path := []byte("/fast")
+	hosts := []string{"www.kratgo.com", "www.cache-fast.com", "www.other.com"}
+	tags := [][][]byte{{[]byte("a")}, {[]byte("b")}, {[]byte("c")}}
+
+	i, err := New(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for j, host := range hosts {
+		responses := []cache.Response{{Host: []byte(host), Path: path, Tags: tags[j]}}
+		i.cache.Set(host+string(path), cache.Entry{Responses: responses})
+	}
+
+	i.invalidateTags(invTypeTags, Entry{Tags: []string{"a", "b"}})
+
+	wantLength := 1
+	length := i.cache.Len()
+	if length != wantLength {
+		t.Errorf("Invalidator.invalidateTags() cache length == '%d', want '%d'", length, wantLength)
+	}
+
+	if keys := i.cache.TagKeys("c"); len(keys) != 1 {
+		t.Errorf("Invalidator.invalidateTags() removed an untagged key")
 	}
 }
 
@@ -550,9 +592,9 @@ func TestInvalidator_Start(t *testing.T) {
 	i.chEntries = make(chan Entry, 1)
 
 	cacheEntry := cache.AcquireEntry()
-	cacheEntry.SetResponse(cache.Response{Path: []byte(path)})
+	cacheEntry.SetResponse(cache.Response{Host: []byte(key), Path: []byte(path)})
 
-	if err := i.cache.Set(key, *cacheEntry); err != nil {
+	if err := i.cache.Set(key+path, *cacheEntry); err != nil {
 		t.Fatal(err)
 	}
 
@@ -566,3 +608,51 @@ func TestInvalidator_Start(t *testing.T) {
 		t.Error("Invalidator.Start() invalidator has not been start")
 	}
 }
+
+func TestInvalidator_invalidateTagsPartial(t *testing.T) {
+	hosts := []string{"www.kratgo.com", "www.cache-fast.com"}
+	paths := []string{"/d", "/e", "/f"}
+
+	i, err := New(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The pooled entry is reused for every key, so the kept responses must not share their buffers
+	for _, host := range hosts {
+		responses := make([]cache.Response, len(paths))
+
+		for j, path := range paths {
+			responses[j] = cache.Response{Host: []byte(host), Path: []byte(path), Body: []byte(host + path)}
+		}
+
+		responses[0].Tags = [][]byte{[]byte("t")}
+
+		i.cache.Set(host+"/", cache.Entry{Responses: responses})
+	}
+
+	i.invalidateTags(invTypeTags, Entry{Tags: []string{"t"}})
+
+	for _, host := range hosts {
+		entry := cache.AcquireEntry()
+
+		if err := i.cache.Get(host+"/", entry); err != nil {
+			t.Fatal(err)
+		}
+
+		if entry.HasResponse([]byte("/d")) {
+			t.Errorf("Invalidator.invalidateTags() the tagged response of '%s' has not been removed", host)
+		}
+
+		for _, path := range paths[1:] {
+			resp := entry.GetResponse([]byte(path))
+			if resp == nil {
+				t.Errorf("Invalidator.invalidateTags() removed the untagged response '%s' of '%s'", path, host)
+			} else if body := string(resp.Body); body != host+path {
+				t.Errorf("Invalidator.invalidateTags() body of '%s%s' == '%s', want '%s'", host, path, body, host+path)
+			}
+		}
+
+		cache.ReleaseEntry(entry)
+	}
+}
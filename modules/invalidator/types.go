@@ -40,6 +40,7 @@ type Entry struct {
 	Host   string      `json:"host"`
 	Path   string      `json:"path"`
 	Header EntryHeader `json:"header"`
+	Tags   []string    `json:"tags"` // Cache tags, which take precedence over the rest of fields
 }
 
 type invType int
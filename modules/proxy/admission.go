@@ -0,0 +1,179 @@
+package proxy
+
+import (
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func newFrequencySketch(sampleSize int, window time.Duration) *frequencySketch {
+	s := &frequencySketch{
+		sampleSize: sampleSize,
+		window:     window,
+	}
+
+	for i := range s.counters {
+		s.counters[i] = make([]uint8, sketchWidth)
+	}
+
+	return s
+}
+
+// add increments the frequency of the hashed key, and returns its estimated frequency.
+// Only the minimum counters are incremented (conservative update), to reduce the overestimation.
+func (s *frequencySketch) add(hash uint64, now time.Time) uint8 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.window > 0 && !now.Before(s.resetAt) {
+		s.reset()
+		s.resetAt = now.Add(s.window)
+	}
+
+	var idx [sketchDepth]uint32
+
+	h1, h2 := uint32(hash), uint32(hash>>32)
+	freq := uint8(255)
+
+	for i := range idx {
+		idx[i] = (h1 + uint32(i)*h2) % sketchWidth
+
+		if c := s.counters[i][idx[i]]; c < freq {
+			freq = c
+		}
+	}
+
+	if freq < 255 {
+		freq++
+
+		for i := range idx {
+			if s.counters[i][idx[i]] < freq {
+				s.counters[i][idx[i]] = freq
+			}
+		}
+	}
+
+	s.additions++
+	if s.sampleSize > 0 && s.additions >= s.sampleSize {
+		s.halve()
+	}
+
+	return freq
+}
+
+// halve ages the sketch, so the keys which were popular in the past are forgotten.
+func (s *frequencySketch) halve() {
+	for i := range s.counters {
+		for j := range s.counters[i] {
+			s.counters[i][j] >>= 1
+		}
+	}
+
+	s.additions /= 2
+}
+
+func (s *frequencySketch) reset() {
+	for i := range s.counters {
+		for j := range s.counters[i] {
+			s.counters[i][j] = 0
+		}
+	}
+
+	s.additions = 0
+}
+
+// admissionHash returns the hash of the response, by its cache key and path, in the frequency sketches.
+func admissionHash(cacheKey, path []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(cacheKey)
+	h.Write([]byte{0})
+	h.Write(path)
+
+	return h.Sum64()
+}
+
+func (p *Proxy) parseAdmissionRules() error {
+	for _, ar := range p.fileConfig.Cache.Admission {
+		r := &admissionRule{when: ar.When, policy: ar.Policy}
+
+		if ar.When != "" {
+			expr, params, err := p.newEvaluableExpression(ar.When)
+			if err != nil {
+				return fmt.Errorf("Could not get the evaluable expression for rule '%s': %v", ar.When, err)
+			}
+			r.expr = expr
+			r.params = append(r.params, params...)
+		}
+
+		switch {
+		case ar.Requests < 0 || ar.Requests > 255:
+			return fmt.Errorf("The requests of the admission rule '%s' must be between 0 and 255", ar.When)
+		case ar.Requests == 0:
+			r.requests = defaultAdmissionRequests
+		default:
+			r.requests = uint8(ar.Requests)
+		}
+
+		switch ar.Policy {
+		case frequencyAdmissionPolicy:
+			r.sketch = newFrequencySketch(sketchSampleSize, 0)
+		case nthRequestAdmissionPolicy:
+			window := ar.Window
+			if window <= 0 {
+				window = defaultAdmissionWindow
+			}
+
+			r.sketch = newFrequencySketch(0, window)
+		default:
+			return fmt.Errorf("Invalid policy '%s' of the admission rule '%s'", ar.Policy, ar.When)
+		}
+
+		p.admissionRules = append(p.admissionRules, r)
+	}
+
+	return nil
+}
+
+// admit returns true if the backend response could be saved in cache, according to the admission policy
+// of the first matching rule. The responses which don't match any rule are always admitted.
+func (p *Proxy) admit(cacheKey, path []byte, ctx *fasthttp.RequestCtx, pt *proxyTools) (bool, error) {
+	for _, r := range p.admissionRules {
+		if r.expr != nil {
+			match, err := evalRule(ctx, r.rule, pt.params)
+			if err != nil {
+				return false, fmt.Errorf("Invalid admission rule: %v", err)
+			} else if !match {
+				continue
+			}
+		}
+
+		if r.sketch.add(admissionHash(cacheKey, path), time.Now()) < r.requests {
+			r.rejected.Add(1)
+
+			return false, nil
+		}
+
+		r.admitted.Add(1)
+
+		return true, nil
+	}
+
+	return true, nil
+}
+
+func (p *Proxy) admissionStats() []AdmissionStats {
+	stats := make([]AdmissionStats, len(p.admissionRules))
+
+	for i, r := range p.admissionRules {
+		stats[i] = AdmissionStats{
+			Rule:     r.when,
+			Policy:   r.policy,
+			Admitted: r.admitted.Load(),
+			Rejected: r.rejected.Load(),
+		}
+	}
+
+	return stats
+}
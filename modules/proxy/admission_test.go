@@ -0,0 +1,210 @@
+package proxy
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/savsgio/kratgo/modules/cache"
+	"github.com/savsgio/kratgo/modules/config"
+	"github.com/valyala/fasthttp"
+)
+
+func TestFrequencySketch_add(t *testing.T) {
+	now := time.Now()
+	hash := admissionHash([]byte("www.kratgo.com/fast/"), []byte("/fast/"))
+	other := admissionHash([]byte("www.kratgo.com/slow/"), []byte("/slow/"))
+
+	s := newFrequencySketch(0, 0)
+
+	for i := 1; i <= 3; i++ {
+		if freq := s.add(hash, now); freq != uint8(i) {
+			t.Errorf("frequencySketch.add() == '%d', want '%d'", freq, i)
+		}
+	}
+
+	if freq := s.add(other, now); freq != 1 {
+		t.Errorf("frequencySketch.add() other key == '%d', want '%d'", freq, 1)
+	}
+
+	s.halve()
+
+	if freq := s.add(hash, now); freq != 2 {
+		t.Errorf("frequencySketch.add() after halve == '%d', want '%d'", freq, 2)
+	}
+
+	// Aged by the sample size
+	s = newFrequencySketch(4, 0)
+
+	for i := 0; i < 4; i++ {
+		s.add(hash, now)
+	}
+
+	if freq := s.add(hash, now); freq != 3 {
+		t.Errorf("frequencySketch.add() after the sample size == '%d', want '%d'", freq, 3)
+	}
+
+	// Reset by the window
+	s = newFrequencySketch(0, time.Minute)
+
+	s.add(hash, now)
+	if freq := s.add(hash, now.Add(30*time.Second)); freq != 2 {
+		t.Errorf("frequencySketch.add() within the window == '%d', want '%d'", freq, 2)
+	}
+
+	if freq := s.add(hash, now.Add(time.Minute)); freq != 1 {
+		t.Errorf("frequencySketch.add() after the window == '%d', want '%d'", freq, 1)
+	}
+}
+
+func TestProxy_parseAdmissionRules(t *testing.T) {
+	type args struct {
+		rules []config.AdmissionRule
+	}
+
+	type want struct {
+		requests []uint8
+		err      bool
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Ok",
+			args: args{
+				rules: []config.AdmissionRule{
+					{When: "$(path) =~ '^/search/'", Policy: nthRequestAdmissionPolicy, Requests: 3, Window: time.Minute},
+					{Policy: frequencyAdmissionPolicy},
+				},
+			},
+			want: want{
+				requests: []uint8{3, defaultAdmissionRequests},
+			},
+		},
+		{
+			name: "InvalidPolicy",
+			args: args{
+				rules: []config.AdmissionRule{{Policy: "lru"}},
+			},
+			want: want{
+				err: true,
+			},
+		},
+		{
+			name: "InvalidRequests",
+			args: args{
+				rules: []config.AdmissionRule{{Policy: frequencyAdmissionPolicy, Requests: 256}},
+			},
+			want: want{
+				err: true,
+			},
+		},
+		{
+			name: "InvalidCondition",
+			args: args{
+				rules: []config.AdmissionRule{{When: "$(fake::X-Data) == '1'", Policy: frequencyAdmissionPolicy}},
+			},
+			want: want{
+				err: true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(testConfig())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			p.fileConfig.Cache.Admission = tt.args.rules
+
+			err = p.parseAdmissionRules()
+			if (err != nil) != tt.want.err {
+				t.Fatalf("Proxy.parseAdmissionRules() Unexpected error: %v", err)
+			}
+
+			if tt.want.err {
+				return
+			}
+
+			var requests []uint8
+			for _, r := range p.admissionRules {
+				requests = append(requests, r.requests)
+			}
+
+			if !reflect.DeepEqual(requests, tt.want.requests) {
+				t.Errorf("Proxy.parseAdmissionRules() requests == '%v', want '%v'", requests, tt.want.requests)
+			}
+		})
+	}
+}
+
+func TestProxy_handlerAdmission(t *testing.T) {
+	cfg := testConfig()
+	cfg.FileConfig.Cache.Admission = []config.AdmissionRule{
+		{When: "$(path) =~ '^/products/'", Policy: nthRequestAdmissionPolicy, Requests: 2, Window: time.Minute},
+	}
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := &slowBackend{
+		body:    []byte("Kratgo body"),
+		headers: map[string]string{fasthttp.HeaderCacheControl: "max-age=60"},
+	}
+	p.backends = newTestBackends(backend)
+
+	isCached := func(path string) bool {
+		entry := cache.AcquireEntry()
+		defer cache.ReleaseEntry(entry)
+
+		if err := p.cache.Get("www.kratgo.com"+path+"?", entry); err != nil {
+			t.Fatal(err)
+		}
+
+		return entry.HasResponse([]byte(path))
+	}
+
+	request := func(path string) {
+		ctx := new(fasthttp.RequestCtx)
+		ctx.Request.SetRequestURI(path)
+		ctx.Request.Header.SetHost("www.kratgo.com")
+
+		p.handler(ctx)
+	}
+
+	// The first request of a path of the rule is rejected, and the second one admitted
+	request("/products/42")
+	if isCached("/products/42") {
+		t.Error("Proxy.handler() the first request has been admitted in cache")
+	}
+
+	request("/products/42")
+	if !isCached("/products/42") {
+		t.Error("Proxy.handler() the second request has not been admitted in cache")
+	}
+
+	request("/products/42")
+	if backend.calls != 2 {
+		t.Errorf("Proxy.handler() backend calls == '%d', want '%d'", backend.calls, 2)
+	}
+
+	// The paths which don't match any rule are always admitted
+	request("/home/")
+	if !isCached("/home/") {
+		t.Error("Proxy.handler() a path without admission rule has not been admitted in cache")
+	}
+
+	wantStats := []AdmissionStats{
+		{Rule: "$(path) =~ '^/products/'", Policy: nthRequestAdmissionPolicy, Admitted: 1, Rejected: 1},
+	}
+	if stats := p.Stats().Admission; !reflect.DeepEqual(stats, wantStats) {
+		t.Errorf("Proxy.Stats() admission == '%+v', want '%+v'", stats, wantStats)
+	}
+}
@@ -0,0 +1,319 @@
+package proxy
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+
+	gstrconv "github.com/savsgio/gotils/strconv"
+	"github.com/valyala/fasthttp"
+)
+
+// containsBackend returns true if the backend is in the list.
+func containsBackend(tried []*backend, b *backend) bool {
+	for _, t := range tried {
+		if t == b {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lessLoaded returns true if the backend a has fewer requests in flight than b, relative to their weights.
+func lessLoaded(a, b *backend) bool {
+	return a.active.Load()*int64(b.weight) < b.active.Load()*int64(a.weight)
+}
+
+func newRoundRobinBalancer() *roundRobinBalancer {
+	return &roundRobinBalancer{}
+}
+
+func (rr *roundRobinBalancer) next(backends []*backend, _ *fasthttp.RequestCtx, tried []*backend, now time.Time) *backend {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	if len(rr.currentWeights) != len(backends) {
+		rr.currentWeights = make([]int, len(backends))
+	}
+
+	// The chosen backend could refuse the request if its circuit has just run out of trial requests
+	for range backends {
+		best := -1
+		total := 0
+
+		for i, b := range backends {
+			if containsBackend(tried, b) || !b.canServe(now) {
+				continue
+			}
+
+			rr.currentWeights[i] += b.weight
+			total += b.weight
+
+			if best < 0 || rr.currentWeights[i] > rr.currentWeights[best] {
+				best = i
+			}
+		}
+
+		if best < 0 {
+			return nil
+		}
+
+		rr.currentWeights[best] -= total
+
+		if b := backends[best]; b.isAvailable(now) {
+			return b
+		}
+	}
+
+	return nil
+}
+
+func (lc *leastConnBalancer) next(backends []*backend, _ *fasthttp.RequestCtx, tried []*backend, now time.Time) *backend {
+	total := len(backends)
+	offset := int(lc.offset.Add(1) % uint64(total))
+
+	for range backends {
+		var best *backend
+
+		for i := 0; i < total; i++ {
+			b := backends[(offset+i)%total]
+
+			if !containsBackend(tried, b) && b.canServe(now) && (best == nil || lessLoaded(b, best)) {
+				best = b
+			}
+		}
+
+		if best == nil {
+			return nil
+		}
+
+		if best.isAvailable(now) {
+			return best
+		}
+	}
+
+	return nil
+}
+
+func (twoChoicesBalancer) next(backends []*backend, _ *fasthttp.RequestCtx, tried []*backend, now time.Time) *backend {
+	for range backends {
+		available := 0
+
+		for _, b := range backends {
+			if !containsBackend(tried, b) && b.canServe(now) {
+				available++
+			}
+		}
+
+		if available == 0 {
+			return nil
+		}
+
+		first := rand.Intn(available)
+		second := first
+
+		if available > 1 {
+			second = (first + 1 + rand.Intn(available-1)) % available
+		}
+
+		var a, b *backend
+		n := 0
+
+		for _, candidate := range backends {
+			if containsBackend(tried, candidate) || !candidate.canServe(now) {
+				continue
+			}
+
+			if n == first {
+				a = candidate
+			}
+
+			if n == second {
+				b = candidate
+			}
+
+			n++
+		}
+
+		// The backends could have changed their state between both loops
+		if a == nil || (b != nil && lessLoaded(b, a)) {
+			a = b
+		}
+
+		if a != nil && a.isAvailable(now) {
+			return a
+		}
+	}
+
+	return nil
+}
+
+func newHashBalancer(key cacheKeyTemplate, backends []*backend) *hashBalancer {
+	hb := &hashBalancer{
+		key:      key,
+		fallback: newRoundRobinBalancer(),
+	}
+
+	for i, b := range backends {
+		for j := 0; j < b.weight*hashRingReplicas; j++ {
+			hb.ring = append(hb.ring, ringPoint{
+				hash:    hashString(b.addr + "-" + strconv.Itoa(j)),
+				backend: i,
+			})
+		}
+	}
+
+	sort.Slice(hb.ring, func(i, j int) bool {
+		return hb.ring[i].hash < hb.ring[j].hash
+	})
+
+	return hb
+}
+
+// mixHash spreads the bits of a fnv hash, since the hashes of similar keys are close to each other.
+func mixHash(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+
+	return h
+}
+
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write(gstrconv.S2B(s))
+
+	return mixHash(h.Sum64())
+}
+
+// hash returns the hash of the request key, and false if all its variables are empty.
+func (hb *hashBalancer) hash(ctx *fasthttp.RequestCtx) (uint64, bool) {
+	h := fnv.New64a()
+	empty := true
+
+	for _, part := range hb.key {
+		if part.param.name == "" {
+			h.Write(gstrconv.S2B(part.literal))
+			continue
+		}
+
+		value := getEvalValue(ctx, part.param.name, part.param.subKey)
+		if value != "" {
+			empty = false
+		}
+
+		h.Write(gstrconv.S2B(value))
+	}
+
+	return mixHash(h.Sum64()), !empty
+}
+
+func (hb *hashBalancer) next(backends []*backend, ctx *fasthttp.RequestCtx, tried []*backend, now time.Time) *backend {
+	key, ok := hb.hash(ctx)
+	if !ok {
+		return hb.fallback.next(backends, ctx, tried, now)
+	}
+
+	total := len(hb.ring)
+	start := sort.Search(total, func(i int) bool {
+		return hb.ring[i].hash >= key
+	})
+
+	var skipped []bool
+
+	// The requests of an unavailable or already tried backend go to the next backends of the ring
+	for i := 0; i < total; i++ {
+		point := hb.ring[(start+i)%total]
+
+		if point.backend >= len(backends) || (skipped != nil && skipped[point.backend]) {
+			continue
+		}
+
+		if b := backends[point.backend]; !containsBackend(tried, b) && b.isAvailable(now) {
+			return b
+		}
+
+		if skipped == nil {
+			skipped = make([]bool, len(backends))
+		}
+
+		skipped[point.backend] = true
+	}
+
+	return nil
+}
+
+func (p *Proxy) parseBalancer() error {
+	cfg := p.fileConfig.Balancer
+
+	for addr, weight := range cfg.Weights {
+		if weight < 1 {
+			return fmt.Errorf("The weight of the backend '%s' must be greater than 0", addr)
+		}
+
+		found := false
+
+		for _, b := range p.allBackends() {
+			if b.addr == addr {
+				b.weight = weight
+				found = true
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("Could not set the weight of the unknown backend '%s'", addr)
+		}
+	}
+
+	bl, err := p.newBalancer(p.backends)
+	if err != nil {
+		return err
+	}
+
+	p.balancer = bl
+
+	for _, pool := range p.pools {
+		if pool.balancer, err = p.newBalancer(pool.backends); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newBalancer returns a balancer of the backends with the configured strategy.
+func (p *Proxy) newBalancer(backends []*backend) (balancer, error) {
+	cfg := p.fileConfig.Balancer
+
+	switch cfg.Strategy {
+	case "", roundRobinBalancing:
+		return newRoundRobinBalancer(), nil
+
+	case leastConnBalancing:
+		return &leastConnBalancer{}, nil
+
+	case twoChoicesBalancing:
+		return twoChoicesBalancer{}, nil
+
+	case hashBalancing:
+		if cfg.HashKey == "" {
+			return nil, fmt.Errorf("The hash key is mandatory with the '%s' balancing strategy", hashBalancing)
+		}
+
+		key, err := newCacheKeyTemplate(cfg.HashKey)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid hash key of the balancer: %v", err)
+		}
+
+		return newHashBalancer(key, backends), nil
+
+	default:
+		return nil, fmt.Errorf("Invalid balancing strategy '%s'", cfg.Strategy)
+	}
+}
@@ -0,0 +1,300 @@
+package proxy
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/savsgio/kratgo/modules/config"
+	"github.com/valyala/fasthttp"
+)
+
+func newWeightedTestBackends(weights ...int) []*backend {
+	backends := make([]*backend, len(weights))
+
+	for i, weight := range weights {
+		backends[i] = newBackend(fmt.Sprintf("localhost:999%d", i), &mockBackend{})
+		backends[i].weight = weight
+	}
+
+	return backends
+}
+
+func countPicks(bl balancer, backends []*backend, ctx *fasthttp.RequestCtx, n int) map[*backend]int {
+	picks := make(map[*backend]int)
+	now := time.Now()
+
+	for i := 0; i < n; i++ {
+		picks[bl.next(backends, ctx, nil, now)]++
+	}
+
+	return picks
+}
+
+func TestProxy_parseBalancer(t *testing.T) {
+	type args struct {
+		balancer config.Balancer
+	}
+
+	type want struct {
+		balancer balancer
+		weights  []int
+		err      bool
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Default",
+			args: args{balancer: config.Balancer{}},
+			want: want{balancer: &roundRobinBalancer{}, weights: []int{1, 1}},
+		},
+		{
+			name: "WeightedRoundRobin",
+			args: args{
+				balancer: config.Balancer{
+					Strategy: roundRobinBalancing,
+					Weights:  map[string]int{"localhost:9991": 3},
+				},
+			},
+			want: want{balancer: &roundRobinBalancer{}, weights: []int{1, 3}},
+		},
+		{
+			name: "LeastConn",
+			args: args{balancer: config.Balancer{Strategy: leastConnBalancing}},
+			want: want{balancer: &leastConnBalancer{}, weights: []int{1, 1}},
+		},
+		{
+			name: "TwoChoices",
+			args: args{balancer: config.Balancer{Strategy: twoChoicesBalancing}},
+			want: want{balancer: twoChoicesBalancer{}, weights: []int{1, 1}},
+		},
+		{
+			name: "Hash",
+			args: args{balancer: config.Balancer{Strategy: hashBalancing, HashKey: "$(cookie::session)"}},
+			want: want{balancer: &hashBalancer{}, weights: []int{1, 1}},
+		},
+		{
+			name: "HashWithoutKey",
+			args: args{balancer: config.Balancer{Strategy: hashBalancing}},
+			want: want{err: true},
+		},
+		{
+			name: "HashWithResponseKey",
+			args: args{balancer: config.Balancer{Strategy: hashBalancing, HashKey: "$(resp.header::X-Node)"}},
+			want: want{err: true},
+		},
+		{
+			name: "InvalidStrategy",
+			args: args{balancer: config.Balancer{Strategy: "fastest"}},
+			want: want{err: true},
+		},
+		{
+			name: "InvalidWeight",
+			args: args{balancer: config.Balancer{Weights: map[string]int{"localhost:9991": 0}}},
+			want: want{err: true},
+		},
+		{
+			name: "UnknownBackendWeight",
+			args: args{balancer: config.Balancer{Weights: map[string]int{"localhost:8000": 2}}},
+			want: want{err: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.FileConfig.BackendAddrs = []string{"localhost:9990", "localhost:9991"}
+			cfg.FileConfig.Balancer = tt.args.balancer
+
+			p, err := New(cfg)
+			if (err != nil) != tt.want.err {
+				t.Fatalf("New() Unexpected error: %v", err)
+			}
+
+			if tt.want.err {
+				return
+			}
+
+			if got, want := fmt.Sprintf("%T", p.balancer), fmt.Sprintf("%T", tt.want.balancer); got != want {
+				t.Errorf("Proxy.parseBalancer() == '%s', want '%s'", got, want)
+			}
+
+			for i, b := range p.backends {
+				if b.weight != tt.want.weights[i] {
+					t.Errorf("Proxy.parseBalancer() backend '%s' weight == '%d', want '%d'", b.addr, b.weight, tt.want.weights[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_roundRobinBalancer(t *testing.T) {
+	backends := newWeightedTestBackends(3, 1)
+	rr := newRoundRobinBalancer()
+	now := time.Now()
+
+	// The requests of the heavier backend are spread between the ones of the other
+	want := []*backend{backends[0], backends[0], backends[1], backends[0]}
+
+	for i := 0; i < 3*len(want); i++ {
+		if b := rr.next(backends, nil, nil, now); b != want[i%len(want)] {
+			t.Errorf("roundRobinBalancer.next() request %d == '%s', want '%s'", i, b.addr, want[i%len(want)].addr)
+		}
+	}
+
+	backends[0].healthy.Store(false)
+
+	for i := 0; i < 4; i++ {
+		if b := rr.next(backends, nil, nil, now); b != backends[1] {
+			t.Errorf("roundRobinBalancer.next() == '%v', want '%s'", b, backends[1].addr)
+		}
+	}
+
+	backends[1].healthy.Store(false)
+
+	if b := rr.next(backends, nil, nil, now); b != nil {
+		t.Errorf("roundRobinBalancer.next() == '%s', want '%v'", b.addr, nil)
+	}
+}
+
+func Test_leastConnBalancer(t *testing.T) {
+	backends := newWeightedTestBackends(1, 1, 2)
+	lc := &leastConnBalancer{}
+
+	backends[0].active.Store(2)
+	backends[1].active.Store(1)
+	backends[2].active.Store(5)
+
+	picks := countPicks(lc, backends, nil, 10)
+	if picks[backends[1]] != 10 {
+		t.Errorf("leastConnBalancer.next() picks == '%v', want all for '%s'", picks, backends[1].addr)
+	}
+
+	// The ties are spread between the backends
+	backends[1].active.Store(2)
+
+	picks = countPicks(lc, backends, nil, 10)
+	if picks[backends[0]] == 0 || picks[backends[1]] == 0 || picks[backends[2]] != 0 {
+		t.Errorf("leastConnBalancer.next() picks == '%v', want spread between '%s' and '%s'",
+			picks, backends[0].addr, backends[1].addr)
+	}
+
+	// The half-open circuits without trial requests left are skipped
+	now := time.Now()
+
+	backends[1].circuit = newCircuitBreaker(config.CircuitBreaker{CoolDown: time.Second, HalfOpenRequests: 1})
+	backends[1].circuit.open(now.Add(-time.Minute))
+	backends[1].active.Store(0)
+
+	if b := lc.next(backends, nil, nil, now); b != backends[1] {
+		t.Errorf("leastConnBalancer.next() == '%s', want '%s'", b.addr, backends[1].addr)
+	}
+
+	if b := lc.next(backends, nil, nil, now); b == backends[1] {
+		t.Errorf("leastConnBalancer.next() == '%s' without trial requests left", b.addr)
+	}
+}
+
+func Test_twoChoicesBalancer(t *testing.T) {
+	backends := newWeightedTestBackends(1, 1, 1)
+	tc := twoChoicesBalancer{}
+
+	backends[0].active.Store(1)
+	backends[1].active.Store(2)
+	backends[2].active.Store(3)
+
+	// The most loaded backend is never the less loaded of two choices
+	picks := countPicks(tc, backends, nil, 100)
+	if picks[backends[0]] == 0 || picks[backends[1]] == 0 || picks[backends[2]] != 0 {
+		t.Errorf("twoChoicesBalancer.next() picks == '%v'", picks)
+	}
+
+	backends[0].healthy.Store(false)
+	backends[1].healthy.Store(false)
+
+	picks = countPicks(tc, backends, nil, 10)
+	if picks[backends[2]] != 10 {
+		t.Errorf("twoChoicesBalancer.next() picks == '%v', want all for '%s'", picks, backends[2].addr)
+	}
+
+	backends[2].healthy.Store(false)
+
+	if b := tc.next(backends, nil, nil, time.Now()); b != nil {
+		t.Errorf("twoChoicesBalancer.next() == '%s', want '%v'", b.addr, nil)
+	}
+}
+
+func Test_hashBalancer(t *testing.T) {
+	backends := newWeightedTestBackends(1, 1, 1)
+
+	key, err := newCacheKeyTemplate("$(cookie::session)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hb := newHashBalancer(key, backends)
+	now := time.Now()
+
+	sessions := make(map[string]*backend)
+	picks := make(map[*backend]int)
+
+	for i := 0; i < 300; i++ {
+		ctx := new(fasthttp.RequestCtx)
+		ctx.Request.Header.SetCookie("session", fmt.Sprintf("session-%d", i))
+
+		b := hb.next(backends, ctx, nil, now)
+		if again := hb.next(backends, ctx, nil, now); again != b {
+			t.Fatalf("hashBalancer.next() == '%s', want '%s' for the same key", again.addr, b.addr)
+		}
+
+		sessions[fmt.Sprintf("session-%d", i)] = b
+		picks[b]++
+	}
+
+	for _, b := range backends {
+		if picks[b] < 50 {
+			t.Errorf("hashBalancer.next() picks of '%s' == '%d', want a fair share of 300", b.addr, picks[b])
+		}
+	}
+
+	// Only the keys of the unavailable backend are moved to other backends
+	backends[0].healthy.Store(false)
+
+	for session, prev := range sessions {
+		ctx := new(fasthttp.RequestCtx)
+		ctx.Request.Header.SetCookie("session", session)
+
+		b := hb.next(backends, ctx, nil, now)
+
+		if prev != backends[0] && b != prev {
+			t.Errorf("hashBalancer.next() key '%s' moved from '%s' to '%s'", session, prev.addr, b.addr)
+		} else if b == backends[0] {
+			t.Errorf("hashBalancer.next() key '%s' in the unhealthy backend", session)
+		}
+	}
+
+	// The requests without key are balanced with round-robin
+	backends[0].healthy.Store(true)
+
+	picks = countPicks(hb, backends, new(fasthttp.RequestCtx), 30)
+	for _, b := range backends {
+		if picks[b] != 10 {
+			t.Errorf("hashBalancer.next() picks without key == '%v', want 10 for every backend", picks)
+		}
+	}
+
+	for _, b := range backends {
+		b.healthy.Store(false)
+	}
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie("session", "session-1")
+
+	if b := hb.next(backends, ctx, nil, now); b != nil {
+		t.Errorf("hashBalancer.next() == '%s', want '%v'", b.addr, nil)
+	}
+}
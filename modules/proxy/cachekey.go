@@ -0,0 +1,79 @@
+package proxy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/savsgio/kratgo/modules/config"
+	"github.com/valyala/fasthttp"
+)
+
+// newCacheKeyTemplate parses a cache key template with variables, like "$(host)$(path)?$(query)".
+func newCacheKeyTemplate(key string) (cacheKeyTemplate, error) {
+	var t cacheKeyTemplate
+
+	if key == "" {
+		return nil, fmt.Errorf("Empty cache key")
+	}
+
+	offset := 0
+
+	for _, loc := range config.ConfigVarRegex.FindAllStringIndex(key, -1) {
+		if loc[0] > offset {
+			t = append(t, cacheKeyPart{literal: key[offset:loc[0]]})
+		}
+
+		variable := key[loc[0]:loc[1]]
+
+		configKey, evalKey, evalSubKey := config.ParseConfigKeys(variable)
+		if configKey == "" {
+			return nil, fmt.Errorf("Invalid variable: %s", variable)
+		}
+
+		for _, v := range cacheKeyForbiddenVars {
+			if strings.HasPrefix(evalKey, v) {
+				return nil, fmt.Errorf("The variable '%s' is not available before fetching the backend response", variable)
+			}
+		}
+
+		t = append(t, cacheKeyPart{param: ruleParam{name: evalKey, subKey: evalSubKey}})
+		offset = loc[1]
+	}
+
+	if offset < len(key) {
+		t = append(t, cacheKeyPart{literal: key[offset:]})
+	}
+
+	return t, nil
+}
+
+func (t cacheKeyTemplate) appendKey(dst []byte, ctx *fasthttp.RequestCtx) []byte {
+	for _, part := range t {
+		if part.param.name == "" {
+			dst = append(dst, part.literal...)
+		} else {
+			dst = append(dst, getEvalValue(ctx, part.param.name, part.param.subKey)...)
+		}
+	}
+
+	return dst
+}
+
+// appendCacheKey appends to dst the cache key of the request,
+// built with the template of the first matching rule or with the default one.
+func appendCacheKey(
+	dst []byte, ctx *fasthttp.RequestCtx, key cacheKeyTemplate, rules []cacheKeyRule, params *evalParams,
+) ([]byte, error) {
+	for _, r := range rules {
+		match, err := evalRule(ctx, r.rule, params)
+		if err != nil {
+			return dst, fmt.Errorf("Invalid cache key rule: %v", err)
+		}
+
+		if match {
+			return r.key.appendKey(dst, ctx), nil
+		}
+	}
+
+	return key.appendKey(dst, ctx), nil
+}
@@ -0,0 +1,176 @@
+package proxy
+
+import (
+	"testing"
+
+	"github.com/savsgio/kratgo/modules/config"
+	"github.com/valyala/fasthttp"
+)
+
+func Test_newCacheKeyTemplate(t *testing.T) {
+	type args struct {
+		key string
+	}
+
+	type want struct {
+		parts int
+		err   bool
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Default",
+			args: args{key: defaultCacheKey},
+			want: want{parts: 4},
+		},
+		{
+			name: "WithLiterals",
+			args: args{key: "kratgo:$(method):$(host)$(path)?$(query::q)"},
+			want: want{parts: 7},
+		},
+		{
+			name: "OnlyLiteral",
+			args: args{key: "kratgo"},
+			want: want{parts: 1},
+		},
+		{
+			name: "Empty",
+			args: args{key: ""},
+			want: want{err: true},
+		},
+		{
+			name: "InvalidVariable",
+			args: args{key: "$(host)$(fake)"},
+			want: want{err: true},
+		},
+		{
+			name: "ResponseVariable",
+			args: args{key: "$(host)$(statusCode)"},
+			want: want{err: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := newCacheKeyTemplate(tt.args.key)
+			if (err != nil) != tt.want.err {
+				t.Fatalf("newCacheKeyTemplate() Unexpected error: %v", err)
+			}
+
+			if len(tmpl) != tt.want.parts {
+				t.Errorf("newCacheKeyTemplate() parts == '%d', want '%d'", len(tmpl), tt.want.parts)
+			}
+		})
+	}
+}
+
+func Test_appendCacheKey(t *testing.T) {
+	cfg := testConfig()
+	cfg.FileConfig.Cache = config.ProxyCache{
+		Key: "$(host)$(path)",
+		Rules: []config.ProxyCacheRule{
+			{When: "$(path) == '/search/'", Key: "$(host)$(path)?$(query::q)"},
+			{When: "$(path) == '/lang/'", Key: "$(host)$(path)#$(cookie::lang)"},
+		},
+	}
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type args struct {
+		uri    string
+		cookie string
+	}
+
+	type want struct {
+		key string
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Default",
+			args: args{uri: "/fast/?q=kratgo"},
+			want: want{key: "www.kratgo.com/fast/"},
+		},
+		{
+			name: "QueryRule",
+			args: args{uri: "/search/?page=1&q=kratgo"},
+			want: want{key: "www.kratgo.com/search/?kratgo"},
+		},
+		{
+			name: "CookieRule",
+			args: args{uri: "/lang/", cookie: "es"},
+			want: want{key: "www.kratgo.com/lang/#es"},
+		},
+	}
+
+	pt := p.acquireTools()
+	defer p.releaseTools(pt)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := new(fasthttp.RequestCtx)
+			ctx.Request.SetRequestURI(tt.args.uri)
+			ctx.Request.Header.SetHost("www.kratgo.com")
+			if tt.args.cookie != "" {
+				ctx.Request.Header.SetCookie("lang", tt.args.cookie)
+			}
+
+			key, err := appendCacheKey(pt.cacheKey[:0], ctx, p.defaultCacheKey, p.cacheKeyRules, pt.params)
+			if err != nil {
+				t.Fatalf("appendCacheKey() Unexpected error: %v", err)
+			}
+
+			if string(key) != tt.want.key {
+				t.Errorf("appendCacheKey() = '%s', want '%s'", key, tt.want.key)
+			}
+		})
+	}
+
+	p.cacheKeyRules[0].params = p.cacheKeyRules[0].params[:0]
+
+	if _, err := appendCacheKey(nil, new(fasthttp.RequestCtx), p.defaultCacheKey, p.cacheKeyRules, pt.params); err == nil {
+		t.Errorf("appendCacheKey() expected error")
+	}
+}
+
+func Test_appendCacheKeyDefault(t *testing.T) {
+	tmpl, err := newCacheKeyTemplate(defaultCacheKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := acquireEvalParams()
+	defer releaseEvalParams(params)
+
+	// The requests with different queries are cached apart
+	for uri, want := range map[string]string{
+		"/fast/":           "www.kratgo.com/fast/?",
+		"/fast/?q=kratgo":  "www.kratgo.com/fast/?q=kratgo",
+		"/fastq=kratgo":    "www.kratgo.com/fastq=kratgo?",
+		"/fast/?q=kratgo2": "www.kratgo.com/fast/?q=kratgo2",
+	} {
+		ctx := new(fasthttp.RequestCtx)
+		ctx.Request.SetRequestURI(uri)
+		ctx.Request.Header.SetHost("www.kratgo.com")
+
+		key, err := appendCacheKey(nil, ctx, tmpl, nil, params)
+		if err != nil {
+			t.Fatalf("appendCacheKey() Unexpected error: %v", err)
+		}
+
+		if string(key) != want {
+			t.Errorf("appendCacheKey() = '%s', want '%s'", key, want)
+		}
+	}
+}
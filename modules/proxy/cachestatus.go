@@ -0,0 +1,162 @@
+package proxy
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// isSfTokenChar returns true if the character could be part of a structured field token (RFC 8941, section 3.3.4).
+func isSfTokenChar(c byte) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	}
+
+	switch c {
+	case '!', '#', '$', '%', '&', '\'', '*', '+', '-', '.', '^', '_', '`', '|', '~', ':', '/':
+		return true
+	}
+
+	return false
+}
+
+func isSfToken(s string) bool {
+	if len(s) == 0 || !(s[0] == '*' || (s[0] >= 'a' && s[0] <= 'z') || (s[0] >= 'A' && s[0] <= 'Z')) {
+		return false
+	}
+
+	for i := 1; i < len(s); i++ {
+		if !isSfTokenChar(s[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// appendSfString appends to dst s as a structured field string (RFC 8941, section 3.3.3).
+// The characters which could not be represented in it are skipped.
+func appendSfString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			dst = append(dst, '\\', c)
+		case c >= 0x20 && c < 0x7f:
+			dst = append(dst, c)
+		}
+	}
+
+	return append(dst, '"')
+}
+
+// appendSfItem appends to dst s as a structured field token if it's valid, or as a string otherwise.
+func appendSfItem(dst []byte, s string) []byte {
+	if isSfToken(s) {
+		return append(dst, s...)
+	}
+
+	return appendSfString(dst, s)
+}
+
+func (p *Proxy) parseDiagnostics() {
+	cfg := p.fileConfig.Response.Diagnostics
+
+	p.xCache = cfg.XCache
+	p.age = cfg.Age
+
+	if !cfg.CacheStatus {
+		return
+	}
+
+	node := cfg.Node
+	if node == "" {
+		if hostname, err := os.Hostname(); err == nil && hostname != "" {
+			node = hostname
+		} else {
+			node = defaultCacheStatusNode
+		}
+	}
+
+	p.cacheStatusNode = appendSfItem(nil, node)
+}
+
+// appendCacheStatus appends to dst the Cache-Status header value (RFC 9211) of the request.
+func appendCacheStatus(dst, node []byte, pt *proxyTools, now int64) []byte {
+	dst = append(dst, node...)
+
+	if (pt.status == cacheHit && !pt.collapsed) || (pt.status == cacheStale && pt.fwd == "") {
+		dst = append(dst, "; hit"...)
+
+	} else if pt.fwd != "" {
+		dst = append(dst, "; fwd="...)
+		dst = append(dst, pt.fwd...)
+
+		if pt.fwdStatus > 0 {
+			dst = append(dst, "; fwd-status="...)
+			dst = strconv.AppendInt(dst, int64(pt.fwdStatus), 10)
+		}
+
+		if pt.collapsed {
+			dst = append(dst, "; collapsed"...)
+		}
+
+		if pt.stored {
+			dst = append(dst, "; stored"...)
+		}
+	}
+
+	// The remaining freshness of the served cached response, negative if it's stale
+	if pt.storedAt > 0 && pt.ttl > 0 {
+		dst = append(dst, "; ttl="...)
+		dst = strconv.AppendInt(dst, pt.storedAt+pt.ttl-now, 10)
+	}
+
+	if pt.nocacheRule != "" {
+		dst = append(dst, "; detail="...)
+		dst = appendSfString(dst, "nocache: "+pt.nocacheRule)
+	}
+
+	return dst
+}
+
+func xCacheValue(status cacheStatus) string {
+	switch status {
+	case cacheHit, cacheRevalidated:
+		return xCacheHit
+	case cacheStale:
+		return xCacheStale
+	case cacheBypass:
+		return xCacheBypass
+	default:
+		return xCacheMiss
+	}
+}
+
+// writeCacheStatus adds to the response the configured diagnostic headers, which tell the client
+// if the response has been served from cache or from the backend, and why.
+func (p *Proxy) writeCacheStatus(ctx *fasthttp.RequestCtx, pt *proxyTools) {
+	now := time.Now().Unix()
+
+	if p.xCache {
+		ctx.Response.Header.Set(headerXCache, xCacheValue(pt.status))
+	}
+
+	// The age is only known for the responses served from cache
+	if p.age && pt.storedAt > 0 {
+		age := now - pt.storedAt
+		if age < 0 {
+			age = 0
+		}
+
+		ctx.Response.Header.SetBytesV(fasthttp.HeaderAge, strconv.AppendInt(nil, age, 10))
+	}
+
+	if len(p.cacheStatusNode) > 0 {
+		ctx.Response.Header.SetBytesV(headerCacheStatus, appendCacheStatus(nil, p.cacheStatusNode, pt, now))
+	}
+}
@@ -0,0 +1,232 @@
+package proxy
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/savsgio/kratgo/modules/cache"
+	"github.com/savsgio/kratgo/modules/config"
+	"github.com/valyala/fasthttp"
+)
+
+func Test_appendSfItem(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "kratgo", want: "kratgo"},
+		{value: "cache-1.example.com", want: "cache-1.example.com"},
+		{value: "1cache", want: "\"1cache\""},
+		{value: "my cache", want: "\"my cache\""},
+		{value: "say \"hi\" \\o/", want: "\"say \\\"hi\\\" \\\\o/\""},
+		{value: "tab\tñ", want: "\"tab\""},
+		{value: "", want: "\"\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := string(appendSfItem(nil, tt.value)); got != tt.want {
+				t.Errorf("appendSfItem() == '%s', want '%s'", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_appendCacheStatus(t *testing.T) {
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name string
+		pt   proxyTools
+		want string
+	}{
+		{
+			name: "Hit",
+			pt:   proxyTools{status: cacheHit, storedAt: now - 10, ttl: 60},
+			want: "kratgo; hit; ttl=50",
+		},
+		{
+			name: "HitWithoutLifetime",
+			pt:   proxyTools{status: cacheHit, storedAt: now - 10},
+			want: "kratgo; hit",
+		},
+		{
+			name: "Grace",
+			pt:   proxyTools{status: cacheStale, storedAt: now - 70, ttl: 60},
+			want: "kratgo; hit; ttl=-10",
+		},
+		{
+			name: "StaleIfError",
+			pt:   proxyTools{status: cacheStale, fwd: fwdStale, fwdStatus: 503, storedAt: now - 70, ttl: 60},
+			want: "kratgo; fwd=stale; fwd-status=503; ttl=-10",
+		},
+		{
+			name: "Collapsed",
+			pt:   proxyTools{status: cacheHit, fwd: fwdURIMiss, collapsed: true, storedAt: now, ttl: 60},
+			want: "kratgo; fwd=uri-miss; collapsed; ttl=60",
+		},
+		{
+			name: "Miss",
+			pt:   proxyTools{status: cacheMiss, fwd: fwdURIMiss, fwdStatus: 200, stored: true},
+			want: "kratgo; fwd=uri-miss; fwd-status=200; stored",
+		},
+		{
+			name: "Revalidated",
+			pt:   proxyTools{status: cacheRevalidated, fwd: fwdStale, fwdStatus: 304, stored: true},
+			want: "kratgo; fwd=stale; fwd-status=304; stored",
+		},
+		{
+			name: "BackendError",
+			pt:   proxyTools{status: cacheMiss, fwd: fwdVaryMiss},
+			want: "kratgo; fwd=vary-miss",
+		},
+		{
+			name: "Bypass",
+			pt:   proxyTools{status: cacheBypass, fwd: fwdBypass, fwdStatus: 200, nocacheRule: "$(method) == 'POST'"},
+			want: "kratgo; fwd=bypass; fwd-status=200; detail=\"nocache: $(method) == 'POST'\"",
+		},
+		{
+			name: "Error",
+			pt:   proxyTools{status: cacheMiss},
+			want: "kratgo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(appendCacheStatus(nil, []byte("kratgo"), &tt.pt, now)); got != tt.want {
+				t.Errorf("appendCacheStatus() == '%s', want '%s'", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxy_handlerCacheStatus(t *testing.T) {
+	cfg := testConfig()
+	cfg.FileConfig.Nocache = []string{"$(method) == 'POST'"}
+	cfg.FileConfig.Response.Diagnostics = config.Diagnostics{
+		XCache:      true,
+		Age:         true,
+		CacheStatus: true,
+		Node:        "node-1",
+	}
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.backends = newTestBackends(&slowBackend{
+		body:    []byte("Kratgo body"),
+		headers: map[string]string{fasthttp.HeaderCacheControl: "max-age=60"},
+	})
+
+	type args struct {
+		method string
+		age    int64
+	}
+
+	type want struct {
+		xCache      string
+		cacheStatus string
+		age         int64 // -1 if not present
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Miss",
+			args: args{method: fasthttp.MethodGet},
+			want: want{xCache: xCacheMiss, cacheStatus: "node-1; fwd=uri-miss; fwd-status=200; stored", age: -1},
+		},
+		{
+			name: "Hit",
+			args: args{method: fasthttp.MethodGet, age: 10},
+			want: want{xCache: xCacheHit, cacheStatus: "node-1; hit; ttl=50", age: 10},
+		},
+		{
+			name: "Bypass",
+			args: args{method: fasthttp.MethodPost},
+			want: want{
+				xCache:      xCacheBypass,
+				cacheStatus: "node-1; fwd=bypass; fwd-status=200; detail=\"nocache: $(method) == 'POST'\"",
+				age:         -1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.args.age > 0 {
+				entry := cache.AcquireEntry()
+				if err := p.cache.Get("www.kratgo.com/status/?", entry); err != nil {
+					t.Fatal(err)
+				}
+
+				r := entry.GetResponse([]byte("/status/"))
+				if r == nil {
+					t.Fatal("Proxy.handler() response not found in cache")
+				}
+
+				r.StoredAt = time.Now().Unix() - tt.args.age
+				p.cache.Set("www.kratgo.com/status/?", *entry)
+
+				cache.ReleaseEntry(entry)
+			}
+
+			ctx := new(fasthttp.RequestCtx)
+			ctx.Request.Header.SetMethod(tt.args.method)
+			ctx.Request.SetRequestURI("/status/")
+			ctx.Request.Header.SetHost("www.kratgo.com")
+
+			p.handler(ctx)
+
+			if xCache := string(ctx.Response.Header.Peek(headerXCache)); xCache != tt.want.xCache {
+				t.Errorf("Proxy.handler() X-Cache == '%s', want '%s'", xCache, tt.want.xCache)
+			}
+
+			// The time could change between saving the response and serving it
+			cacheStatus := string(ctx.Response.Header.Peek(headerCacheStatus))
+			if cacheStatus != tt.want.cacheStatus && !strings.HasPrefix(cacheStatus, "node-1; hit; ttl=4") {
+				t.Errorf("Proxy.handler() Cache-Status == '%s', want '%s'", cacheStatus, tt.want.cacheStatus)
+			}
+
+			age := int64(-1)
+			if value := ctx.Response.Header.Peek(fasthttp.HeaderAge); len(value) > 0 {
+				if age, err = strconv.ParseInt(string(value), 10, 64); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if age != tt.want.age && age != tt.want.age+1 {
+				t.Errorf("Proxy.handler() Age == '%d', want '%d'", age, tt.want.age)
+			}
+		})
+	}
+}
+
+func TestProxy_handlerCacheStatusDisabled(t *testing.T) {
+	p, err := New(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.backends = newTestBackends(&slowBackend{body: []byte("Kratgo body")})
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.SetRequestURI("/status/")
+	ctx.Request.Header.SetHost("www.kratgo.com")
+
+	p.handler(ctx)
+
+	for _, header := range []string{headerXCache, headerCacheStatus, fasthttp.HeaderAge} {
+		if value := ctx.Response.Header.Peek(header); len(value) > 0 {
+			t.Errorf("Proxy.handler() unexpected '%s' header: %s", header, value)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package proxy
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/savsgio/kratgo/modules/config"
+	"github.com/valyala/fasthttp"
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+func newCircuitBreaker(cfg config.CircuitBreaker) *circuitBreaker {
+	return &circuitBreaker{cfg: cfg}
+}
+
+// allow returns true if a request could be sent to the backend. The open circuit becomes half-open
+// after the cool-down, and then only lets pass the trial requests.
+func (cb *circuitBreaker) allow(now time.Time) bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == circuitOpen {
+		if now.Before(cb.openedAt.Add(cb.cfg.CoolDown)) {
+			return false
+		}
+
+		cb.state = circuitHalfOpen
+		cb.probes = 0
+		cb.successes = 0
+	}
+
+	if cb.state == circuitHalfOpen {
+		if cb.probes >= cb.cfg.HalfOpenRequests {
+			return false
+		}
+
+		cb.probes++
+	}
+
+	return true
+}
+
+// ready returns true if allow would let pass a request, without changing the state of the circuit.
+func (cb *circuitBreaker) ready(now time.Time) bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case circuitOpen:
+		return !now.Before(cb.openedAt.Add(cb.cfg.CoolDown))
+	case circuitHalfOpen:
+		return cb.probes < cb.cfg.HalfOpenRequests
+	default:
+		return true
+	}
+}
+
+func (cb *circuitBreaker) open(now time.Time) {
+	cb.state = circuitOpen
+	cb.openedAt = now
+}
+
+func (cb *circuitBreaker) close(now time.Time) {
+	cb.state = circuitClosed
+	cb.windowStart = now
+	cb.requests = 0
+	cb.failures = 0
+}
+
+// record counts the result of a request to the backend, and returns the state of the circuit
+// and true if it has changed.
+func (cb *circuitBreaker) record(failed bool, now time.Time) (circuitState, bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case circuitHalfOpen:
+		if failed {
+			cb.open(now)
+			return cb.state, true
+		}
+
+		cb.successes++
+		if cb.successes >= cb.cfg.HalfOpenRequests {
+			cb.close(now)
+			return cb.state, true
+		}
+
+	case circuitClosed:
+		if !now.Before(cb.windowStart.Add(cb.cfg.Window)) {
+			cb.close(now)
+		}
+
+		cb.requests++
+		if failed {
+			cb.failures++
+		}
+
+		if cb.requests >= cb.cfg.MinRequests && float64(cb.failures)/float64(cb.requests) >= cb.cfg.FailureRate {
+			cb.open(now)
+			return cb.state, true
+		}
+	}
+
+	// The results of the requests sent before opening the circuit are ignored
+	return cb.state, false
+}
+
+func (cb *circuitBreaker) getState() circuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return cb.state
+}
+
+func (p *Proxy) parseCircuitBreaker() error {
+	cb := p.fileConfig.CircuitBreaker
+
+	if cb.ErrorPage != "" {
+		page, err := os.ReadFile(cb.ErrorPage)
+		if err != nil {
+			return fmt.Errorf("Could not read the error page '%s': %v", cb.ErrorPage, err)
+		}
+
+		p.errorPage = page
+	}
+
+	if !cb.Enabled {
+		return nil
+	}
+
+	switch {
+	case cb.FailureRate < 0 || cb.FailureRate > 1:
+		return fmt.Errorf("The failure rate of the circuit breaker must be between 0 and 1")
+	case cb.MinRequests < 0 || cb.HalfOpenRequests < 0:
+		return fmt.Errorf("The requests of the circuit breaker must be greater than or equal to 0")
+	case cb.Window < 0 || cb.CoolDown < 0:
+		return fmt.Errorf("The window and cool-down of the circuit breaker must be greater than or equal to 0")
+	}
+
+	if cb.FailureRate == 0 {
+		cb.FailureRate = defaultCircuitFailureRate
+	}
+
+	if cb.MinRequests == 0 {
+		cb.MinRequests = defaultCircuitMinRequests
+	}
+
+	if cb.Window == 0 {
+		cb.Window = defaultCircuitWindow
+	}
+
+	if cb.CoolDown == 0 {
+		cb.CoolDown = defaultCircuitCoolDown
+	}
+
+	if cb.HalfOpenRequests == 0 {
+		cb.HalfOpenRequests = defaultCircuitHalfOpenRequests
+	}
+
+	p.circuitBreaker = cb
+
+	now := time.Now()
+
+	for _, b := range p.allBackends() {
+		b.circuit = newCircuitBreaker(cb)
+		b.circuit.close(now)
+	}
+
+	return nil
+}
+
+// recordBackendResult counts the result of a request in the circuit of the backend.
+// The connection errors, timeouts and server errors are failures.
+func (p *Proxy) recordBackendResult(b *backend, err error, resp *fasthttp.Response) {
+	if b.circuit == nil {
+		return
+	}
+
+	failed := err != nil || resp.StatusCode() >= fasthttp.StatusInternalServerError
+
+	state, changed := b.circuit.record(failed, time.Now())
+	if !changed {
+		return
+	}
+
+	if state == circuitOpen {
+		p.log.Warningf("Circuit of backend '%s' opened for %s", b.addr, p.circuitBreaker.CoolDown)
+	} else {
+		p.log.Infof("Circuit of backend '%s' %s", b.addr, state)
+	}
+}
+
+// writeUnavailable writes the configured error page, since no backend is available.
+func (p *Proxy) writeUnavailable(ctx *fasthttp.RequestCtx) {
+	if len(p.errorPage) > 0 {
+		ctx.Response.Reset()
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		ctx.SetContentType(errorPageContentType)
+		ctx.SetBody(p.errorPage)
+	} else {
+		ctx.Error(ErrNoHealthyBackend.Error(), fasthttp.StatusServiceUnavailable)
+	}
+
+	if p.circuitBreaker.CoolDown > 0 {
+		retryAfter := strconv.FormatInt(int64(p.circuitBreaker.CoolDown/time.Second), 10)
+		ctx.Response.Header.Set(fasthttp.HeaderRetryAfter, retryAfter)
+	}
+}
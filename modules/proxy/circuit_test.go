@@ -0,0 +1,303 @@
+package proxy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/savsgio/kratgo/modules/cache"
+	"github.com/savsgio/kratgo/modules/config"
+	"github.com/valyala/fasthttp"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+
+	cb := newCircuitBreaker(config.CircuitBreaker{
+		FailureRate:      0.5,
+		MinRequests:      4,
+		Window:           time.Minute,
+		CoolDown:         10 * time.Second,
+		HalfOpenRequests: 2,
+	})
+	cb.close(now)
+
+	type step struct {
+		at      time.Duration
+		allow   bool // Checks if the request is allowed, instead of recording its result
+		failed  bool
+		want    bool // Allowed, or state changed
+		wantSta circuitState
+	}
+
+	steps := []step{
+		{failed: true, wantSta: circuitClosed},
+		{failed: false, wantSta: circuitClosed},
+		{failed: true, wantSta: circuitClosed},
+
+		// The failures of the previous window are not counted
+		{at: time.Minute, failed: true, wantSta: circuitClosed},
+		{at: time.Minute, failed: false, wantSta: circuitClosed},
+		{at: time.Minute, failed: false, wantSta: circuitClosed},
+		{at: time.Minute, failed: true, want: true, wantSta: circuitOpen},
+
+		{at: time.Minute + 5*time.Second, allow: true, want: false, wantSta: circuitOpen},
+		{at: time.Minute + 10*time.Second, allow: true, want: true, wantSta: circuitHalfOpen},
+		{at: time.Minute + 10*time.Second, allow: true, want: true, wantSta: circuitHalfOpen},
+		{at: time.Minute + 10*time.Second, allow: true, want: false, wantSta: circuitHalfOpen},
+		{at: time.Minute + 11*time.Second, failed: false, wantSta: circuitHalfOpen},
+		{at: time.Minute + 11*time.Second, failed: true, want: true, wantSta: circuitOpen},
+
+		{at: time.Minute + 15*time.Second, allow: true, want: false, wantSta: circuitOpen},
+		{at: time.Minute + 21*time.Second, allow: true, want: true, wantSta: circuitHalfOpen},
+		{at: time.Minute + 21*time.Second, allow: true, want: true, wantSta: circuitHalfOpen},
+		{at: time.Minute + 22*time.Second, failed: false, wantSta: circuitHalfOpen},
+		{at: time.Minute + 22*time.Second, failed: false, want: true, wantSta: circuitClosed},
+		{at: time.Minute + 22*time.Second, allow: true, want: true, wantSta: circuitClosed},
+	}
+
+	for i, s := range steps {
+		var got bool
+
+		if s.allow {
+			got = cb.allow(now.Add(s.at))
+		} else {
+			_, got = cb.record(s.failed, now.Add(s.at))
+		}
+
+		if got != s.want {
+			t.Errorf("circuitBreaker step %d == '%v', want '%v'", i, got, s.want)
+		}
+
+		if state := cb.getState(); state != s.wantSta {
+			t.Errorf("circuitBreaker step %d state == '%s', want '%s'", i, state, s.wantSta)
+		}
+	}
+}
+
+func TestProxy_parseCircuitBreaker(t *testing.T) {
+	errorPage := filepath.Join(t.TempDir(), "503.html")
+	if err := os.WriteFile(errorPage, []byte("<h1>Down</h1>"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	type args struct {
+		circuitBreaker config.CircuitBreaker
+	}
+
+	type want struct {
+		circuitBreaker config.CircuitBreaker
+		errorPage      string
+		err            bool
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Disabled",
+			args: args{
+				circuitBreaker: config.CircuitBreaker{MinRequests: 5, ErrorPage: errorPage},
+			},
+			want: want{
+				circuitBreaker: config.CircuitBreaker{},
+				errorPage:      "<h1>Down</h1>",
+			},
+		},
+		{
+			name: "Defaults",
+			args: args{
+				circuitBreaker: config.CircuitBreaker{Enabled: true},
+			},
+			want: want{
+				circuitBreaker: config.CircuitBreaker{
+					Enabled:          true,
+					FailureRate:      defaultCircuitFailureRate,
+					MinRequests:      defaultCircuitMinRequests,
+					Window:           defaultCircuitWindow,
+					CoolDown:         defaultCircuitCoolDown,
+					HalfOpenRequests: defaultCircuitHalfOpenRequests,
+				},
+			},
+		},
+		{
+			name: "InvalidFailureRate",
+			args: args{
+				circuitBreaker: config.CircuitBreaker{Enabled: true, FailureRate: 1.5},
+			},
+			want: want{
+				err: true,
+			},
+		},
+		{
+			name: "InvalidCoolDown",
+			args: args{
+				circuitBreaker: config.CircuitBreaker{Enabled: true, CoolDown: -time.Second},
+			},
+			want: want{
+				err: true,
+			},
+		},
+		{
+			name: "InvalidErrorPage",
+			args: args{
+				circuitBreaker: config.CircuitBreaker{ErrorPage: filepath.Join(t.TempDir(), "missing.html")},
+			},
+			want: want{
+				err: true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.FileConfig.CircuitBreaker = tt.args.circuitBreaker
+
+			p, err := New(cfg)
+			if (err != nil) != tt.want.err {
+				t.Fatalf("New() Unexpected error: %v", err)
+			}
+
+			if tt.want.err {
+				return
+			}
+
+			if p.circuitBreaker != tt.want.circuitBreaker {
+				t.Errorf("Proxy.parseCircuitBreaker() == '%+v', want '%+v'", p.circuitBreaker, tt.want.circuitBreaker)
+			}
+
+			if string(p.errorPage) != tt.want.errorPage {
+				t.Errorf("Proxy.parseCircuitBreaker() error page == '%s', want '%s'", p.errorPage, tt.want.errorPage)
+			}
+
+			for _, b := range p.backends {
+				if enabled := b.circuit != nil; enabled != tt.want.circuitBreaker.Enabled {
+					t.Errorf("Proxy.parseCircuitBreaker() backend '%s' circuit enabled == '%v'", b.addr, enabled)
+				}
+			}
+		})
+	}
+}
+
+func TestProxy_handlerCircuitBreaker(t *testing.T) {
+	errorPage := filepath.Join(t.TempDir(), "503.html")
+	if err := os.WriteFile(errorPage, []byte("<h1>Down</h1>"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testConfig()
+	cfg.FileConfig.CircuitBreaker = config.CircuitBreaker{
+		Enabled:     true,
+		FailureRate: 1,
+		MinRequests: 2,
+		CoolDown:    time.Minute,
+		ErrorPage:   errorPage,
+	}
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := &mockBackend{body: []byte("Error"), statusCode: fasthttp.StatusBadGateway}
+	p.backends = newTestBackends(backend)
+	p.backends[0].circuit = newCircuitBreaker(p.circuitBreaker)
+
+	// An expired response which is only retained to revalidate it, and other one which is kept to serve it stale
+	for path, keep := range map[string]bool{"/retained/": false, "/stale/": true} {
+		response := cache.AcquireResponse()
+		response.Host = []byte("www.kratgo.com")
+		response.Path = []byte(path)
+		response.Body = []byte("Stale")
+		response.StoredAt = time.Now().Add(-2 * time.Minute).Unix()
+		response.TTL = 60
+		response.Retain = 3600
+
+		if keep {
+			response.Keep = 3600
+		}
+
+		entry := cache.AcquireEntry()
+		entry.SetResponse(*response)
+		p.cache.Set("www.kratgo.com"+path+"?", *entry)
+
+		cache.ReleaseEntry(entry)
+		cache.ReleaseResponse(response)
+	}
+
+	type want struct {
+		statusCode    int
+		body          string
+		backendCalled bool
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want want
+	}{
+		{
+			name: "BackendError1",
+			path: "/data/",
+			want: want{statusCode: fasthttp.StatusBadGateway, body: "Error", backendCalled: true},
+		},
+		{
+			name: "BackendError2",
+			path: "/retained/",
+			want: want{statusCode: fasthttp.StatusBadGateway, body: "Error", backendCalled: true},
+		},
+		{
+			name: "ErrorPage",
+			path: "/data/",
+			want: want{statusCode: fasthttp.StatusServiceUnavailable, body: "<h1>Down</h1>"},
+		},
+		{
+			name: "Retained",
+			path: "/retained/",
+			want: want{statusCode: fasthttp.StatusServiceUnavailable, body: "<h1>Down</h1>"},
+		},
+		{
+			name: "Stale",
+			path: "/stale/",
+			want: want{statusCode: fasthttp.StatusOK, body: "Stale"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend.called = false
+
+			ctx := new(fasthttp.RequestCtx)
+			ctx.Request.SetRequestURI(tt.path)
+			ctx.Request.Header.SetHost("www.kratgo.com")
+
+			p.handler(ctx)
+
+			if statusCode := ctx.Response.StatusCode(); statusCode != tt.want.statusCode {
+				t.Errorf("Proxy.handler() status code == '%d', want '%d'", statusCode, tt.want.statusCode)
+			}
+
+			if body := string(ctx.Response.Body()); body != tt.want.body {
+				t.Errorf("Proxy.handler() body == '%s', want '%s'", body, tt.want.body)
+			}
+
+			if backend.called != tt.want.backendCalled {
+				t.Errorf("Proxy.handler() backend called == '%v', want '%v'", backend.called, tt.want.backendCalled)
+			}
+
+			if tt.want.statusCode == fasthttp.StatusServiceUnavailable {
+				if retryAfter := string(ctx.Response.Header.Peek(fasthttp.HeaderRetryAfter)); retryAfter != "60" {
+					t.Errorf("Proxy.handler() Retry-After == '%s', want '%s'", retryAfter, "60")
+				}
+			}
+		})
+	}
+
+	if status := p.Backends()[0]; status.Circuit != "open" {
+		t.Errorf("Proxy.Backends() circuit == '%s', want '%s'", status.Circuit, "open")
+	}
+}
@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"time"
+
+	gstrconv "github.com/savsgio/gotils/strconv"
+)
+
+func newCoalescer() *coalescer {
+	return &coalescer{
+		calls: make(map[string]*coalescedCall),
+	}
+}
+
+// join returns the in flight call of the key, and true if the caller is the leader
+// which must fetch the backend response and finish the call with done().
+func (c *coalescer) join(key []byte) (*coalescedCall, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if call, ok := c.calls[gstrconv.B2S(key)]; ok {
+		return call, false
+	}
+
+	call := &coalescedCall{done: make(chan struct{})}
+	c.calls[string(key)] = call
+
+	return call, true
+}
+
+// done finishes the call of the key and wakes up its waiting requests.
+func (c *coalescer) done(key []byte, call *coalescedCall, err error) {
+	c.mu.Lock()
+	delete(c.calls, gstrconv.B2S(key))
+	c.mu.Unlock()
+
+	call.err = err
+	close(call.done)
+}
+
+// wait blocks until the call is done or the timeout is reached, returning false in such case.
+func (call *coalescedCall) wait(timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-call.done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
@@ -0,0 +1,164 @@
+package proxy
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+type slowBackend struct {
+	calls int32
+	delay time.Duration
+
+	body    []byte
+	headers map[string]string
+}
+
+func (mock *slowBackend) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	atomic.AddInt32(&mock.calls, 1)
+	time.Sleep(mock.delay)
+
+	resp.SetBody(mock.body)
+	resp.SetStatusCode(fasthttp.StatusOK)
+
+	for k, v := range mock.headers {
+		resp.Header.Set(k, v)
+	}
+
+	return nil
+}
+
+func TestCoalescer_join(t *testing.T) {
+	c := newCoalescer()
+	key := []byte("www.kratgo.com/data")
+
+	call, leader := c.join(key)
+	if !leader {
+		t.Fatal("coalescer.join() leader == false, want true")
+	}
+
+	call2, leader2 := c.join(key)
+	if leader2 {
+		t.Error("coalescer.join() leader == true, want false")
+	}
+
+	if call2 != call {
+		t.Error("coalescer.join() returns a different call for the same key")
+	}
+
+	if call.wait(10 * time.Millisecond) {
+		t.Error("coalescedCall.wait() == true, want false")
+	}
+
+	c.done(key, call, nil)
+
+	if !call.wait(10 * time.Millisecond) {
+		t.Error("coalescedCall.wait() == false, want true")
+	}
+
+	if _, leader := c.join(key); !leader {
+		t.Error("coalescer.join() leader == false after done, want true")
+	}
+}
+
+func TestProxy_fetchCoalesced(t *testing.T) {
+	type args struct {
+		headers map[string]string
+		timeout time.Duration
+	}
+
+	type want struct {
+		calls int32
+	}
+
+	requests := 10
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Collapsed",
+			args: args{
+				timeout: 5 * time.Second,
+			},
+			want: want{
+				calls: 1,
+			},
+		},
+		{
+			name: "Uncacheable",
+			args: args{
+				headers: map[string]string{"Cache-Control": "no-store"},
+				timeout: 5 * time.Second,
+			},
+			want: want{
+				calls: int32(requests),
+			},
+		},
+		{
+			name: "Timeout",
+			args: args{
+				timeout: time.Millisecond,
+			},
+			want: want{
+				calls: int32(requests),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.FileConfig.Coalescing.Enabled = true
+			cfg.FileConfig.Coalescing.Timeout = tt.args.timeout
+
+			p, err := New(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			backend := &slowBackend{
+				delay:   100 * time.Millisecond,
+				body:    []byte("Kratgo"),
+				headers: tt.args.headers,
+			}
+			p.backends = newTestBackends(backend)
+
+			wg := sync.WaitGroup{}
+			bodies := make([]string, requests)
+
+			for i := 0; i < requests; i++ {
+				wg.Add(1)
+
+				go func(i int) {
+					defer wg.Done()
+
+					ctx := new(fasthttp.RequestCtx)
+					ctx.Request.SetRequestURI("/data")
+					ctx.Request.Header.SetHost("www.kratgo.com")
+
+					p.handler(ctx)
+
+					bodies[i] = string(ctx.Response.Body())
+				}(i)
+			}
+
+			wg.Wait()
+
+			if calls := atomic.LoadInt32(&backend.calls); calls != tt.want.calls {
+				t.Errorf("Proxy.fetchCoalesced() backend calls == '%d', want '%d'", calls, tt.want.calls)
+			}
+
+			for i, body := range bodies {
+				if body != string(backend.body) {
+					t.Errorf("Proxy.fetchCoalesced() request %d body == '%s', want '%s'", i, body, backend.body)
+				}
+			}
+		})
+	}
+}
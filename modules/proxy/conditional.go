@@ -0,0 +1,196 @@
+package proxy
+
+import (
+	"bytes"
+	"hash/fnv"
+	"strconv"
+
+	"github.com/savsgio/kratgo/modules/cache"
+	"github.com/valyala/fasthttp"
+)
+
+// appendWeakETag appends to dst a weak entity tag generated from the length and the hash of the body.
+func appendWeakETag(dst, body []byte) []byte {
+	h := fnv.New64a()
+	h.Write(body)
+
+	dst = append(dst, weakETagPrefix...)
+	dst = append(dst, '"')
+	dst = strconv.AppendUint(dst, uint64(len(body)), 16)
+	dst = append(dst, '-')
+	dst = strconv.AppendUint(dst, h.Sum64(), 16)
+
+	return append(dst, '"')
+}
+
+// isWeakETag returns true if the entity tag is weak.
+func isWeakETag(etag []byte) bool {
+	return bytes.HasPrefix(etag, weakETagPrefix)
+}
+
+// appendWeakenedETag appends to dst the weak version of the entity tag.
+func appendWeakenedETag(dst, etag []byte) []byte {
+	if !isWeakETag(etag) {
+		dst = append(dst, weakETagPrefix...)
+	}
+
+	return append(dst, etag...)
+}
+
+// etagWeakMatch compares two entity tags ignoring their weakness.
+func etagWeakMatch(a, b []byte) bool {
+	return len(a) > 0 && bytes.Equal(bytes.TrimPrefix(a, weakETagPrefix), bytes.TrimPrefix(b, weakETagPrefix))
+}
+
+// matchIfNoneMatch returns true if any of the entity tags of the If-None-Match value matches the etag.
+func matchIfNoneMatch(value, etag []byte) bool {
+	if bytes.Equal(bytes.TrimSpace(value), etagAny) {
+		return true
+	}
+
+	for len(value) > 0 {
+		var item []byte
+
+		if i := bytes.IndexByte(value, ','); i >= 0 {
+			item, value = value[:i], value[i+1:]
+		} else {
+			item, value = value, nil
+		}
+
+		if etagWeakMatch(bytes.TrimSpace(item), etag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isNotModified returns true if the conditional headers of the request match the validators
+// of the cached response, so it could be answered with a 304 Not Modified.
+func isNotModified(req *fasthttp.RequestHeader, r *cache.Response) bool {
+	if (!req.IsGet() && !req.IsHead()) || responseStatusCode(r) != fasthttp.StatusOK {
+		return false
+	}
+
+	return matchValidators(req.Peek(fasthttp.HeaderIfNoneMatch), req.Peek(fasthttp.HeaderIfModifiedSince),
+		r.GetHeader(headerETag), r.GetHeader(headerLastModified))
+}
+
+// matchValidators returns true if the If-None-Match or If-Modified-Since values match the validators
+// of a response. If-None-Match takes precedence over If-Modified-Since, as defined in RFC 9110.
+func matchValidators(ifNoneMatch, ifModifiedSince, etag, lastModified []byte) bool {
+	if len(ifNoneMatch) > 0 {
+		return matchIfNoneMatch(ifNoneMatch, etag)
+	}
+
+	if len(ifModifiedSince) == 0 || len(lastModified) == 0 {
+		return false
+	}
+
+	since, err := fasthttp.ParseHTTPDate(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+
+	modified, err := fasthttp.ParseHTTPDate(lastModified)
+	if err != nil {
+		return false
+	}
+
+	return !modified.After(since)
+}
+
+// writeNotModified writes a 304 Not Modified response with the cached headers allowed in it.
+func writeNotModified(ctx *fasthttp.RequestCtx, r *cache.Response) {
+	for _, h := range r.Headers {
+		for _, name := range notModifiedHeaders {
+			if bytes.EqualFold(h.Key, name) {
+				ctx.Response.Header.SetCanonical(h.Key, h.Value)
+				break
+			}
+		}
+	}
+
+	ctx.Response.SetStatusCode(fasthttp.StatusNotModified)
+	ctx.Response.SkipBody = true
+}
+
+// setNotModified replaces the response with a 304 Not Modified, keeping only the headers allowed in it.
+func setNotModified(resp *fasthttp.Response) {
+	h := new(fasthttp.ResponseHeader)
+	resp.Header.CopyTo(h)
+
+	resp.Reset()
+
+	h.VisitAll(func(k, v []byte) {
+		for _, name := range notModifiedHeaders {
+			if bytes.EqualFold(k, name) {
+				resp.Header.SetCanonical(k, v)
+				break
+			}
+		}
+	})
+
+	resp.SetStatusCode(fasthttp.StatusNotModified)
+	resp.SkipBody = true
+}
+
+// isGeneratedETag returns true if the entity tag has been generated by the proxy from the body,
+// so it's unknown by the backend.
+func isGeneratedETag(etag, body []byte) bool {
+	return isWeakETag(etag) && bytes.Equal(etag, appendWeakETag(nil, body))
+}
+
+// hasBackendValidators returns true if the backend response has an ETag or a Last-Modified header,
+// which could be used to revalidate it.
+func hasBackendValidators(h *fasthttp.ResponseHeader, body []byte) bool {
+	if len(h.PeekBytes(headerLastModified)) > 0 {
+		return true
+	}
+
+	etag := h.PeekBytes(headerETag)
+
+	return len(etag) > 0 && !isGeneratedETag(etag, body)
+}
+
+// setConditionalHeaders replaces the conditional headers of the request with the validators
+// of the cached response, so the backend responds with a 304 Not Modified if it has not changed.
+func setConditionalHeaders(req *fasthttp.RequestHeader, r *cache.Response) {
+	for _, name := range conditionalHeaders {
+		req.Del(name)
+	}
+
+	if etag := r.GetHeader(headerETag); len(etag) > 0 && !isGeneratedETag(etag, r.Body) {
+		req.SetBytesV(fasthttp.HeaderIfNoneMatch, etag)
+	}
+
+	if lastModified := r.GetHeader(headerLastModified); len(lastModified) > 0 {
+		req.SetBytesV(fasthttp.HeaderIfModifiedSince, lastModified)
+	}
+}
+
+// mergeNotModified replaces the 304 Not Modified backend response with the cached one,
+// updated with the headers of the 304 response (RFC 9111, section 4.3.4).
+func mergeNotModified(resp *fasthttp.Response, r *cache.Response) {
+	h := new(fasthttp.ResponseHeader)
+	resp.Header.CopyTo(h)
+
+	resp.Reset()
+
+	for _, rh := range r.Headers {
+		resp.Header.SetCanonical(rh.Key, rh.Value)
+	}
+
+	h.VisitAll(func(k, v []byte) {
+		for _, name := range notModifiedIgnoredHeaders {
+			if bytes.EqualFold(k, name) {
+				return
+			}
+		}
+
+		resp.Header.SetCanonical(k, v)
+	})
+
+	resp.SetStatusCode(responseStatusCode(r))
+	resp.SetBody(r.Body)
+}
@@ -0,0 +1,535 @@
+package proxy
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/savsgio/kratgo/modules/cache"
+	"github.com/valyala/fasthttp"
+)
+
+func Test_appendWeakETag(t *testing.T) {
+	etag := appendWeakETag(nil, []byte("Kratgo body"))
+
+	if !isWeakETag(etag) || !bytes.HasSuffix(etag, []byte("\"")) {
+		t.Errorf("appendWeakETag() == '%s', want a weak entity tag", etag)
+	}
+
+	if other := appendWeakETag(nil, []byte("Kratgo body")); !bytes.Equal(other, etag) {
+		t.Errorf("appendWeakETag() == '%s', want '%s'", other, etag)
+	}
+
+	if other := appendWeakETag(nil, []byte("Other body")); bytes.Equal(other, etag) {
+		t.Errorf("appendWeakETag() generates the same entity tag '%s' for different bodies", etag)
+	}
+
+	if weak := appendWeakenedETag(nil, []byte("\"v1\"")); string(weak) != "W/\"v1\"" {
+		t.Errorf("appendWeakenedETag() == '%s', want '%s'", weak, "W/\"v1\"")
+	}
+
+	if weak := appendWeakenedETag(nil, []byte("W/\"v1\"")); string(weak) != "W/\"v1\"" {
+		t.Errorf("appendWeakenedETag() == '%s', want '%s'", weak, "W/\"v1\"")
+	}
+}
+
+func Test_isNotModified(t *testing.T) {
+	type args struct {
+		method       string
+		reqHeaders   map[string]string
+		cacheHeaders map[string]string
+	}
+
+	type want struct {
+		notModified bool
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "NoConditional",
+			args: args{
+				cacheHeaders: map[string]string{"ETag": "\"v1\""},
+			},
+			want: want{notModified: false},
+		},
+		{
+			name: "IfNoneMatch",
+			args: args{
+				reqHeaders:   map[string]string{"If-None-Match": "\"v0\", \"v1\""},
+				cacheHeaders: map[string]string{"ETag": "\"v1\""},
+			},
+			want: want{notModified: true},
+		},
+		{
+			name: "IfNoneMatchWeak",
+			args: args{
+				reqHeaders:   map[string]string{"If-None-Match": "W/\"v1\""},
+				cacheHeaders: map[string]string{"ETag": "\"v1\""},
+			},
+			want: want{notModified: true},
+		},
+		{
+			name: "IfNoneMatchAny",
+			args: args{
+				reqHeaders:   map[string]string{"If-None-Match": "*"},
+				cacheHeaders: map[string]string{"Content-Type": "text/plain"},
+			},
+			want: want{notModified: true},
+		},
+		{
+			name: "IfNoneMatchChanged",
+			args: args{
+				reqHeaders:   map[string]string{"If-None-Match": "\"v0\""},
+				cacheHeaders: map[string]string{"ETag": "\"v1\""},
+			},
+			want: want{notModified: false},
+		},
+		{
+			name: "IfNoneMatchPrecedence",
+			args: args{
+				reqHeaders: map[string]string{
+					"If-None-Match":     "\"v0\"",
+					"If-Modified-Since": "Wed, 21 Oct 2015 07:28:00 GMT",
+				},
+				cacheHeaders: map[string]string{
+					"ETag":          "\"v1\"",
+					"Last-Modified": "Wed, 21 Oct 2015 07:28:00 GMT",
+				},
+			},
+			want: want{notModified: false},
+		},
+		{
+			name: "IfModifiedSince",
+			args: args{
+				reqHeaders:   map[string]string{"If-Modified-Since": "Wed, 21 Oct 2015 07:28:00 GMT"},
+				cacheHeaders: map[string]string{"Last-Modified": "Tue, 20 Oct 2015 07:28:00 GMT"},
+			},
+			want: want{notModified: true},
+		},
+		{
+			name: "IfModifiedSinceChanged",
+			args: args{
+				reqHeaders:   map[string]string{"If-Modified-Since": "Wed, 21 Oct 2015 07:28:00 GMT"},
+				cacheHeaders: map[string]string{"Last-Modified": "Thu, 22 Oct 2015 07:28:00 GMT"},
+			},
+			want: want{notModified: false},
+		},
+		{
+			name: "IfModifiedSinceInvalid",
+			args: args{
+				reqHeaders:   map[string]string{"If-Modified-Since": "yesterday"},
+				cacheHeaders: map[string]string{"Last-Modified": "Tue, 20 Oct 2015 07:28:00 GMT"},
+			},
+			want: want{notModified: false},
+		},
+		{
+			name: "UnsafeMethod",
+			args: args{
+				method:       "POST",
+				reqHeaders:   map[string]string{"If-None-Match": "\"v1\""},
+				cacheHeaders: map[string]string{"ETag": "\"v1\""},
+			},
+			want: want{notModified: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := new(fasthttp.RequestHeader)
+			if tt.args.method != "" {
+				req.SetMethod(tt.args.method)
+			}
+			for k, v := range tt.args.reqHeaders {
+				req.Set(k, v)
+			}
+
+			r := cache.AcquireResponse()
+			for k, v := range tt.args.cacheHeaders {
+				r.SetHeader([]byte(k), []byte(v))
+			}
+
+			if notModified := isNotModified(req, r); notModified != tt.want.notModified {
+				t.Errorf("isNotModified() == '%v', want '%v'", notModified, tt.want.notModified)
+			}
+
+			cache.ReleaseResponse(r)
+		})
+	}
+}
+
+func TestProxy_handlerConditional(t *testing.T) {
+	p, err := New(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := &slowBackend{
+		body: []byte("Kratgo body"),
+		headers: map[string]string{
+			fasthttp.HeaderCacheControl: "max-age=60",
+		},
+	}
+	p.backends = newTestBackends(backend)
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.SetRequestURI("/conditional/")
+	ctx.Request.Header.SetHost("www.kratgo.com")
+
+	p.handler(ctx)
+
+	etag := string(ctx.Response.Header.Peek(fasthttp.HeaderETag))
+	if etag == "" {
+		t.Fatal("Proxy.handler() response without validators has not a generated ETag")
+	}
+
+	ctx.Request.Header.Set(fasthttp.HeaderIfNoneMatch, etag)
+	ctx.Response.Reset()
+
+	p.handler(ctx)
+
+	if statusCode := ctx.Response.StatusCode(); statusCode != fasthttp.StatusNotModified {
+		t.Fatalf("Proxy.handler() status code == '%d', want '%d'", statusCode, fasthttp.StatusNotModified)
+	}
+
+	if body := ctx.Response.Body(); len(body) > 0 {
+		t.Errorf("Proxy.handler() 304 body == '%s', want empty", body)
+	}
+
+	if v := string(ctx.Response.Header.Peek(fasthttp.HeaderETag)); v != etag {
+		t.Errorf("Proxy.handler() 304 ETag == '%s', want '%s'", v, etag)
+	}
+
+	if v := string(ctx.Response.Header.Peek(fasthttp.HeaderCacheControl)); v != "max-age=60" {
+		t.Errorf("Proxy.handler() 304 Cache-Control == '%s', want '%s'", v, "max-age=60")
+	}
+
+	ctx.Request.Header.Set(fasthttp.HeaderIfNoneMatch, "\"other\"")
+	ctx.Response.Reset()
+
+	p.handler(ctx)
+
+	if statusCode := ctx.Response.StatusCode(); statusCode != fasthttp.StatusOK {
+		t.Fatalf("Proxy.handler() status code == '%d', want '%d'", statusCode, fasthttp.StatusOK)
+	}
+
+	if body := string(ctx.Response.Body()); body != "Kratgo body" {
+		t.Errorf("Proxy.handler() body == '%s', want '%s'", body, "Kratgo body")
+	}
+
+	if calls := backend.calls; calls != 1 {
+		t.Errorf("Proxy.handler() backend calls == '%d', want '%d'", calls, 1)
+	}
+}
+
+func Test_setConditionalHeaders(t *testing.T) {
+	body := []byte("Kratgo body")
+
+	type args struct {
+		cacheHeaders map[string]string
+	}
+
+	type want struct {
+		ifNoneMatch     string
+		ifModifiedSince string
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "ETag",
+			args: args{cacheHeaders: map[string]string{"ETag": "\"v1\""}},
+			want: want{ifNoneMatch: "\"v1\""},
+		},
+		{
+			name: "LastModified",
+			args: args{cacheHeaders: map[string]string{"Last-Modified": "Wed, 21 Oct 2015 07:28:00 GMT"}},
+			want: want{ifModifiedSince: "Wed, 21 Oct 2015 07:28:00 GMT"},
+		},
+		{
+			name: "GeneratedETag",
+			args: args{cacheHeaders: map[string]string{"ETag": string(appendWeakETag(nil, body))}},
+			want: want{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := new(fasthttp.RequestHeader)
+			req.Set(fasthttp.HeaderIfNoneMatch, "\"client\"")
+			req.Set(fasthttp.HeaderIfModifiedSince, "Thu, 01 Jan 2015 00:00:00 GMT")
+			req.Set(fasthttp.HeaderIfRange, "\"client\"")
+
+			r := cache.AcquireResponse()
+			r.Body = append(r.Body, body...)
+			for k, v := range tt.args.cacheHeaders {
+				r.SetHeader([]byte(k), []byte(v))
+			}
+
+			setConditionalHeaders(req, r)
+
+			if v := string(req.Peek(fasthttp.HeaderIfNoneMatch)); v != tt.want.ifNoneMatch {
+				t.Errorf("setConditionalHeaders() If-None-Match == '%s', want '%s'", v, tt.want.ifNoneMatch)
+			}
+
+			if v := string(req.Peek(fasthttp.HeaderIfModifiedSince)); v != tt.want.ifModifiedSince {
+				t.Errorf("setConditionalHeaders() If-Modified-Since == '%s', want '%s'", v, tt.want.ifModifiedSince)
+			}
+
+			if v := req.Peek(fasthttp.HeaderIfRange); len(v) > 0 {
+				t.Errorf("setConditionalHeaders() If-Range == '%s', want empty", v)
+			}
+
+			cache.ReleaseResponse(r)
+		})
+	}
+}
+
+func Test_mergeNotModified(t *testing.T) {
+	r := cache.AcquireResponse()
+	r.Body = append(r.Body, "Kratgo body"...)
+	r.SetHeader([]byte("Content-Type"), []byte("text/html"))
+	r.SetHeader([]byte("Etag"), []byte("\"v1\""))
+	r.SetHeader([]byte("Cache-Control"), []byte("max-age=60"))
+
+	resp := new(fasthttp.Response)
+	resp.SetStatusCode(fasthttp.StatusNotModified)
+	resp.Header.Set("Cache-Control", "max-age=120")
+	resp.Header.Set("X-Data", "1")
+
+	mergeNotModified(resp, r)
+
+	if statusCode := resp.StatusCode(); statusCode != fasthttp.StatusOK {
+		t.Errorf("mergeNotModified() status code == '%d', want '%d'", statusCode, fasthttp.StatusOK)
+	}
+
+	if body := string(resp.Body()); body != "Kratgo body" {
+		t.Errorf("mergeNotModified() body == '%s', want '%s'", body, "Kratgo body")
+	}
+
+	wantHeaders := map[string]string{
+		"Content-Type":  "text/html",
+		"Etag":          "\"v1\"",
+		"Cache-Control": "max-age=120",
+		"X-Data":        "1",
+	}
+	for k, v := range wantHeaders {
+		if value := string(resp.Header.Peek(k)); value != v {
+			t.Errorf("mergeNotModified() header '%s' == '%s', want '%s'", k, value, v)
+		}
+	}
+
+	cache.ReleaseResponse(r)
+}
+
+type revalidationBackend struct {
+	calls        int32
+	transfers    int32
+	etag         string
+	body         []byte
+	cacheControl string // Cache-Control of the responses, "max-age=1" if empty
+}
+
+func (mock *revalidationBackend) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	mock.calls++
+
+	cacheControl := mock.cacheControl
+	if cacheControl == "" {
+		cacheControl = "max-age=1"
+	}
+
+	resp.Header.Set(fasthttp.HeaderCacheControl, cacheControl)
+	resp.Header.Set(fasthttp.HeaderETag, mock.etag)
+
+	if string(req.Header.Peek(fasthttp.HeaderIfNoneMatch)) == mock.etag {
+		resp.SetStatusCode(fasthttp.StatusNotModified)
+		return nil
+	}
+
+	mock.transfers++
+
+	resp.SetStatusCode(fasthttp.StatusOK)
+	resp.SetBody(mock.body)
+
+	return nil
+}
+
+func TestProxy_handlerRevalidation(t *testing.T) {
+	cfg := testConfig()
+	cfg.FileConfig.Revalidation.Retain = time.Hour
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := &revalidationBackend{etag: "\"v1\"", body: []byte("Kratgo body")}
+	p.backends = newTestBackends(backend)
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.SetRequestURI("/revalidation/")
+	ctx.Request.Header.SetHost("www.kratgo.com")
+
+	p.handler(ctx)
+
+	entry := cache.AcquireEntry()
+	if err := p.cache.Get("www.kratgo.com/revalidation/?", entry); err != nil {
+		t.Fatal(err)
+	}
+
+	r := entry.GetResponse([]byte("/revalidation/"))
+	if r == nil {
+		t.Fatal("Proxy.handler() response not found in cache")
+	}
+
+	if r.Retain != 3600 {
+		t.Errorf("Proxy.handler() cache retain == '%d', want '%d'", r.Retain, 3600)
+	}
+
+	// Expire the cached response
+	r.StoredAt -= 10
+	storedAt := r.StoredAt
+	p.cache.Set("www.kratgo.com/revalidation/?", *entry)
+
+	ctx.Request.Header.Set(fasthttp.HeaderIfNoneMatch, "\"client\"")
+	ctx.Response.Reset()
+
+	p.handler(ctx)
+
+	if statusCode := ctx.Response.StatusCode(); statusCode != fasthttp.StatusOK {
+		t.Errorf("Proxy.handler() status code == '%d', want '%d'", statusCode, fasthttp.StatusOK)
+	}
+
+	if body := string(ctx.Response.Body()); body != "Kratgo body" {
+		t.Errorf("Proxy.handler() body == '%s', want '%s'", body, "Kratgo body")
+	}
+
+	if backend.calls != 2 || backend.transfers != 1 {
+		t.Errorf("Proxy.handler() backend calls and transfers == '%d %d', want '%d %d'",
+			backend.calls, backend.transfers, 2, 1)
+	}
+
+	entry.Reset()
+	if err := p.cache.Get("www.kratgo.com/revalidation/?", entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if r := entry.GetResponse([]byte("/revalidation/")); r == nil || r.StoredAt <= storedAt || string(r.Body) != "Kratgo body" {
+		t.Errorf("Proxy.handler() the cached response has not been refreshed")
+	}
+
+	ctx.Response.Reset()
+	p.handler(ctx)
+
+	stats := p.Stats()
+	wantStats := Stats{
+		Hits:           1,
+		Revalidated:    1,
+		Misses:         1,
+		BackendFetches: 2,
+		Hosts:          map[string]HostStats{"www.kratgo.com": {Hits: 2, Misses: 1, HitRatio: 2.0 / 3}},
+		Admission:      []AdmissionStats{},
+	}
+	if !reflect.DeepEqual(stats, wantStats) {
+		t.Errorf("Proxy.Stats() == '%+v', want '%+v'", stats, wantStats)
+	}
+
+	// The client which already has the refreshed response gets a 304 Not Modified
+	entry.Reset()
+	if err := p.cache.Get("www.kratgo.com/revalidation/?", entry); err != nil {
+		t.Fatal(err)
+	}
+
+	entry.GetResponse([]byte("/revalidation/")).StoredAt -= 10
+	p.cache.Set("www.kratgo.com/revalidation/?", *entry)
+
+	ctx.Request.Header.Set(fasthttp.HeaderIfNoneMatch, backend.etag)
+	ctx.Response.Reset()
+
+	p.handler(ctx)
+
+	if statusCode := ctx.Response.StatusCode(); statusCode != fasthttp.StatusNotModified {
+		t.Errorf("Proxy.handler() status code == '%d', want '%d'", statusCode, fasthttp.StatusNotModified)
+	}
+
+	if body := ctx.Response.Body(); len(body) > 0 {
+		t.Errorf("Proxy.handler() body == '%s', want ''", body)
+	}
+
+	if etag := string(ctx.Response.Header.Peek(fasthttp.HeaderETag)); etag != backend.etag {
+		t.Errorf("Proxy.handler() ETag == '%s', want '%s'", etag, backend.etag)
+	}
+
+	if backend.calls != 3 || backend.transfers != 1 {
+		t.Errorf("Proxy.handler() backend calls and transfers == '%d %d', want '%d %d'",
+			backend.calls, backend.transfers, 3, 1)
+	}
+}
+
+func TestProxy_handlerNoCache(t *testing.T) {
+	p, err := New(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := &revalidationBackend{etag: "\"v1\"", body: []byte("Kratgo body"), cacheControl: "no-cache"}
+	p.backends = newTestBackends(backend)
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.SetRequestURI("/nocache/")
+	ctx.Request.Header.SetHost("www.kratgo.com")
+
+	p.handler(ctx)
+
+	entry := cache.AcquireEntry()
+	if err := p.cache.Get("www.kratgo.com/nocache/?", entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if r := entry.GetResponse([]byte("/nocache/")); r == nil || !r.Revalidate || r.TTL != 0 {
+		t.Fatalf("Proxy.handler() the no-cache response has not been saved to be revalidated")
+	}
+
+	// Every request revalidates the cached response, without transferring the body again
+	for i := 0; i < 2; i++ {
+		ctx.Request.Header.Del(fasthttp.HeaderIfNoneMatch)
+		ctx.Response.Reset()
+		p.handler(ctx)
+
+		if statusCode := ctx.Response.StatusCode(); statusCode != fasthttp.StatusOK {
+			t.Errorf("Proxy.handler() status code == '%d', want '%d'", statusCode, fasthttp.StatusOK)
+		}
+
+		if body := string(ctx.Response.Body()); body != "Kratgo body" {
+			t.Errorf("Proxy.handler() body == '%s', want '%s'", body, "Kratgo body")
+		}
+	}
+
+	if backend.calls != 3 || backend.transfers != 1 {
+		t.Errorf("Proxy.handler() backend calls and transfers == '%d %d', want '%d %d'",
+			backend.calls, backend.transfers, 3, 1)
+	}
+
+	// The no-cache responses without validators could not be revalidated, so they are not saved
+	backend.etag = ""
+	ctx.Request.SetRequestURI("/nocache/novalidators/")
+	ctx.Response.Reset()
+
+	p.handler(ctx)
+
+	entry.Reset()
+	if err := p.cache.Get("www.kratgo.com/nocache/novalidators/?", entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.HasResponse([]byte("/nocache/novalidators/")) {
+		t.Errorf("Proxy.handler() the no-cache response without validators has been saved")
+	}
+}
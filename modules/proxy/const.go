@@ -1,12 +1,210 @@
 package proxy
 
+import (
+	"time"
+
+	"github.com/savsgio/kratgo/modules/config"
+	"github.com/valyala/fasthttp"
+)
+
 const proxyReqHeaderKey = "X-Kratgo-Cache"
 const proxyReqHeaderValue = "true"
 
+const defaultCacheKey = "$(host)$(path)?$(query)"
+
+const defaultCoalescingTimeout = 5 * time.Second
+
+const (
+	streamingBufferSize          = 4096
+	streamingMaxConns            = fasthttp.DefaultMaxConnsPerHost
+	streamingMaxIdleConnDuration = fasthttp.DefaultMaxIdleConnDuration
+	streamingReadTimeout         = 60 * time.Second
+	streamingWriteTimeout        = 60 * time.Second
+)
+
+// Max number of ranges of a Range request header, the requests with more ranges get the full response
+const maxByteRanges = 16
+
+// Max number of encoded bodies remembered as not saved in cache, the oldest ones are forgotten when it's exceeded
+const maxUnsavedEncodings = 4096
+
+const multipartByteRangesContentType = "multipart/byteranges; boundary="
+
 const headerLocation = "Location"
 const headerContentEncoding = "Content-Encoding"
+const headerSurrogateControl = "Surrogate-Control"
+const headerXCache = "X-Cache"
+const headerCacheStatus = "Cache-Status"
+
+// Values of the X-Cache header
+const (
+	xCacheHit    = "HIT"
+	xCacheMiss   = "MISS"
+	xCacheBypass = "BYPASS"
+	xCacheStale  = "STALE"
+)
+
+// Reasons to forward a request to the backend, in the Cache-Status header (RFC 9211, section 2.2)
+const (
+	fwdBypass   = "bypass"
+	fwdURIMiss  = "uri-miss"
+	fwdVaryMiss = "vary-miss"
+	fwdStale    = "stale"
+)
+
+const defaultCacheStatusNode = "kratgo"
+
+const (
+	defaultHealthCheckInterval = 5 * time.Second
+	defaultHealthCheckTimeout  = 2 * time.Second
+	defaultHealthCheckRise     = 2
+	defaultHealthCheckFall     = 3
+)
+
+const healthCheckUserAgent = "Kratgo health check"
+
+const (
+	defaultCircuitFailureRate      = 0.5
+	defaultCircuitMinRequests      = 10
+	defaultCircuitWindow           = 10 * time.Second
+	defaultCircuitCoolDown         = 30 * time.Second
+	defaultCircuitHalfOpenRequests = 1
+)
+
+// Balancing strategies
+const (
+	roundRobinBalancing = "roundrobin"
+	leastConnBalancing  = "leastconn"
+	twoChoicesBalancing = "random2"
+	hashBalancing       = "hash"
+)
+
+// Points of every backend in the consistent hashing ring, multiplied by its weight
+const hashRingReplicas = 160
+
+const (
+	defaultRetryBudget     = 0.2
+	defaultRetryMinRetries = 10
+	retryBudgetWindow      = 10 * time.Second
+)
+
+const errorPageContentType = "text/html; charset=utf-8"
+
+// Max number of hosts with their own stats, since the Host header is controlled by the clients
+const maxStatsHosts = 1024
+
+// Admission policies
+const (
+	frequencyAdmissionPolicy  = "frequency"
+	nthRequestAdmissionPolicy = "nth"
+)
+
+const (
+	defaultAdmissionRequests = 2
+	defaultAdmissionWindow   = time.Minute
+)
+
+// Size of the frequency sketches, and additions before aging the sketch of a "frequency" policy
+const (
+	sketchDepth      = 4
+	sketchWidth      = 1 << 16
+	sketchSampleSize = 10 * sketchWidth
+)
+
+var (
+	directiveNoStore              = []byte("no-store")
+	directiveNoCache              = []byte("no-cache")
+	directivePrivate              = []byte("private")
+	directiveMaxAge               = []byte("max-age")
+	directiveSMaxAge              = []byte("s-maxage")
+	directiveStaleWhileRevalidate = []byte("stale-while-revalidate")
+	directiveStaleIfError         = []byte("stale-if-error")
+
+	varyAll = []byte("*")
+
+	headerAcceptEncoding = []byte(fasthttp.HeaderAcceptEncoding)
+	strContentEncoding   = []byte(headerContentEncoding)
+
+	headerETag         = []byte(fasthttp.HeaderETag)
+	headerLastModified = []byte(fasthttp.HeaderLastModified)
+
+	weakETagPrefix = []byte("W/")
+	etagAny        = []byte("*")
+
+	// Headers of the cached response sent in a 304 Not Modified (RFC 9110, section 15.4.5)
+	notModifiedHeaders = [][]byte{
+		[]byte(fasthttp.HeaderCacheControl),
+		[]byte(fasthttp.HeaderContentLocation),
+		[]byte(fasthttp.HeaderETag),
+		[]byte(fasthttp.HeaderExpires),
+		[]byte(fasthttp.HeaderLastModified),
+		[]byte(fasthttp.HeaderVary),
+	}
+
+	// Conditional headers of the client removed from the requests which revalidate a cached response
+	conditionalHeaders = []string{
+		fasthttp.HeaderIfMatch,
+		fasthttp.HeaderIfNoneMatch,
+		fasthttp.HeaderIfModifiedSince,
+		fasthttp.HeaderIfUnmodifiedSince,
+		fasthttp.HeaderIfRange,
+	}
+
+	// Headers of a 304 Not Modified backend response which do not update the cached response
+	notModifiedIgnoredHeaders = [][]byte{
+		[]byte(fasthttp.HeaderContentLength),
+		[]byte(fasthttp.HeaderContentType),
+		[]byte(fasthttp.HeaderContentEncoding),
+		[]byte(fasthttp.HeaderContentRange),
+		[]byte(fasthttp.HeaderTransferEncoding),
+	}
+
+	byteRangesUnit   = []byte("bytes")
+	byteRangesPrefix = []byte("bytes=")
+
+	encodingBrotli = []byte("br")
+	encodingGzip   = []byte("gzip")
+	encodingXGzip  = []byte("x-gzip")
+	encodingAny    = []byte("*")
+
+	contentTypeTextPrefix = []byte("text/")
+	contentTypeJSONSuffix = []byte("+json")
+	contentTypeXMLSuffix  = []byte("+xml")
+
+	compressibleContentTypes = [][]byte{
+		[]byte("application/javascript"),
+		[]byte("application/x-javascript"),
+		[]byte("application/json"),
+		[]byte("application/xml"),
+		[]byte("application/wasm"),
+		[]byte("image/x-icon"),
+		[]byte("font/ttf"),
+		[]byte("font/otf"),
+	}
+)
 
 const (
 	setHeaderAction typeHeaderAction = iota
 	unsetHeaderAction
 )
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+const (
+	cacheMiss cacheStatus = iota
+	cacheHit
+	cacheRevalidated
+	cacheStale
+	cacheBypass
+)
+
+// Variables which depend on the backend response, so they can not be used in the cache key
+var cacheKeyForbiddenVars = []string{
+	config.EvalContentTypeVar,
+	config.EvalStatusCodeVar,
+	config.EvalRespHeaderVar,
+}
@@ -0,0 +1,219 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	gstrconv "github.com/savsgio/gotils/strconv"
+	"github.com/valyala/fasthttp"
+)
+
+// parseQuality returns the quality value of an Accept-Encoding item parameters (1 if not present).
+func parseQuality(params []byte) float64 {
+	for len(params) > 0 {
+		var param []byte
+
+		if i := bytes.IndexByte(params, ';'); i >= 0 {
+			param, params = params[:i], params[i+1:]
+		} else {
+			param, params = params, nil
+		}
+
+		param = bytes.TrimSpace(param)
+		if len(param) < 2 || toLower(param[0]) != 'q' || param[1] != '=' {
+			continue
+		}
+
+		q, err := strconv.ParseFloat(gstrconv.B2S(param[2:]), 64)
+		if err != nil {
+			return 0
+		}
+
+		return q
+	}
+
+	return 1
+}
+
+// preferredEncoding returns the supported encoding with the highest quality of the Accept-Encoding
+// request header, preferring brotli over gzip. Returns nil if the client only accepts identity.
+func preferredEncoding(acceptEncoding []byte) []byte {
+	qBrotli, qGzip, qAny := -1.0, -1.0, -1.0
+
+	for len(acceptEncoding) > 0 {
+		var item []byte
+
+		if i := bytes.IndexByte(acceptEncoding, ','); i >= 0 {
+			item, acceptEncoding = acceptEncoding[:i], acceptEncoding[i+1:]
+		} else {
+			item, acceptEncoding = acceptEncoding, nil
+		}
+
+		coding, params := item, []byte(nil)
+		if i := bytes.IndexByte(item, ';'); i >= 0 {
+			coding, params = item[:i], item[i+1:]
+		}
+		coding = bytes.TrimSpace(coding)
+
+		switch {
+		case bytes.EqualFold(coding, encodingBrotli):
+			qBrotli = parseQuality(params)
+		case bytes.EqualFold(coding, encodingGzip), bytes.EqualFold(coding, encodingXGzip):
+			qGzip = parseQuality(params)
+		case bytes.Equal(coding, encodingAny):
+			qAny = parseQuality(params)
+		}
+	}
+
+	// The encodings not listed are accepted with the quality of "*"
+	if qBrotli < 0 {
+		qBrotli = qAny
+	}
+
+	if qGzip < 0 {
+		qGzip = qAny
+	}
+
+	switch {
+	case qBrotli > 0 && qBrotli >= qGzip:
+		return encodingBrotli
+	case qGzip > 0:
+		return encodingGzip
+	}
+
+	return nil
+}
+
+// normalizeAcceptEncoding replaces the Accept-Encoding request header with the preferred supported encoding,
+// so the backend only responds with an encoding which could be stored and served by the proxy.
+func normalizeAcceptEncoding(req *fasthttp.RequestHeader) {
+	encoding := preferredEncoding(req.Peek(fasthttp.HeaderAcceptEncoding))
+	if encoding == nil {
+		req.Del(fasthttp.HeaderAcceptEncoding)
+		return
+	}
+
+	req.SetBytesV(fasthttp.HeaderAcceptEncoding, encoding)
+}
+
+// isCompressible returns true if the content type is worth to compress.
+func isCompressible(contentType []byte) bool {
+	if i := bytes.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	contentType = bytes.TrimSpace(contentType)
+
+	if bytes.HasPrefix(contentType, contentTypeTextPrefix) ||
+		bytes.HasSuffix(contentType, contentTypeJSONSuffix) ||
+		bytes.HasSuffix(contentType, contentTypeXMLSuffix) {
+		return true
+	}
+
+	for _, ct := range compressibleContentTypes {
+		if bytes.EqualFold(contentType, ct) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isSupportedEncoding returns true if the proxy could decode and encode bodies with the encoding.
+func isSupportedEncoding(encoding []byte) bool {
+	return bytes.Equal(encoding, encodingGzip) || bytes.Equal(encoding, encodingBrotli)
+}
+
+// appendEncodedBody appends to dst the body compressed with the encoding.
+func appendEncodedBody(dst, encoding, body []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+
+	var w io.WriteCloser
+
+	switch {
+	case bytes.Equal(encoding, encodingGzip):
+		w, _ = gzip.NewWriterLevel(buf, gzip.DefaultCompression)
+	case bytes.Equal(encoding, encodingBrotli):
+		w = brotli.NewWriterLevel(buf, brotli.DefaultCompression)
+	default:
+		return dst, fmt.Errorf("Unsupported encoding '%s'", encoding)
+	}
+
+	if _, err := w.Write(body); err != nil {
+		return dst, err
+	}
+
+	if err := w.Close(); err != nil {
+		return dst, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// appendDecodedBody appends to dst the body decompressed with the encoding.
+func appendDecodedBody(dst, encoding, body []byte) ([]byte, error) {
+	var r io.Reader
+
+	switch {
+	case bytes.Equal(encoding, encodingGzip):
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return dst, err
+		}
+		defer zr.Close()
+
+		r = zr
+	case bytes.Equal(encoding, encodingBrotli):
+		r = brotli.NewReader(bytes.NewReader(body))
+	default:
+		return dst, fmt.Errorf("Unsupported encoding '%s'", encoding)
+	}
+
+	buf := bytes.NewBuffer(dst)
+	if _, err := buf.ReadFrom(r); err != nil {
+		return dst, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// hasVaryAcceptEncoding returns true if the Vary response header lists Accept-Encoding.
+func hasVaryAcceptEncoding(h *fasthttp.ResponseHeader) bool {
+	for _, value := range h.PeekAll(fasthttp.HeaderVary) {
+		for _, name := range bytes.Split(value, []byte(",")) {
+			if bytes.EqualFold(bytes.TrimSpace(name), headerAcceptEncoding) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func unsavedEncodingKey(cacheKey, encoding []byte) string {
+	return string(cacheKey) + "\x00" + string(encoding)
+}
+
+// has returns true if the encoded body of the response stored at storedAt could not be saved in cache.
+func (u *unsavedEncodings) has(cacheKey, encoding []byte, storedAt int64) bool {
+	u.mu.Lock()
+	unsavedAt, ok := u.items[unsavedEncodingKey(cacheKey, encoding)]
+	u.mu.Unlock()
+
+	return ok && unsavedAt == storedAt
+}
+
+func (u *unsavedEncodings) add(cacheKey, encoding []byte, storedAt int64) {
+	u.mu.Lock()
+
+	if u.items == nil || len(u.items) >= maxUnsavedEncodings {
+		u.items = make(map[string]int64)
+	}
+
+	u.items[unsavedEncodingKey(cacheKey, encoding)] = storedAt
+
+	u.mu.Unlock()
+}
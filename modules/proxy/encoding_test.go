@@ -0,0 +1,276 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/savsgio/kratgo/modules/cache"
+	"github.com/valyala/fasthttp"
+)
+
+func Test_preferredEncoding(t *testing.T) {
+	type args struct {
+		acceptEncoding string
+	}
+
+	type want struct {
+		encoding string
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Empty",
+			args: args{acceptEncoding: ""},
+			want: want{encoding: ""},
+		},
+		{
+			name: "Identity",
+			args: args{acceptEncoding: "identity"},
+			want: want{encoding: ""},
+		},
+		{
+			name: "Gzip",
+			args: args{acceptEncoding: "gzip, deflate"},
+			want: want{encoding: "gzip"},
+		},
+		{
+			name: "XGzip",
+			args: args{acceptEncoding: "x-gzip"},
+			want: want{encoding: "gzip"},
+		},
+		{
+			name: "BrotliPreferred",
+			args: args{acceptEncoding: "gzip, deflate, br"},
+			want: want{encoding: "br"},
+		},
+		{
+			name: "Quality",
+			args: args{acceptEncoding: "br;q=0.5, gzip;q=0.8"},
+			want: want{encoding: "gzip"},
+		},
+		{
+			name: "Rejected",
+			args: args{acceptEncoding: "br;q=0, gzip"},
+			want: want{encoding: "gzip"},
+		},
+		{
+			name: "Any",
+			args: args{acceptEncoding: "*"},
+			want: want{encoding: "br"},
+		},
+		{
+			name: "AnyRejected",
+			args: args{acceptEncoding: "gzip;q=0, *;q=0"},
+			want: want{encoding: ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoding := preferredEncoding([]byte(tt.args.acceptEncoding))
+
+			if string(encoding) != tt.want.encoding {
+				t.Errorf("preferredEncoding() == '%s', want '%s'", encoding, tt.want.encoding)
+			}
+		})
+	}
+}
+
+func Test_isCompressible(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{contentType: "text/html; charset=utf-8", want: true},
+		{contentType: "application/json", want: true},
+		{contentType: "application/ld+json", want: true},
+		{contentType: "image/svg+xml", want: true},
+		{contentType: "application/javascript", want: true},
+		{contentType: "image/png", want: false},
+		{contentType: "application/octet-stream", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			if got := isCompressible([]byte(tt.contentType)); got != tt.want {
+				t.Errorf("isCompressible() == '%v', want '%v'", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_appendEncodedBody(t *testing.T) {
+	body := bytes.Repeat([]byte("Kratgo compressed body. "), 100)
+
+	for _, encoding := range [][]byte{encodingGzip, encodingBrotli} {
+		t.Run(string(encoding), func(t *testing.T) {
+			encoded, err := appendEncodedBody(nil, encoding, body)
+			if err != nil {
+				t.Fatalf("appendEncodedBody() returns err: %v", err)
+			}
+
+			if len(encoded) >= len(body) {
+				t.Errorf("appendEncodedBody() encoded length == '%d', want less than '%d'", len(encoded), len(body))
+			}
+
+			decoded, err := appendDecodedBody(nil, encoding, encoded)
+			if err != nil {
+				t.Fatalf("appendDecodedBody() returns err: %v", err)
+			}
+
+			if !bytes.Equal(decoded, body) {
+				t.Errorf("appendDecodedBody() == '%s', want '%s'", decoded, body)
+			}
+		})
+	}
+
+	if _, err := appendEncodedBody(nil, []byte("deflate"), body); err == nil {
+		t.Errorf("appendEncodedBody() expected error with unsupported encoding")
+	}
+
+	if _, err := appendDecodedBody(nil, encodingGzip, body); err == nil {
+		t.Errorf("appendDecodedBody() expected error with invalid gzip body")
+	}
+}
+
+func Test_hasVaryAcceptEncoding(t *testing.T) {
+	h := new(fasthttp.ResponseHeader)
+
+	h.Add(fasthttp.HeaderVary, "Accept-Language")
+	if hasVaryAcceptEncoding(h) {
+		t.Errorf("hasVaryAcceptEncoding() == '%v', want '%v'", true, false)
+	}
+
+	h.Add(fasthttp.HeaderVary, "Cookie, accept-encoding")
+	if !hasVaryAcceptEncoding(h) {
+		t.Errorf("hasVaryAcceptEncoding() == '%v', want '%v'", false, true)
+	}
+}
+
+func TestProxy_handlerEncoding(t *testing.T) {
+	p, err := New(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := bytes.Repeat([]byte("Kratgo "), 100)
+	gzipBody, err := appendEncodedBody(nil, encodingGzip, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := &slowBackend{
+		body: gzipBody,
+		headers: map[string]string{
+			fasthttp.HeaderContentType:     "text/plain",
+			fasthttp.HeaderContentEncoding: "gzip",
+			fasthttp.HeaderCacheControl:    "max-age=60",
+		},
+	}
+	p.backends = newTestBackends(backend)
+
+	requests := []struct {
+		acceptEncoding string
+		encoding       string
+	}{
+		{acceptEncoding: "", encoding: ""},                  // Decompressed backend response
+		{acceptEncoding: "gzip, deflate", encoding: "gzip"}, // Stored backend variant
+		{acceptEncoding: "br, gzip", encoding: "br"},        // Encoded and saved variant
+		{acceptEncoding: "br", encoding: "br"},              // Saved variant
+		{acceptEncoding: "identity", encoding: ""},          // Stored identity body
+	}
+
+	for _, req := range requests {
+		ctx := new(fasthttp.RequestCtx)
+		ctx.Request.SetRequestURI("/encoding/")
+		ctx.Request.Header.SetHost("www.kratgo.com")
+		if req.acceptEncoding != "" {
+			ctx.Request.Header.Set(fasthttp.HeaderAcceptEncoding, req.acceptEncoding)
+		}
+
+		p.handler(ctx)
+
+		encoding := ctx.Response.Header.Peek(fasthttp.HeaderContentEncoding)
+		if string(encoding) != req.encoding {
+			t.Fatalf("Proxy.handler() Accept-Encoding '%s' content encoding == '%s', want '%s'",
+				req.acceptEncoding, encoding, req.encoding)
+		}
+
+		respBody := ctx.Response.Body()
+		if len(encoding) > 0 {
+			if respBody, err = appendDecodedBody(nil, encoding, respBody); err != nil {
+				t.Fatalf("Proxy.handler() Accept-Encoding '%s' could not decode body: %v", req.acceptEncoding, err)
+			}
+		}
+
+		if !bytes.Equal(respBody, body) {
+			t.Errorf("Proxy.handler() Accept-Encoding '%s' body == '%s', want '%s'", req.acceptEncoding, respBody, body)
+		}
+
+		if !hasVaryAcceptEncoding(&ctx.Response.Header) {
+			t.Errorf("Proxy.handler() Accept-Encoding '%s' response has not 'Vary: Accept-Encoding'", req.acceptEncoding)
+		}
+	}
+
+	if calls := backend.calls; calls != 1 {
+		t.Errorf("Proxy.handler() backend calls == '%d', want '%d'", calls, 1)
+	}
+
+	entry := cache.AcquireEntry()
+	if err := p.cache.Get("www.kratgo.com/encoding/?", entry); err != nil {
+		t.Fatal(err)
+	}
+
+	r := entry.GetResponse([]byte("/encoding/"))
+	if r == nil {
+		t.Fatal("Proxy.handler() response not found in cache")
+	}
+
+	if !bytes.Equal(r.Body, body) {
+		t.Errorf("Proxy.handler() cache body == '%s', want '%s'", r.Body, body)
+	}
+
+	for _, encoding := range []string{"gzip", "br"} {
+		if r.GetEncodedBody([]byte(encoding)) == nil {
+			t.Errorf("Proxy.handler() cache '%s' encoded body not found", encoding)
+		}
+	}
+}
+
+func Test_unsavedEncodings(t *testing.T) {
+	u := new(unsavedEncodings)
+	cacheKey := []byte("www.kratgo.com/encoding/")
+	encoding := []byte("gzip")
+
+	if u.has(cacheKey, encoding, 10) {
+		t.Error("unsavedEncodings.has() == 'true', want 'false'")
+	}
+
+	u.add(cacheKey, encoding, 10)
+
+	if !u.has(cacheKey, encoding, 10) {
+		t.Error("unsavedEncodings.has() == 'false', want 'true'")
+	}
+
+	// The encoded bodies of a replaced response are tried again
+	if u.has(cacheKey, encoding, 20) {
+		t.Error("unsavedEncodings.has() replaced response == 'true', want 'false'")
+	}
+
+	if u.has(cacheKey, []byte("br"), 10) {
+		t.Error("unsavedEncodings.has() other encoding == 'true', want 'false'")
+	}
+
+	for i := 0; i < maxUnsavedEncodings; i++ {
+		u.add([]byte(fmt.Sprintf("www.kratgo.com/%d/", i)), encoding, 10)
+	}
+
+	if n := len(u.items); n > maxUnsavedEncodings {
+		t.Errorf("unsavedEncodings items == '%d', want at most '%d'", n, maxUnsavedEncodings)
+	}
+}
@@ -0,0 +1,6 @@
+package proxy
+
+import "errors"
+
+// ErrNoHealthyBackend ...
+var ErrNoHealthyBackend = errors.New("No healthy backend available")
@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+
+	gstrconv "github.com/savsgio/gotils/strconv"
+	"github.com/valyala/fasthttp"
+)
+
+func (cc *cacheControl) reset() {
+	cc.noStore = false
+	cc.noCache = false
+	cc.private = false
+	cc.maxAge = -1
+	cc.sMaxAge = -1
+	cc.staleWhileRevalidate = -1
+	cc.staleIfError = -1
+}
+
+func parseDirectiveSeconds(value []byte) int64 {
+	value = bytes.Trim(value, "\" ")
+
+	n, err := strconv.ParseInt(gstrconv.B2S(value), 10, 64)
+	if err != nil || n < 0 {
+		// Invalid values must be treated as stale (RFC 9111, section 4.2.1)
+		return 0
+	}
+
+	return n
+}
+
+// parseCacheControl parses the directives of a Cache-Control or Surrogate-Control header value.
+func parseCacheControl(cc *cacheControl, value []byte) {
+	cc.reset()
+
+	for len(value) > 0 {
+		var directive []byte
+
+		if i := bytes.IndexByte(value, ','); i >= 0 {
+			directive, value = value[:i], value[i+1:]
+		} else {
+			directive, value = value, nil
+		}
+
+		name, arg := bytes.TrimSpace(directive), []byte(nil)
+		if i := bytes.IndexByte(name, '='); i >= 0 {
+			name, arg = bytes.TrimSpace(name[:i]), name[i+1:]
+		}
+
+		switch {
+		case bytes.EqualFold(name, directiveNoStore):
+			cc.noStore = true
+		case bytes.EqualFold(name, directiveNoCache):
+			cc.noCache = true
+		case bytes.EqualFold(name, directivePrivate):
+			cc.private = true
+		case bytes.EqualFold(name, directiveMaxAge):
+			cc.maxAge = parseDirectiveSeconds(arg)
+		case bytes.EqualFold(name, directiveSMaxAge):
+			cc.sMaxAge = parseDirectiveSeconds(arg)
+		case bytes.EqualFold(name, directiveStaleWhileRevalidate):
+			cc.staleWhileRevalidate = parseDirectiveSeconds(arg)
+		case bytes.EqualFold(name, directiveStaleIfError):
+			cc.staleIfError = parseDirectiveSeconds(arg)
+		}
+	}
+}
+
+// responseTTL returns the freshness lifetime in seconds of the backend response, if the response
+// could be saved in cache, and if it must be revalidated before each use.
+//
+// The lifetime is taken, by order of precedence, from Surrogate-Control max-age,
+// Cache-Control s-maxage and max-age, and Expires. A zero lifetime means that
+// the response has not an explicit one.
+//
+// The responses with Cache-Control no-cache are saved with a zero lifetime, and must be
+// revalidated before each use (RFC 9111, section 5.2.2.4).
+func responseTTL(resp *fasthttp.Response, now time.Time) (int64, bool, bool) {
+	cc := &cacheControl{}
+
+	if value := resp.Header.Peek(headerSurrogateControl); len(value) > 0 {
+		parseCacheControl(cc, value)
+
+		if cc.noStore {
+			return 0, false, false
+		} else if cc.maxAge >= 0 {
+			return cc.maxAge, cc.maxAge > 0, false
+		}
+	}
+
+	parseCacheControl(cc, resp.Header.Peek(fasthttp.HeaderCacheControl))
+
+	if cc.noStore || cc.private {
+		return 0, false, false
+	} else if cc.noCache {
+		return 0, true, true
+	} else if cc.sMaxAge >= 0 {
+		return cc.sMaxAge, cc.sMaxAge > 0, false
+	} else if cc.maxAge >= 0 {
+		return cc.maxAge, cc.maxAge > 0, false
+	}
+
+	if value := resp.Header.Peek(fasthttp.HeaderExpires); len(value) > 0 {
+		expires, err := fasthttp.ParseHTTPDate(value)
+		if err != nil {
+			// Invalid dates, like "0", represent a time in the past
+			return 0, false, false
+		}
+
+		ttl := int64(expires.Sub(now) / time.Second)
+
+		return ttl, ttl > 0, false
+	}
+
+	return 0, true, false
+}
+
+// responseStaleWindows returns the seconds after the expiration of the backend response
+// in which it could be served stale, while it's revalidated (grace) or if the backend fails (keep).
+//
+// The windows are taken from the Cache-Control extensions stale-while-revalidate and
+// stale-if-error, or from the given default values if not present.
+func responseStaleWindows(resp *fasthttp.Response, grace, keep int64) (int64, int64) {
+	cc := &cacheControl{}
+	parseCacheControl(cc, resp.Header.Peek(fasthttp.HeaderCacheControl))
+
+	if cc.staleWhileRevalidate >= 0 {
+		grace = cc.staleWhileRevalidate
+	}
+
+	if cc.staleIfError >= 0 {
+		keep = cc.staleIfError
+	}
+
+	return grace, keep
+}
@@ -0,0 +1,248 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func Test_parseCacheControl(t *testing.T) {
+	type args struct {
+		value string
+	}
+
+	type want struct {
+		cc cacheControl
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Empty",
+			args: args{value: ""},
+			want: want{cc: cacheControl{maxAge: -1, sMaxAge: -1, staleWhileRevalidate: -1, staleIfError: -1}},
+		},
+		{
+			name: "MaxAge",
+			args: args{value: "public, max-age=60"},
+			want: want{cc: cacheControl{maxAge: 60, sMaxAge: -1, staleWhileRevalidate: -1, staleIfError: -1}},
+		},
+		{
+			name: "SMaxAge",
+			args: args{value: "max-age=60,S-MAXAGE=\"120\""},
+			want: want{cc: cacheControl{maxAge: 60, sMaxAge: 120, staleWhileRevalidate: -1, staleIfError: -1}},
+		},
+		{
+			name: "InvalidMaxAge",
+			args: args{value: "max-age=abc"},
+			want: want{cc: cacheControl{maxAge: 0, sMaxAge: -1, staleWhileRevalidate: -1, staleIfError: -1}},
+		},
+		{
+			name: "StaleExtensions",
+			args: args{value: "max-age=60, stale-while-revalidate=30, stale-if-error=86400"},
+			want: want{cc: cacheControl{maxAge: 60, sMaxAge: -1, staleWhileRevalidate: 30, staleIfError: 86400}},
+		},
+		{
+			name: "NoStoreNoCachePrivate",
+			args: args{value: "no-store, no-cache, private"},
+			want: want{cc: cacheControl{noStore: true, noCache: true, private: true, maxAge: -1, sMaxAge: -1, staleWhileRevalidate: -1, staleIfError: -1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc := cacheControl{noStore: true, maxAge: 10}
+
+			parseCacheControl(&cc, []byte(tt.args.value))
+
+			if cc != tt.want.cc {
+				t.Errorf("parseCacheControl() == '%+v', want '%+v'", cc, tt.want.cc)
+			}
+		})
+	}
+}
+
+func Test_responseTTL(t *testing.T) {
+	type args struct {
+		headers map[string]string
+	}
+
+	type want struct {
+		ttl        int64
+		storable   bool
+		revalidate bool
+	}
+
+	now := time.Date(2023, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "WithoutHeaders",
+			args: args{headers: map[string]string{}},
+			want: want{ttl: 0, storable: true},
+		},
+		{
+			name: "MaxAge",
+			args: args{headers: map[string]string{"Cache-Control": "max-age=60"}},
+			want: want{ttl: 60, storable: true},
+		},
+		{
+			name: "SMaxAgeOverMaxAge",
+			args: args{headers: map[string]string{"Cache-Control": "max-age=60, s-maxage=300"}},
+			want: want{ttl: 300, storable: true},
+		},
+		{
+			name: "SurrogateControlOverCacheControl",
+			args: args{headers: map[string]string{
+				"Cache-Control":     "private",
+				"Surrogate-Control": "max-age=3600",
+			}},
+			want: want{ttl: 3600, storable: true},
+		},
+		{
+			name: "SurrogateControlNoStore",
+			args: args{headers: map[string]string{
+				"Cache-Control":     "max-age=60",
+				"Surrogate-Control": "no-store",
+			}},
+			want: want{ttl: 0, storable: false},
+		},
+		{
+			name: "MaxAgeZero",
+			args: args{headers: map[string]string{"Cache-Control": "max-age=0"}},
+			want: want{ttl: 0, storable: false},
+		},
+		{
+			name: "NoStore",
+			args: args{headers: map[string]string{"Cache-Control": "no-store"}},
+			want: want{ttl: 0, storable: false},
+		},
+		{
+			name: "NoCache",
+			args: args{headers: map[string]string{"Cache-Control": "no-cache, max-age=60"}},
+			want: want{ttl: 0, storable: true, revalidate: true},
+		},
+		{
+			name: "NoCacheNoStore",
+			args: args{headers: map[string]string{"Cache-Control": "no-cache, no-store"}},
+			want: want{ttl: 0, storable: false},
+		},
+		{
+			name: "Private",
+			args: args{headers: map[string]string{"Cache-Control": "private, max-age=60"}},
+			want: want{ttl: 0, storable: false},
+		},
+		{
+			name: "Expires",
+			args: args{headers: map[string]string{
+				"Expires": "Wed, 01 Mar 2023 10:10:00 GMT",
+			}},
+			want: want{ttl: 600, storable: true},
+		},
+		{
+			name: "ExpiresInThePast",
+			args: args{headers: map[string]string{
+				"Expires": "Wed, 01 Mar 2023 09:00:00 GMT",
+			}},
+			want: want{ttl: -3600, storable: false},
+		},
+		{
+			name: "ExpiresInvalid",
+			args: args{headers: map[string]string{"Expires": "0"}},
+			want: want{ttl: 0, storable: false},
+		},
+		{
+			name: "MaxAgeOverExpires",
+			args: args{headers: map[string]string{
+				"Cache-Control": "max-age=60",
+				"Expires":       "Wed, 01 Mar 2023 10:10:00 GMT",
+			}},
+			want: want{ttl: 60, storable: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := fasthttp.AcquireResponse()
+			defer fasthttp.ReleaseResponse(resp)
+
+			for k, v := range tt.args.headers {
+				resp.Header.Set(k, v)
+			}
+
+			ttl, storable, revalidate := responseTTL(resp, now)
+			if storable != tt.want.storable {
+				t.Errorf("responseTTL() storable == '%v', want '%v'", storable, tt.want.storable)
+			}
+
+			if revalidate != tt.want.revalidate {
+				t.Errorf("responseTTL() revalidate == '%v', want '%v'", revalidate, tt.want.revalidate)
+			}
+
+			if ttl != tt.want.ttl {
+				t.Errorf("responseTTL() ttl == '%d', want '%d'", ttl, tt.want.ttl)
+			}
+		})
+	}
+}
+
+func Test_responseStaleWindows(t *testing.T) {
+	type args struct {
+		cacheControl string
+		grace        int64
+		keep         int64
+	}
+
+	type want struct {
+		grace int64
+		keep  int64
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Defaults",
+			args: args{cacheControl: "max-age=60", grace: 10, keep: 3600},
+			want: want{grace: 10, keep: 3600},
+		},
+		{
+			name: "StaleWhileRevalidate",
+			args: args{cacheControl: "max-age=60, stale-while-revalidate=30", grace: 10, keep: 3600},
+			want: want{grace: 30, keep: 3600},
+		},
+		{
+			name: "StaleIfError",
+			args: args{cacheControl: "max-age=60, stale-if-error=0", grace: 10, keep: 3600},
+			want: want{grace: 10, keep: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := fasthttp.AcquireResponse()
+			defer fasthttp.ReleaseResponse(resp)
+
+			resp.Header.Set("Cache-Control", tt.args.cacheControl)
+
+			grace, keep := responseStaleWindows(resp, tt.args.grace, tt.args.keep)
+			if grace != tt.want.grace {
+				t.Errorf("responseStaleWindows() grace == '%d', want '%d'", grace, tt.want.grace)
+			}
+
+			if keep != tt.want.keep {
+				t.Errorf("responseStaleWindows() keep == '%d', want '%d'", keep, tt.want.keep)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package proxy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func newBackend(addr string, client fetcher) *backend {
+	b := &backend{addr: addr, client: client, weight: 1}
+	b.healthy.Store(true)
+
+	return b
+}
+
+// Do ...
+func (b *backend) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	return b.client.Do(req, resp)
+}
+
+func (b *backend) isHealthy() bool {
+	return b.healthy.Load()
+}
+
+// isAvailable returns true if the backend is healthy and its circuit lets pass the request.
+func (b *backend) isAvailable(now time.Time) bool {
+	return b.isHealthy() && (b.circuit == nil || b.circuit.allow(now))
+}
+
+// canServe returns true if the backend is healthy and its circuit would let pass the request,
+// without taking a trial request of the half-open circuit.
+func (b *backend) canServe(now time.Time) bool {
+	return b.isHealthy() && (b.circuit == nil || b.circuit.ready(now))
+}
+
+// setCheckResult updates the health state of the backend with the result of a health check.
+// It becomes unhealthy after fall consecutive failures, and healthy again after rise consecutive successes.
+// Returns true if the health state has changed.
+func (b *backend) setCheckResult(err error, rise, fall int, now int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastCheck = now
+	healthy := b.healthy.Load()
+
+	if err != nil {
+		b.successes = 0
+		b.failures++
+		b.lastError = err.Error()
+
+		if healthy && b.failures >= fall {
+			b.healthy.Store(false)
+			return true
+		}
+
+		return false
+	}
+
+	b.failures = 0
+	b.successes++
+	b.lastError = ""
+
+	if !healthy && b.successes >= rise {
+		b.healthy.Store(true)
+		return true
+	}
+
+	return false
+}
+
+func (b *backend) status() BackendStatus {
+	b.mu.Lock()
+
+	status := BackendStatus{
+		Addr:      b.addr,
+		Healthy:   b.healthy.Load(),
+		Successes: b.successes,
+		Failures:  b.failures,
+		LastCheck: b.lastCheck,
+		LastError: b.lastError,
+	}
+
+	b.mu.Unlock()
+
+	if b.circuit != nil {
+		status.Circuit = b.circuit.getState().String()
+	}
+
+	return status
+}
+
+func (p *Proxy) parseHealthCheck() error {
+	hc := p.fileConfig.HealthCheck
+	if hc.Path == "" {
+		return nil
+	}
+
+	switch {
+	case hc.Interval < 0:
+		return fmt.Errorf("The interval of the health checks must be greater than or equal to 0")
+	case hc.Timeout < 0:
+		return fmt.Errorf("The timeout of the health checks must be greater than or equal to 0")
+	case hc.Status < 0:
+		return fmt.Errorf("The expected status code of the health checks must be greater than or equal to 0")
+	case hc.Rise < 0 || hc.Fall < 0:
+		return fmt.Errorf("The rise and fall of the health checks must be greater than or equal to 0")
+	}
+
+	if hc.Interval == 0 {
+		hc.Interval = defaultHealthCheckInterval
+	}
+
+	if hc.Timeout == 0 {
+		hc.Timeout = defaultHealthCheckTimeout
+	}
+
+	if hc.Status == 0 {
+		hc.Status = fasthttp.StatusOK
+	}
+
+	if hc.Rise == 0 {
+		hc.Rise = defaultHealthCheckRise
+	}
+
+	if hc.Fall == 0 {
+		hc.Fall = defaultHealthCheckFall
+	}
+
+	p.healthCheck = hc
+
+	for _, b := range p.allBackends() {
+		b.checkClient = &fasthttp.HostClient{Addr: b.addr}
+	}
+
+	return nil
+}
+
+// checkBackend requests the health check path to the backend, and returns an error if it does not answer
+// in time or with the expected status code.
+func (p *Proxy) checkBackend(b *backend) error {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	host := p.healthCheck.Host
+	if host == "" {
+		host = b.addr
+	}
+
+	req.SetRequestURI(p.healthCheck.Path)
+	req.Header.SetHost(host)
+	req.Header.SetUserAgent(healthCheckUserAgent)
+	resp.SkipBody = true
+
+	if err := b.checkClient.DoTimeout(req, resp, p.healthCheck.Timeout); err != nil {
+		return err
+	}
+
+	if statusCode := resp.StatusCode(); statusCode != p.healthCheck.Status {
+		return fmt.Errorf("Unexpected status code '%d'", statusCode)
+	}
+
+	return nil
+}
+
+func (p *Proxy) runHealthCheck(b *backend) {
+	err := p.checkBackend(b)
+
+	if !b.setCheckResult(err, p.healthCheck.Rise, p.healthCheck.Fall, time.Now().Unix()) {
+		return
+	}
+
+	if err != nil {
+		p.log.Warningf("Backend '%s' is unhealthy, removed from the rotation: %v", b.addr, err)
+	} else {
+		p.log.Infof("Backend '%s' is healthy, back in the rotation", b.addr)
+	}
+}
+
+// startHealthChecks checks the health of every backend periodically, if the health checks are enabled.
+func (p *Proxy) startHealthChecks() {
+	if p.healthCheck.Path == "" {
+		return
+	}
+
+	for _, b := range p.allBackends() {
+		go func(b *backend) {
+			ticker := time.NewTicker(p.healthCheck.Interval)
+			defer ticker.Stop()
+
+			for {
+				p.runHealthCheck(b)
+				<-ticker.C
+			}
+		}(b)
+	}
+}
+
+// Backends returns the health state and the circuit state of the backends.
+func (p *Proxy) Backends() []BackendStatus {
+	backends := p.allBackends()
+	statuses := make([]BackendStatus, len(backends))
+
+	for i, b := range backends {
+		statuses[i] = b.status()
+	}
+
+	return statuses
+}
@@ -0,0 +1,312 @@
+package proxy
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/savsgio/kratgo/modules/config"
+	"github.com/valyala/fasthttp"
+)
+
+func testHealthBackend(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &fasthttp.Server{
+		Handler: func(ctx *fasthttp.RequestCtx) {
+			switch string(ctx.Path()) {
+			case "/health":
+				if string(ctx.Host()) != "www.kratgo.com" {
+					ctx.SetStatusCode(fasthttp.StatusBadRequest)
+				}
+			case "/slow":
+				time.Sleep(100 * time.Millisecond)
+			default:
+				ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+			}
+		},
+	}
+
+	go s.Serve(ln)
+
+	t.Cleanup(func() {
+		s.Shutdown()
+	})
+
+	return ln.Addr().String()
+}
+
+func TestBackend_setCheckResult(t *testing.T) {
+	b := newBackend("localhost:9990", nil)
+	checkErr := errors.New("Test error")
+
+	steps := []struct {
+		err     error
+		healthy bool
+		changed bool
+	}{
+		{err: checkErr, healthy: true},
+		{err: nil, healthy: true},
+		{err: checkErr, healthy: true},
+		{err: checkErr, healthy: true},
+		{err: checkErr, healthy: false, changed: true},
+		{err: checkErr, healthy: false},
+		{err: nil, healthy: false},
+		{err: nil, healthy: true, changed: true},
+		{err: nil, healthy: true},
+	}
+
+	for i, step := range steps {
+		changed := b.setCheckResult(step.err, 2, 3, int64(i))
+
+		if changed != step.changed {
+			t.Errorf("backend.setCheckResult() step %d changed == '%v', want '%v'", i, changed, step.changed)
+		}
+
+		if healthy := b.isHealthy(); healthy != step.healthy {
+			t.Errorf("backend.setCheckResult() step %d healthy == '%v', want '%v'", i, healthy, step.healthy)
+		}
+	}
+
+	status := b.status()
+	wantStatus := BackendStatus{Addr: "localhost:9990", Healthy: true, Successes: 3, LastCheck: int64(len(steps) - 1)}
+
+	if !reflect.DeepEqual(status, wantStatus) {
+		t.Errorf("backend.status() == '%+v', want '%+v'", status, wantStatus)
+	}
+}
+
+func TestProxy_parseHealthCheck(t *testing.T) {
+	type args struct {
+		healthCheck config.HealthCheck
+	}
+
+	type want struct {
+		healthCheck config.HealthCheck
+		err         bool
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Disabled",
+			args: args{
+				healthCheck: config.HealthCheck{Interval: time.Second},
+			},
+			want: want{
+				healthCheck: config.HealthCheck{},
+			},
+		},
+		{
+			name: "Defaults",
+			args: args{
+				healthCheck: config.HealthCheck{Path: "/health"},
+			},
+			want: want{
+				healthCheck: config.HealthCheck{
+					Path:     "/health",
+					Interval: defaultHealthCheckInterval,
+					Timeout:  defaultHealthCheckTimeout,
+					Status:   fasthttp.StatusOK,
+					Rise:     defaultHealthCheckRise,
+					Fall:     defaultHealthCheckFall,
+				},
+			},
+		},
+		{
+			name: "Custom",
+			args: args{
+				healthCheck: config.HealthCheck{
+					Path:     "/health",
+					Host:     "www.kratgo.com",
+					Interval: time.Second,
+					Timeout:  500 * time.Millisecond,
+					Status:   fasthttp.StatusNoContent,
+					Rise:     1,
+					Fall:     5,
+				},
+			},
+			want: want{
+				healthCheck: config.HealthCheck{
+					Path:     "/health",
+					Host:     "www.kratgo.com",
+					Interval: time.Second,
+					Timeout:  500 * time.Millisecond,
+					Status:   fasthttp.StatusNoContent,
+					Rise:     1,
+					Fall:     5,
+				},
+			},
+		},
+		{
+			name: "InvalidInterval",
+			args: args{
+				healthCheck: config.HealthCheck{Path: "/health", Interval: -time.Second},
+			},
+			want: want{
+				err: true,
+			},
+		},
+		{
+			name: "InvalidFall",
+			args: args{
+				healthCheck: config.HealthCheck{Path: "/health", Fall: -1},
+			},
+			want: want{
+				err: true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.FileConfig.HealthCheck = tt.args.healthCheck
+
+			p, err := New(cfg)
+			if (err != nil) != tt.want.err {
+				t.Fatalf("New() Unexpected error: %v", err)
+			}
+
+			if tt.want.err {
+				return
+			}
+
+			if p.healthCheck != tt.want.healthCheck {
+				t.Errorf("Proxy.parseHealthCheck() == '%+v', want '%+v'", p.healthCheck, tt.want.healthCheck)
+			}
+
+			for _, b := range p.backends {
+				if enabled := b.checkClient != nil; enabled != (tt.want.healthCheck.Path != "") {
+					t.Errorf("Proxy.parseHealthCheck() backend '%s' check client enabled == '%v'", b.addr, enabled)
+				}
+			}
+		})
+	}
+}
+
+func TestProxy_runHealthCheck(t *testing.T) {
+	addr := testHealthBackend(t)
+
+	type args struct {
+		path string
+		host string
+	}
+
+	type want struct {
+		failures  int
+		lastError string
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Ok",
+			args: args{path: "/health", host: "www.kratgo.com"},
+			want: want{failures: 0},
+		},
+		{
+			name: "UnexpectedStatus",
+			args: args{path: "/down", host: "www.kratgo.com"},
+			want: want{failures: 1, lastError: "Unexpected status code '503'"},
+		},
+		{
+			name: "Host",
+			args: args{path: "/health"},
+			want: want{failures: 1, lastError: "Unexpected status code '400'"},
+		},
+		{
+			name: "Timeout",
+			args: args{path: "/slow", host: "www.kratgo.com"},
+			want: want{failures: 1, lastError: fasthttp.ErrTimeout.Error()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.FileConfig.BackendAddrs = []string{addr}
+			cfg.FileConfig.HealthCheck = config.HealthCheck{
+				Path:    tt.args.path,
+				Host:    tt.args.host,
+				Timeout: 50 * time.Millisecond,
+				Fall:    1,
+			}
+
+			p, err := New(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b := p.backends[0]
+			p.runHealthCheck(b)
+
+			status := p.Backends()[0]
+
+			if status.Failures != tt.want.failures {
+				t.Errorf("Proxy.runHealthCheck() failures == '%d', want '%d'", status.Failures, tt.want.failures)
+			}
+
+			if healthy := tt.want.failures == 0; status.Healthy != healthy {
+				t.Errorf("Proxy.runHealthCheck() healthy == '%v', want '%v'", status.Healthy, healthy)
+			}
+
+			if status.LastError != tt.want.lastError {
+				t.Errorf("Proxy.runHealthCheck() last error == '%s', want '%s'", status.LastError, tt.want.lastError)
+			}
+
+			if status.LastCheck == 0 {
+				t.Errorf("Proxy.runHealthCheck() last check == '%d', want greater than 0", status.LastCheck)
+			}
+		})
+	}
+}
+
+func TestProxy_startHealthChecks(t *testing.T) {
+	cfg := testConfig()
+	cfg.FileConfig.BackendAddrs = []string{"127.0.0.1:1", testHealthBackend(t)}
+	cfg.FileConfig.HealthCheck = config.HealthCheck{
+		Path:     "/health",
+		Host:     "www.kratgo.com",
+		Interval: 10 * time.Millisecond,
+		Timeout:  50 * time.Millisecond,
+		Fall:     2,
+	}
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.startHealthChecks()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for p.backends[0].isHealthy() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if p.backends[0].isHealthy() {
+		t.Error("Proxy.startHealthChecks() the dead backend is still healthy")
+	}
+
+	if !p.backends[1].isHealthy() {
+		t.Error("Proxy.startHealthChecks() the live backend is unhealthy")
+	}
+
+	for i := 0; i < 4; i++ {
+		if b := p.getBackend(nil, nil, nil); b != p.backends[1] {
+			t.Errorf("Proxy.getBackend() == '%s', want '%s'", b.addr, p.backends[1].addr)
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package proxy
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	logger "github.com/savsgio/go-logger/v4"
 	"github.com/savsgio/govaluate/v3"
@@ -14,7 +16,7 @@ import (
 
 // New ...
 func New(cfg Config) (*Proxy, error) {
-	if len(cfg.FileConfig.BackendAddrs) == 0 {
+	if len(cfg.FileConfig.BackendAddrs) == 0 && len(cfg.FileConfig.Pools) == 0 {
 		return nil, fmt.Errorf("Proxy.BackendAddrs configuration is mandatory")
 	}
 
@@ -33,10 +35,28 @@ func New(cfg Config) (*Proxy, error) {
 	p.httpScheme = cfg.HTTPScheme
 	p.log = log
 
-	for _, addr := range p.fileConfig.BackendAddrs {
-		p.backends = append(p.backends, &fasthttp.HostClient{Addr: addr})
+	backends := make(map[string]*backend)
+	p.backends = p.newBackends(p.fileConfig.BackendAddrs, backends)
+
+	if err := p.parsePools(backends); err != nil {
+		return nil, err
+	}
+
+	if p.fileConfig.Coalescing.Enabled {
+		p.coalescer = newCoalescer()
+
+		p.coalescingTimeout = p.fileConfig.Coalescing.Timeout
+		if p.coalescingTimeout <= 0 {
+			p.coalescingTimeout = defaultCoalescingTimeout
+		}
 	}
-	p.totalBackends = len(p.backends)
+
+	p.revalidations = newCoalescer()
+	p.stats.hosts = make(map[string]*hostStats)
+	p.staleGrace = int64(p.fileConfig.Stale.Grace / time.Second)
+	p.staleKeep = int64(p.fileConfig.Stale.Keep / time.Second)
+	p.revalidationRetain = int64(p.fileConfig.Revalidation.Retain / time.Second)
+	p.tagsHeader = []byte(p.fileConfig.Cache.TagsHeader)
 
 	p.tools = sync.Pool{
 		New: func() interface{} {
@@ -47,10 +67,40 @@ func New(cfg Config) (*Proxy, error) {
 		},
 	}
 
+	if err := p.parseCacheKeyRules(); err != nil {
+		return nil, err
+	}
+
 	if err := p.parseNocacheRules(); err != nil {
 		return nil, err
 	}
 
+	if err := p.parseStatusTTLs(); err != nil {
+		return nil, err
+	}
+
+	if err := p.parseAdmissionRules(); err != nil {
+		return nil, err
+	}
+
+	p.parseDiagnostics()
+
+	if err := p.parseHealthCheck(); err != nil {
+		return nil, err
+	}
+
+	if err := p.parseCircuitBreaker(); err != nil {
+		return nil, err
+	}
+
+	if err := p.parseBalancer(); err != nil {
+		return nil, err
+	}
+
+	if err := p.parseRetry(); err != nil {
+		return nil, err
+	}
+
 	if err := p.parseHeadersRules(setHeaderAction, p.fileConfig.Response.Headers.Set); err != nil {
 		return nil, err
 	}
@@ -59,9 +109,39 @@ func New(cfg Config) (*Proxy, error) {
 		return nil, err
 	}
 
+	if err := p.parseRoutes(); err != nil {
+		return nil, err
+	}
+
 	return p, nil
 }
 
+// newBackends returns the backends of the addrs, which are shared by all the pools with the same addr.
+func (p *Proxy) newBackends(addrs []string, known map[string]*backend) []*backend {
+	backends := make([]*backend, 0, len(addrs))
+
+	for _, addr := range addrs {
+		b, ok := known[addr]
+
+		if !ok {
+			var client fetcher
+
+			if p.fileConfig.Cache.MaxObjectSize > 0 {
+				client = newStreamingClient(addr, p.fileConfig.Cache.MaxObjectSize)
+			} else {
+				client = &fasthttp.HostClient{Addr: addr}
+			}
+
+			b = newBackend(addr, client)
+			known[addr] = b
+		}
+
+		backends = append(backends, b)
+	}
+
+	return backends
+}
+
 func (p *Proxy) acquireTools() *proxyTools {
 	return p.tools.Get().(*proxyTools)
 }
@@ -69,28 +149,194 @@ func (p *Proxy) acquireTools() *proxyTools {
 func (p *Proxy) releaseTools(pt *proxyTools) {
 	pt.params.reset()
 	pt.entry.Reset()
+	pt.cacheKey = pt.cacheKey[:0]
+	pt.body = pt.body[:0]
+	pt.encoding = pt.encoding[:0]
+	pt.tags = pt.tags[:0]
+	pt.route = nil
+	pt.expired = nil
+	pt.status = cacheMiss
+	pt.ifNoneMatch = pt.ifNoneMatch[:0]
+	pt.ifModifiedSince = pt.ifModifiedSince[:0]
+	pt.fwd = ""
+	pt.fwdStatus = 0
+	pt.stored = false
+	pt.collapsed = false
+	pt.nocacheRule = ""
+	pt.storedAt = 0
+	pt.ttl = 0
+	pt.ranges = pt.ranges[:0]
+	pt.ifRange = pt.ifRange[:0]
+	pt.byteRanges = pt.byteRanges[:0]
 
 	p.tools.Put(pt)
 }
 
-func (p *Proxy) getBackend() fetcher {
-	if p.totalBackends == 1 {
-		return p.backends[0]
+// Stats returns the counters of the requests served from cache, revalidated with the backend
+// and fetched from the backend, in total and by host, and of the requests to the backends.
+func (p *Proxy) Stats() Stats {
+	return Stats{
+		Hits:            p.stats.hits.Load(),
+		Revalidated:     p.stats.revalidated.Load(),
+		Misses:          p.stats.misses.Load(),
+		BackendFetches:  p.stats.backendFetches.Load(),
+		BackendErrors:   p.stats.backendErrors.Load(),
+		BackendRetries:  p.stats.backendRetries.Load(),
+		NocacheBypasses: p.stats.nocacheBypasses.Load(),
+		Hosts:           p.hostsStats(),
+		Admission:       p.admissionStats(),
+	}
+}
+
+func (p *Proxy) hostsStats() map[string]HostStats {
+	p.stats.hostsMu.RLock()
+	defer p.stats.hostsMu.RUnlock()
+
+	stats := make(map[string]HostStats, len(p.stats.hosts))
+
+	for host, hs := range p.stats.hosts {
+		s := HostStats{Hits: hs.hits.Load(), Misses: hs.misses.Load()}
+		if total := s.Hits + s.Misses; total > 0 {
+			s.HitRatio = float64(s.Hits) / float64(total)
+		}
+
+		stats[host] = s
+	}
+
+	return stats
+}
+
+// getHostStats returns the stats of the host, or nil if the max number of hosts has been reached.
+func (p *Proxy) getHostStats(host []byte) *hostStats {
+	p.stats.hostsMu.RLock()
+	hs := p.stats.hosts[string(host)]
+	p.stats.hostsMu.RUnlock()
+
+	if hs != nil {
+		return hs
+	}
+
+	p.stats.hostsMu.Lock()
+	defer p.stats.hostsMu.Unlock()
+
+	if hs = p.stats.hosts[string(host)]; hs == nil && len(p.stats.hosts) < maxStatsHosts {
+		hs = new(hostStats)
+		p.stats.hosts[string(host)] = hs
+	}
+
+	return hs
+}
+
+func (p *Proxy) countRequest(host []byte, status cacheStatus) {
+	hit := true
+
+	switch status {
+	case cacheHit, cacheStale:
+		p.stats.hits.Add(1)
+	case cacheRevalidated:
+		p.stats.revalidated.Add(1)
+	default:
+		p.stats.misses.Add(1)
+		hit = false
+	}
+
+	hs := p.getHostStats(host)
+	if hs == nil {
+		return
+	}
+
+	if hit {
+		hs.hits.Add(1)
+	} else {
+		hs.misses.Add(1)
+	}
+}
+
+// doBackend sends the request to the backend of the pool chosen by its balancer, and retries it
+// on other backends of the pool if it fails and the retries are enabled.
+func (p *Proxy) doBackend(ctx *fasthttp.RequestCtx, pool *backendPool) error {
+	b := p.getBackend(ctx, pool, nil)
+	if b == nil {
+		return ErrNoHealthyBackend
+	}
+
+	retriable := p.retryBudget != nil && isIdempotentMethod(ctx.Request.Header.Method())
+	if retriable {
+		p.retryBudget.addRequest(time.Now())
+	}
+
+	var tried []*backend
+
+	for attempt := 1; ; attempt++ {
+		err := p.doBackendAttempt(b, attempt, ctx)
+
+		if !retriable || attempt >= p.retry.Attempts || !p.shouldRetry(err, &ctx.Response) {
+			return err
+		}
+
+		if !p.retryBudget.allowRetry(time.Now()) {
+			p.log.Warningf("Could not retry %s %s, the retry budget is exhausted", ctx.Method(), ctx.Path())
+			return err
+		}
+
+		tried = append(tried, b)
+
+		// The result of the last attempt is kept if there is no other backend to retry
+		next := p.getBackend(ctx, pool, tried)
+		if next == nil {
+			return err
+		}
+
+		p.log.Warningf("Retrying %s %s on backend '%s', attempt %d to backend '%s' failed", ctx.Method(), ctx.Path(), next.addr, attempt, b.addr)
+		p.stats.backendRetries.Add(1)
+		ctx.Response.Reset()
+
+		b = next
 	}
+}
+
+// doBackendAttempt sends the request to the backend, and counts its result.
+func (p *Proxy) doBackendAttempt(b *backend, attempt int, ctx *fasthttp.RequestCtx) error {
+	p.stats.backendFetches.Add(1)
+
+	b.active.Add(1)
+	err := b.Do(&ctx.Request, &ctx.Response)
+	b.active.Add(-1)
 
-	p.mu.Lock()
+	p.recordBackendResult(b, err, &ctx.Response)
 
-	if p.currentBackend >= p.totalBackends-1 {
-		p.currentBackend = 0
+	if err != nil {
+		p.stats.backendErrors.Add(1)
+		p.log.Debugf("Attempt %d of %s %s to backend '%s' failed: %v", attempt, ctx.Method(), ctx.Path(), b.addr, err)
 	} else {
-		p.currentBackend++
+		p.log.Debugf("Attempt %d of %s %s to backend '%s': %d", attempt, ctx.Method(), ctx.Path(), b.addr, ctx.Response.StatusCode())
 	}
 
-	backend := p.backends[p.currentBackend]
+	return err
+}
 
-	p.mu.Unlock()
+// getBackend returns the backend of the pool for the request not tried yet, or nil if all its backends
+// are unhealthy, their circuits are open or they have been tried. The nil pool is the default one.
+func (p *Proxy) getBackend(ctx *fasthttp.RequestCtx, pool *backendPool, tried []*backend) *backend {
+	now := time.Now()
 
-	return backend
+	backends, bl := p.backends, p.balancer
+	if pool != nil {
+		backends, bl = pool.backends, pool.balancer
+	}
+
+	switch len(backends) {
+	case 0:
+		return nil
+	case 1:
+		if b := backends[0]; !containsBackend(tried, b) && b.isAvailable(now) {
+			return b
+		}
+
+		return nil
+	}
+
+	return bl.next(backends, ctx, tried, now)
 }
 
 func (p *Proxy) newEvaluableExpression(rule string) (*govaluate.EvaluableExpression, []ruleParam, error) {
@@ -110,31 +356,98 @@ func (p *Proxy) newEvaluableExpression(rule string) (*govaluate.EvaluableExpress
 	return expr, params, err
 }
 
-func (p *Proxy) parseNocacheRules() error {
-	for _, ncRule := range p.fileConfig.Nocache {
-		r := rule{}
+func (p *Proxy) parseCacheKeyRules() error {
+	key := p.fileConfig.Cache.Key
+	if key == "" {
+		key = defaultCacheKey
+	}
+
+	t, err := newCacheKeyTemplate(key)
+	if err != nil {
+		return fmt.Errorf("Could not parse the cache key '%s': %v", key, err)
+	}
+	p.defaultCacheKey = t
+
+	for _, cr := range p.fileConfig.Cache.Rules {
+		if cr.When == "" {
+			return fmt.Errorf("The condition of the cache key '%s' is mandatory", cr.Key)
+		}
+
+		r := cacheKeyRule{}
+
+		expr, params, err := p.newEvaluableExpression(cr.When)
+		if err != nil {
+			return fmt.Errorf("Could not get the evaluable expression for rule '%s': %v", cr.When, err)
+		}
+		r.expr = expr
+		r.params = append(r.params, params...)
+
+		if r.key, err = newCacheKeyTemplate(cr.Key); err != nil {
+			return fmt.Errorf("Could not parse the cache key '%s': %v", cr.Key, err)
+		}
+
+		p.cacheKeyRules = append(p.cacheKeyRules, r)
+	}
+
+	return nil
+}
+
+func (p *Proxy) newNocacheRules(ncRules []string) ([]nocacheRule, error) {
+	var rules []nocacheRule
+
+	for _, ncRule := range ncRules {
+		r := nocacheRule{when: ncRule}
 
 		expr, params, err := p.newEvaluableExpression(ncRule)
 		if err != nil {
-			return fmt.Errorf("Could not get the evaluable expression for rule '%s': %v", ncRule, err)
+			return nil, fmt.Errorf("Could not get the evaluable expression for rule '%s': %v", ncRule, err)
 		}
 		r.expr = expr
 		r.params = append(r.params, params...)
 
-		p.nocacheRules = append(p.nocacheRules, r)
+		rules = append(rules, r)
 	}
 
+	return rules, nil
+}
+
+func (p *Proxy) parseNocacheRules() error {
+	rules, err := p.newNocacheRules(p.fileConfig.Nocache)
+	if err != nil {
+		return err
+	}
+
+	p.nocacheRules = append(p.nocacheRules, rules...)
+
 	return nil
 }
 
-func (p *Proxy) parseHeadersRules(action typeHeaderAction, headers []config.Header) error {
+func (p *Proxy) parseStatusTTLs() error {
+	p.statusTTLs = make(map[int]int64)
+
+	for statusCode, ttl := range p.fileConfig.Cache.Statuses {
+		if !isCacheableStatusCode(statusCode) {
+			return fmt.Errorf("The status code '%d' could not be cached", statusCode)
+		} else if ttl < 0 {
+			return fmt.Errorf("The ttl of the status code '%d' must be greater than or equal to 0", statusCode)
+		}
+
+		p.statusTTLs[statusCode] = int64(ttl / time.Second)
+	}
+
+	return nil
+}
+
+func (p *Proxy) newHeadersRules(action typeHeaderAction, headers []config.Header) ([]headerRule, error) {
+	var rules []headerRule
+
 	for _, h := range headers {
 		r := headerRule{action: action, name: h.Name}
 
 		if h.When != "" {
 			expr, params, err := p.newEvaluableExpression(h.When)
 			if err != nil {
-				return fmt.Errorf("Could not get the evaluable expression for rule '%s': %v", h.When, err)
+				return nil, fmt.Errorf("Could not get the evaluable expression for rule '%s': %v", h.When, err)
 			}
 			r.expr = expr
 			r.params = append(r.params, params...)
@@ -150,33 +463,99 @@ func (p *Proxy) parseHeadersRules(action typeHeaderAction, headers []config.Head
 			}
 		}
 
-		p.headersRules = append(p.headersRules, r)
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+func (p *Proxy) parseHeadersRules(action typeHeaderAction, headers []config.Header) error {
+	rules, err := p.newHeadersRules(action, headers)
+	if err != nil {
+		return err
 	}
 
+	p.headersRules = append(p.headersRules, rules...)
+
 	return nil
 }
 
-func (p *Proxy) saveBackendResponse(cacheKey, path []byte, resp *fasthttp.Response, entry *cache.Entry) error {
+func (p *Proxy) saveBackendResponse(cacheKey, host, path []byte, lt lifetime, ctx *fasthttp.RequestCtx, pt *proxyTools) error {
 	r := cache.AcquireResponse()
+
+	if !setResponseVary(r, &ctx.Request.Header, &ctx.Response.Header) {
+		cache.ReleaseResponse(r)
+
+		return nil
+	}
+
+	r.Host = append(r.Host, host...)
 	r.Path = append(r.Path, path...)
-	r.Body = append(r.Body, resp.Body()...)
+	r.StoredAt = time.Now().Unix()
+	r.TTL = lt.ttl
+	r.Grace = lt.grace
+	r.Keep = lt.keep
+	r.Retain = lt.retain
+	r.Revalidate = lt.revalidate
+	r.StatusCode = ctx.Response.StatusCode()
+	setResponseTags(r, pt.tags)
+
+	// The identity body is stored, and the backend one as a compressed version of it
+	if len(pt.encoding) > 0 {
+		r.Body = append(r.Body, pt.body...)
+		r.SetEncodedBody(pt.encoding, ctx.Response.Body())
+	} else {
+		r.Body = append(r.Body, ctx.Response.Body()...)
+	}
+
+	// Responses without validators get a weak ETag, so the repeat visitors could revalidate them
+	if r.StatusCode == fasthttp.StatusOK && len(ctx.Response.Header.PeekBytes(headerETag)) == 0 &&
+		len(ctx.Response.Header.PeekBytes(headerLastModified)) == 0 {
+		ctx.Response.Header.SetBytesKV(headerETag, appendWeakETag(nil, r.Body))
+	}
 
-	resp.Header.VisitAll(func(k, v []byte) {
-		r.SetHeader(k, v)
+	ctx.Response.Header.VisitAll(func(k, v []byte) {
+		if !bytes.EqualFold(k, strContentEncoding) {
+			r.SetHeader(k, v)
+		}
 	})
 
-	entry.SetResponse(*r)
+	pt.entry.SetResponse(*r)
 
-	if err := p.cache.SetBytes(cacheKey, *entry); err != nil {
+	if err := p.cache.SetBytes(cacheKey, *pt.entry); err != nil {
 		return fmt.Errorf("Could not save response in cache for key '%s': %v", cacheKey, err)
 	}
 
+	pt.stored = true
+
 	cache.ReleaseResponse(r)
 
 	return nil
 }
 
-func (p *Proxy) fetchFromBackend(cacheKey, path []byte, ctx *fasthttp.RequestCtx, pt *proxyTools) error {
+// decodeBackendBody decompresses the body of the backend response into pt.body, if it's compressed.
+// Returns false if the body could not be decompressed, so it could not be served to all clients.
+func (p *Proxy) decodeBackendBody(ctx *fasthttp.RequestCtx, pt *proxyTools) bool {
+	pt.encoding = appendNormalizedVaryValue(pt.encoding[:0], ctx.Response.Header.Peek(headerContentEncoding))
+	if len(pt.encoding) == 0 {
+		return true
+	} else if !isSupportedEncoding(pt.encoding) {
+		return false
+	}
+
+	body, err := appendDecodedBody(pt.body[:0], pt.encoding, ctx.Response.Body())
+	pt.body = body
+
+	if err != nil {
+		p.log.Warningf("Could not decode the backend response body with '%s': %v", pt.encoding, err)
+
+		return false
+	}
+
+	return true
+}
+
+func (p *Proxy) fetchFromBackend(cacheKey, host, path []byte, ctx *fasthttp.RequestCtx, pt *proxyTools) error {
 	p.log.Debugf("%s - %s", ctx.Method(), ctx.Path())
 
 	ctx.Request.Header.Set(proxyReqHeaderKey, proxyReqHeaderValue)
@@ -184,67 +563,469 @@ func (p *Proxy) fetchFromBackend(cacheKey, path []byte, ctx *fasthttp.RequestCtx
 		ctx.Request.Header.Del(header)
 	}
 
-	if err := p.getBackend().Do(&ctx.Request, &ctx.Response); err != nil {
+	if pt.expired != nil {
+		pt.ifNoneMatch = append(pt.ifNoneMatch[:0], ctx.Request.Header.Peek(fasthttp.HeaderIfNoneMatch)...)
+		pt.ifModifiedSince = append(pt.ifModifiedSince[:0], ctx.Request.Header.Peek(fasthttp.HeaderIfModifiedSince)...)
+
+		setConditionalHeaders(&ctx.Request.Header, pt.expired)
+	}
+
+	if err := p.doBackend(ctx, pt.route.backendPool()); err == ErrNoHealthyBackend {
+		return err
+	} else if err != nil {
+		return fmt.Errorf("Could not fetch response from backend: %v", err)
+	}
+
+	pt.fwdStatus = ctx.Response.StatusCode()
+
+	// The cached response has not changed, so it's refreshed without transferring the body again
+	if pt.expired != nil && ctx.Response.StatusCode() == fasthttp.StatusNotModified {
+		mergeNotModified(&ctx.Response, pt.expired)
+		pt.status = cacheRevalidated
+	}
+
+	// The bodies too large to be cached are streamed to the client, so they are not read here
+	streamed := ctx.Response.IsBodyStream()
+
+	decoded := !streamed && p.decodeBackendBody(ctx, pt)
+	err := p.processBackendResponse(cacheKey, host, path, decoded, ctx, pt)
+
+	if err == nil && streamed && len(pt.ranges) > 0 {
+		return p.fetchRangesFromBackend(ctx, pt)
+	}
+
+	// The clients which do not accept the backend encoding get the identity body
+	if decoded && len(pt.encoding) > 0 && !bytes.Equal(ctx.Request.Header.Peek(fasthttp.HeaderAcceptEncoding), pt.encoding) {
+		ctx.Response.SetBody(pt.body)
+		ctx.Response.Header.Del(headerContentEncoding)
+	}
+
+	// The client could already have the refreshed response, checked with its own conditional headers
+	if err == nil && pt.status == cacheRevalidated && (ctx.IsGet() || ctx.IsHead()) &&
+		matchValidators(pt.ifNoneMatch, pt.ifModifiedSince, ctx.Response.Header.PeekBytes(headerETag),
+			ctx.Response.Header.PeekBytes(headerLastModified)) {
+		setNotModified(&ctx.Response)
+	}
+
+	return err
+}
+
+// fetchRangesFromBackend requests to the backend the ranges of the client,
+// since they could not be served from a streamed response.
+func (p *Proxy) fetchRangesFromBackend(ctx *fasthttp.RequestCtx, pt *proxyTools) error {
+	ctx.Response.Reset()
+
+	ctx.Request.Header.SetBytesV(fasthttp.HeaderRange, pt.ranges)
+	if len(pt.ifRange) > 0 {
+		ctx.Request.Header.SetBytesV(fasthttp.HeaderIfRange, pt.ifRange)
+	}
+
+	// The backend response is already the requested one
+	pt.ranges = pt.ranges[:0]
+	pt.ifRange = pt.ifRange[:0]
+
+	if err := p.doBackend(ctx, pt.route.backendPool()); err == ErrNoHealthyBackend {
+		return err
+	} else if err != nil {
 		return fmt.Errorf("Could not fetch response from backend: %v", err)
 	}
 
-	if err := processHeaderRules(ctx, p.headersRules, pt.params); err != nil {
+	if err := processHeaderRules(ctx, p.headersRulesOf(pt.route), pt.params); err != nil {
 		return fmt.Errorf("Could not process headers rules: %v", err)
 	}
 
+	return nil
+}
+
+func (p *Proxy) processBackendResponse(cacheKey, host, path []byte, decoded bool, ctx *fasthttp.RequestCtx, pt *proxyTools) error {
+	ttl, storable, revalidate := responseTTL(&ctx.Response, time.Now())
+	grace, keep := responseStaleWindows(&ctx.Response, p.staleGrace, p.staleKeep)
+	ctx.Response.Header.Del(headerSurrogateControl)
+
+	if len(p.tagsHeader) > 0 {
+		pt.tags = append(pt.tags[:0], ctx.Response.Header.PeekBytes(p.tagsHeader)...)
+		ctx.Response.Header.DelBytes(p.tagsHeader)
+	}
+
+	if err := processHeaderRules(ctx, p.headersRulesOf(pt.route), pt.params); err != nil {
+		return fmt.Errorf("Could not process headers rules: %v", err)
+	}
+
+	statusCode := ctx.Response.StatusCode()
+	statusTTL, cacheableStatus := p.statusTTLs[statusCode]
+
+	// The redirections are only cached if their status code is configured
 	location := ctx.Response.Header.Peek(headerLocation)
-	if len(location) > 0 {
+	if len(location) > 0 && !cacheableStatus {
 		return nil
 	}
 
-	noCache, err := checkIfNoCache(ctx, p.nocacheRules, pt.params)
+	if (len(pt.encoding) > 0 || isCompressible(ctx.Response.Header.ContentType())) && !hasVaryAcceptEncoding(&ctx.Response.Header) {
+		ctx.Response.Header.Add(fasthttp.HeaderVary, fasthttp.HeaderAcceptEncoding)
+	}
+
+	noCache, err := checkIfNoCache(ctx, p.nocacheRulesOf(pt.route), pt.params)
 	if err != nil {
 		return err
+	} else if noCache != nil {
+		pt.nocacheRule = noCache.when
+	}
+
+	if noCache != nil || !storable || !decoded || (statusCode != fasthttp.StatusOK && !cacheableStatus) {
+		return nil
+	}
+
+	// A server error never replaces the expired response, which could be served stale or revalidated
+	if pt.expired != nil && statusCode >= fasthttp.StatusInternalServerError {
+		return nil
+	}
+
+	body := ctx.Response.Body()
+	if len(pt.encoding) > 0 {
+		body = pt.body
+	}
+
+	validators := statusCode == fasthttp.StatusOK && hasBackendValidators(&ctx.Response.Header, body)
+
+	// The responses which must be revalidated before each use are only saved if they could be
+	// revalidated with a conditional request
+	if revalidate && !validators {
+		return nil
+	}
+
+	// The admission policies only filter the new responses, the cached ones are always refreshed
+	if !pt.entry.HasResponse(path) {
+		if admitted, err := p.admit(cacheKey, path, ctx, pt); err != nil || !admitted {
+			return err
+		}
+	}
+
+	lt := lifetime{ttl: ttl, grace: grace, keep: keep, revalidate: revalidate}
+
+	if revalidate {
+		// The responses which must be revalidated are never served stale without revalidating them
+		lt.grace, lt.keep = 0, 0
+	} else if lt.ttl == 0 {
+		// The responses without an explicit lifetime expire after the ttl of their status code, if configured,
+		// or after the ttl of their route
+		lt.ttl = statusTTL
+
+		if lt.ttl == 0 && pt.route != nil {
+			lt.ttl = pt.route.ttl
+		}
+	}
+
+	if p.revalidationRetain > 0 && validators {
+		lt.retain = p.revalidationRetain
+	}
+
+	return p.saveBackendResponse(cacheKey, host, path, lt, ctx, pt)
+}
+
+// writeCachedResponse writes the cached response, compressed with the encoding accepted by the client
+// if its content type is compressible. The compressed body is produced only once and saved in cache.
+func (p *Proxy) writeCachedResponse(ctx *fasthttp.RequestCtx, pt *proxyTools, r *cache.Response) {
+	pt.storedAt = r.StoredAt
+	pt.ttl = r.TTL
+
+	if isNotModified(&ctx.Request.Header, r) {
+		writeNotModified(ctx, r)
+		return
+	}
+
+	ctx.SetStatusCode(responseStatusCode(r))
+
+	for _, h := range r.Headers {
+		ctx.Response.Header.SetCanonical(h.Key, h.Value)
+	}
+
+	encoding := ctx.Request.Header.Peek(fasthttp.HeaderAcceptEncoding)
+	if len(encoding) == 0 {
+		ctx.SetBody(r.Body)
+		return
+	}
+
+	body := r.GetEncodedBody(encoding)
+	if body == nil {
+		if !isCompressible(ctx.Response.Header.ContentType()) {
+			ctx.SetBody(r.Body)
+			return
+		}
+
+		encoded, err := appendEncodedBody(nil, encoding, r.Body)
+		if err != nil {
+			p.log.Errorf("Could not encode the response body with '%s': %v", encoding, err)
+
+			ctx.SetBody(r.Body)
+			return
+		}
+
+		p.saveEncodedBody(pt.cacheKey, ctx, r.StoredAt, encoding, encoded)
+		body = encoded
+	}
+
+	// The encoded body is not byte-for-byte the cached one, so its strong ETag is weakened
+	if etag := ctx.Response.Header.PeekBytes(headerETag); len(etag) > 0 && !isWeakETag(etag) {
+		ctx.Response.Header.SetBytesKV(headerETag, appendWeakenedETag(nil, etag))
+	}
+
+	ctx.Response.Header.SetBytesV(headerContentEncoding, encoding)
+	ctx.SetBody(body)
+}
+
+// saveEncodedBody saves in cache the compressed body of the response variant selected by the request,
+// only if it has not been replaced since it was read. The entry keeps its expiration, and the bodies
+// which could not be saved are not tried again for the same response.
+func (p *Proxy) saveEncodedBody(cacheKey []byte, ctx *fasthttp.RequestCtx, storedAt int64, encoding, body []byte) {
+	if p.unsavedEncodings.has(cacheKey, encoding, storedAt) {
+		return
+	}
+
+	entry := cache.AcquireEntry()
+	defer cache.ReleaseEntry(entry)
+
+	if err := p.cache.GetBytes(cacheKey, entry); err != nil {
+		p.log.Errorf("Could not get data from cache with key '%s': %v", cacheKey, err)
+		return
+	}
+
+	r := getResponseVariant(entry, ctx.URI().PathOriginal(), &ctx.Request.Header)
+	if r == nil || r.StoredAt != storedAt {
+		return
+	}
+
+	r.SetEncodedBody(encoding, body)
+
+	if err := p.cache.UpdateBytes(cacheKey, *entry); err != nil {
+		p.unsavedEncodings.add(cacheKey, encoding, storedAt)
+		p.log.Debugf("Could not save the '%s' encoded body in cache for key '%s': %v", encoding, cacheKey, err)
+	}
+}
+
+// fetchCoalesced collapses the concurrent cache misses of the same key, so only one request
+// fetches the response from backend and the rest wait for it and get it from cache.
+// If the response could not be saved in cache, the waiting requests fetch it by themselves.
+func (p *Proxy) fetchCoalesced(cacheKey, host, path []byte, ctx *fasthttp.RequestCtx, pt *proxyTools) error {
+	call, leader := p.coalescer.join(cacheKey)
+	if leader {
+		err := p.fetchFromBackend(cacheKey, host, path, ctx, pt)
+		p.coalescer.done(cacheKey, call, err)
+
+		return err
+	}
+
+	if !call.wait(p.coalescingTimeout) {
+		p.log.Debugf("Timeout waiting the coalesced request of '%s'", cacheKey)
+
+		return p.fetchFromBackend(cacheKey, host, path, ctx, pt)
+	}
+
+	if call.err != nil {
+		return call.err
 	}
 
-	if noCache || ctx.Response.StatusCode() != fasthttp.StatusOK {
+	pt.entry.Reset()
+
+	if err := p.cache.GetBytes(cacheKey, pt.entry); err != nil {
+		p.log.Errorf("Could not get data from cache with key '%s': %v", cacheKey, err)
+
+	} else if r := getResponseVariant(pt.entry, path, &ctx.Request.Header); r != nil {
+		p.writeCachedResponse(ctx, pt, r)
+		pt.status = cacheHit
+		pt.collapsed = true
+
 		return nil
 	}
 
-	return p.saveBackendResponse(cacheKey, path, &ctx.Response, pt.entry)
+	return p.fetchFromBackend(cacheKey, host, path, ctx, pt)
+}
+
+// revalidate refreshes in background the expired response of the cache key, while the stale one is served.
+// Only one refresh of the same cache key runs at the same time.
+func (p *Proxy) revalidate(cacheKey []byte, r *route, ctx *fasthttp.RequestCtx) {
+	call, leader := p.revalidations.join(cacheKey)
+	if !leader {
+		return
+	}
+
+	pt := p.acquireTools()
+	pt.cacheKey = append(pt.cacheKey, cacheKey...)
+	pt.route = r
+
+	// The request context is reused once the handler returns, so the refresh works over a copy
+	rctx := new(fasthttp.RequestCtx)
+	ctx.Request.CopyTo(&rctx.Request)
+
+	go func() {
+		err := p.cache.GetBytes(pt.cacheKey, pt.entry)
+		if err != nil {
+			err = fmt.Errorf("Could not get data from cache with key '%s': %v", pt.cacheKey, err)
+		} else {
+			pt.expired = getResponseVariant(pt.entry, rctx.URI().PathOriginal(), &rctx.Request.Header)
+			err = p.fetchFromBackend(pt.cacheKey, rctx.Host(), rctx.URI().PathOriginal(), rctx, pt)
+		}
+
+		if err != nil {
+			p.log.Errorf("Could not revalidate the response of '%s': %v", pt.cacheKey, err)
+		}
+
+		p.revalidations.done(pt.cacheKey, call, err)
+
+		// Nobody writes the refreshed response, so a streamed body must be closed to release its connection
+		rctx.Response.Reset()
+		p.releaseTools(pt)
+	}()
 }
 
 func (p *Proxy) handler(ctx *fasthttp.RequestCtx) {
 	pt := p.acquireTools()
 
+	normalizeAcceptEncoding(&ctx.Request.Header)
+
+	host := ctx.Host()
 	path := ctx.URI().PathOriginal()
-	cacheKey := ctx.Host()
 
-	if noCache, err := checkIfNoCache(ctx, p.nocacheRules, pt.params); err != nil {
+	route, err := p.matchRoute(ctx, pt.params)
+	pt.route = route
+
+	if err == nil {
+		pt.cacheKey, err = appendCacheKey(pt.cacheKey, ctx, p.defaultCacheKey, p.cacheKeyRules, pt.params)
+	}
+
+	cacheKey := pt.cacheKey
+
+	if err != nil {
 		ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
 		p.log.Error(err)
 
-	} else if !noCache {
+		p.writeCacheStatus(ctx, pt)
+		p.releaseTools(pt)
+		return
+	}
+
+	coalesce, cacheable := false, false
+	var stale *cache.Response
+
+	if noCache, err := checkIfNoCache(ctx, p.nocacheRulesOf(pt.route), pt.params); err != nil {
+		ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
+		p.log.Error(err)
+
+	} else if noCache != nil {
+		pt.status = cacheBypass
+		pt.fwd = fwdBypass
+		pt.nocacheRule = noCache.when
+
+		p.stats.nocacheBypasses.Add(1)
+
+	} else {
+		cacheable = true
+
+		// The full response is always fetched and cached, and the ranges are served from it
+		pt.ranges = append(pt.ranges, ctx.Request.Header.Peek(fasthttp.HeaderRange)...)
+		pt.ifRange = append(pt.ifRange, ctx.Request.Header.Peek(fasthttp.HeaderIfRange)...)
+		ctx.Request.Header.Del(fasthttp.HeaderRange)
+		ctx.Request.Header.Del(fasthttp.HeaderIfRange)
+
 		if err := p.cache.GetBytes(cacheKey, pt.entry); err != nil {
 			ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
 			p.log.Errorf("Could not get data from cache with key '%s': %v", cacheKey, err)
 
-		} else if r := pt.entry.GetResponse(path); r != nil {
-			ctx.SetBody(r.Body)
-			for _, h := range r.Headers {
-				ctx.Response.Header.SetCanonical(h.Key, h.Value)
+		} else {
+			coalesce = p.coalescer != nil && isIdempotent(ctx)
+
+			if r := getResponseVariant(pt.entry, path, &ctx.Request.Header); r != nil {
+				now := time.Now().Unix()
+
+				if !r.IsExpired(now) {
+					pt.status = cacheHit
+					p.writeCachedResponse(ctx, pt, r)
+					p.countRequest(host, pt.status)
+					writeByteRanges(ctx, pt)
+
+					p.writeCacheStatus(ctx, pt)
+					p.releaseTools(pt)
+					return
+
+				} else if r.InGrace(now) {
+					pt.status = cacheStale
+					p.writeCachedResponse(ctx, pt, r)
+					p.revalidate(cacheKey, pt.route, ctx)
+					p.countRequest(host, pt.status)
+					writeByteRanges(ctx, pt)
+
+					p.writeCacheStatus(ctx, pt)
+					p.releaseTools(pt)
+					return
+
+				} else if r.InKeep(now) || r.InRetain(now) {
+					// The entry could be modified by the fetch, so keep a copy to revalidate it
+					// and to serve it if the backend fails
+					pt.expired = cache.AcquireResponse()
+					r.CopyTo(pt.expired)
+
+					if r.InKeep(now) {
+						stale = pt.expired
+					}
+				}
 			}
 
-			p.releaseTools(pt)
-			return
+			switch {
+			case pt.expired != nil:
+				pt.fwd = fwdStale
+			case pt.entry.HasResponse(path):
+				pt.fwd = fwdVaryMiss
+			default:
+				pt.fwd = fwdURIMiss
+			}
 		}
 	}
 
-	if err := p.fetchFromBackend(cacheKey, path, ctx, pt); err != nil {
+	if coalesce {
+		err = p.fetchCoalesced(cacheKey, host, path, ctx, pt)
+	} else {
+		err = p.fetchFromBackend(cacheKey, host, path, ctx, pt)
+	}
+
+	if stale != nil && (err != nil || ctx.Response.StatusCode() >= fasthttp.StatusInternalServerError) {
+		p.log.Warningf("Serving stale response of '%s' due to backend failure (status: %d, error: %v)",
+			cacheKey, ctx.Response.StatusCode(), err)
+
+		ctx.Response.Reset()
+		p.writeCachedResponse(ctx, pt, stale)
+		pt.status = cacheStale
+
+	} else if err == ErrNoHealthyBackend {
+		p.writeUnavailable(ctx)
+		p.log.Debugf("Could not serve '%s': %v", cacheKey, err)
+
+	} else if err != nil {
 		ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
 		p.log.Error(err)
 	}
 
+	if cacheable {
+		p.countRequest(host, pt.status)
+		writeByteRanges(ctx, pt)
+	}
+
+	p.writeCacheStatus(ctx, pt)
+
+	if pt.expired != nil {
+		cache.ReleaseResponse(pt.expired)
+	}
+
 	p.releaseTools(pt)
 }
 
+// Handler returns the request handler of the proxy, to serve requests through the cache without a connection.
+func (p *Proxy) Handler() fasthttp.RequestHandler {
+	return p.handler
+}
+
 // ListenAndServe ...
 func (p *Proxy) ListenAndServe() error {
+	p.startHealthChecks()
+
 	p.log.Infof("Listening on: %s://%s/", p.httpScheme, p.fileConfig.Addr)
 
 	return p.server.ListenAndServe(p.fileConfig.Addr)
@@ -9,6 +9,7 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -48,6 +49,16 @@ func (mock *mockBackend) Do(req *fasthttp.Request, resp *fasthttp.Response) erro
 	return mock.err
 }
 
+func newTestBackends(clients ...fetcher) []*backend {
+	backends := make([]*backend, len(clients))
+
+	for i, client := range clients {
+		backends[i] = newBackend(fmt.Sprintf("localhost:999%d", i), client)
+	}
+
+	return backends
+}
+
 var testCache *cache.Cache
 
 func init() {
@@ -170,6 +181,27 @@ func TestProxy_New(t *testing.T) {
 				err: true,
 			},
 		},
+		{
+			name: "ErrorParseCacheKeyRules",
+			args: args{
+				cfg: Config{
+					FileConfig: config.Proxy{
+						Addr:         "localhost:9999",
+						BackendAddrs: []string{"localhost:8881", "localhost:8882"},
+						Cache: config.ProxyCache{
+							Key: "$(host)$(resp.header::X-Data)",
+						},
+					},
+					Cache:      testCache,
+					HTTPScheme: httpScheme,
+					LogLevel:   logLevel,
+					LogOutput:  logOutput,
+				},
+			},
+			want: want{
+				err: true,
+			},
+		},
 		{
 			name: "ErrorParseNoCacheRules",
 			args: args{
@@ -286,13 +318,14 @@ func TestProxy_New(t *testing.T) {
 				t.Errorf("New() httpScheme == '%v', want '%v'", p.httpScheme, httpScheme)
 			}
 
-			totalBackends := len(tt.args.cfg.FileConfig.BackendAddrs)
 			if len(p.backends) != len(tt.args.cfg.FileConfig.BackendAddrs) {
 				t.Errorf("New() backends == '%v', want '%v'", p.backends, tt.args.cfg.FileConfig.BackendAddrs)
 			}
 
-			if p.totalBackends != totalBackends {
-				t.Errorf("New() totalBackends == '%v', want '%v'", p.totalBackends, totalBackends)
+			for i, b := range p.backends {
+				if addr := tt.args.cfg.FileConfig.BackendAddrs[i]; b.addr != addr || !b.isHealthy() {
+					t.Errorf("New() backend == '%s' (healthy: %v), want '%s' (healthy: true)", b.addr, b.isHealthy(), addr)
+				}
 			}
 
 			if p.tools.New == nil {
@@ -308,11 +341,11 @@ func TestProxy_getBackend(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	var prevBackend fetcher
+	var prevBackend *backend
 	for i := 0; i < len(p.backends)*3; i++ {
-		backend := p.getBackend()
+		backend := p.getBackend(nil, nil, nil)
 
-		if p.totalBackends == 1 {
+		if len(p.backends) == 1 {
 			if prevBackend != nil && backend != prevBackend {
 				t.Errorf("Proxy.getBackend() returns other backend, current '%p', previous '%p'", backend, prevBackend)
 			}
@@ -324,6 +357,27 @@ func TestProxy_getBackend(t *testing.T) {
 
 		prevBackend = backend
 	}
+
+	// The unhealthy backends are out of the rotation
+	for _, b := range p.backends[1:] {
+		b.healthy.Store(false)
+	}
+
+	for i := 0; i < len(p.backends); i++ {
+		if backend := p.getBackend(nil, nil, nil); backend != p.backends[0] {
+			t.Errorf("Proxy.getBackend() returns the unhealthy backend '%p', want '%p'", backend, p.backends[0])
+		}
+	}
+
+	p.backends[0].healthy.Store(false)
+
+	if backend := p.getBackend(nil, nil, nil); backend != nil {
+		t.Errorf("Proxy.getBackend() returns '%p' with all backends unhealthy, want '%v'", backend, nil)
+	}
+
+	if err := p.doBackend(new(fasthttp.RequestCtx), nil); err != ErrNoHealthyBackend {
+		t.Errorf("Proxy.doBackend() error == '%v', want '%v'", err, ErrNoHealthyBackend)
+	}
 }
 
 func TestProxy_newEvaluableExpression(t *testing.T) {
@@ -503,6 +557,116 @@ func TestProxy_newEvaluableExpression(t *testing.T) {
 	}
 }
 
+func TestProxy_parseCacheKeyRules(t *testing.T) {
+	type args struct {
+		key   string
+		rules []config.ProxyCacheRule
+	}
+
+	type want struct {
+		err bool
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Default",
+			args: args{},
+			want: want{
+				err: false,
+			},
+		},
+		{
+			name: "Ok",
+			args: args{
+				key: "$(host)$(path)?$(query)",
+				rules: []config.ProxyCacheRule{
+					{When: "$(path) =~ '^/search/'", Key: "$(host)$(path)?$(query::q)"},
+				},
+			},
+			want: want{
+				err: false,
+			},
+		},
+		{
+			name: "ErrorKey",
+			args: args{
+				key: "$(host)$(fake)",
+			},
+			want: want{
+				err: true,
+			},
+		},
+		{
+			name: "ErrorRuleCondition",
+			args: args{
+				rules: []config.ProxyCacheRule{
+					{When: "$(fake) == 'kratgo'", Key: "$(host)$(path)"},
+				},
+			},
+			want: want{
+				err: true,
+			},
+		},
+		{
+			name: "ErrorRuleWithoutCondition",
+			args: args{
+				rules: []config.ProxyCacheRule{
+					{Key: "$(host)$(path)"},
+				},
+			},
+			want: want{
+				err: true,
+			},
+		},
+		{
+			name: "ErrorRuleKey",
+			args: args{
+				rules: []config.ProxyCacheRule{
+					{When: "$(path) == '/'", Key: ""},
+				},
+			},
+			want: want{
+				err: true,
+			},
+		},
+	}
+
+	p, err := New(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p.defaultCacheKey = nil
+			p.cacheKeyRules = p.cacheKeyRules[:0]
+			p.fileConfig.Cache.Key = tt.args.key
+			p.fileConfig.Cache.Rules = tt.args.rules
+
+			err := p.parseCacheKeyRules()
+			if (err != nil) != tt.want.err {
+				t.Fatalf("Proxy.parseCacheKeyRules() Unexpected error: %v", err)
+			}
+
+			if tt.want.err {
+				return
+			}
+
+			if p.defaultCacheKey == nil {
+				t.Errorf("Proxy.parseCacheKeyRules() default cache key is '%v'", nil)
+			}
+
+			if len(p.cacheKeyRules) != len(tt.args.rules) {
+				t.Errorf("Proxy.parseCacheKeyRules() parsed %d rules, want %d", len(p.cacheKeyRules), len(tt.args.rules))
+			}
+		})
+	}
+}
+
 func TestProxy_parseNocacheRules(t *testing.T) {
 	type args struct {
 		rules []string
@@ -567,6 +731,89 @@ func TestProxy_parseNocacheRules(t *testing.T) {
 	}
 }
 
+func TestProxy_parseStatusTTLs(t *testing.T) {
+	type args struct {
+		statuses map[int]time.Duration
+	}
+
+	type want struct {
+		statusTTLs map[int]int64
+		err        bool
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Ok",
+			args: args{
+				statuses: map[int]time.Duration{301: time.Hour, 404: 30 * time.Second, 410: 0},
+			},
+			want: want{
+				statusTTLs: map[int]int64{301: 3600, 404: 30, 410: 0},
+			},
+		},
+		{
+			name: "PartialContent",
+			args: args{
+				statuses: map[int]time.Duration{206: time.Hour},
+			},
+			want: want{
+				err: true,
+			},
+		},
+		{
+			name: "NotModified",
+			args: args{
+				statuses: map[int]time.Duration{304: time.Hour},
+			},
+			want: want{
+				err: true,
+			},
+		},
+		{
+			name: "InvalidStatusCode",
+			args: args{
+				statuses: map[int]time.Duration{999: time.Hour},
+			},
+			want: want{
+				err: true,
+			},
+		},
+		{
+			name: "NegativeTTL",
+			args: args{
+				statuses: map[int]time.Duration{404: -time.Second},
+			},
+			want: want{
+				err: true,
+			},
+		},
+	}
+
+	p, err := New(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p.fileConfig.Cache.Statuses = tt.args.statuses
+
+			err := p.parseStatusTTLs()
+			if (err != nil) != tt.want.err {
+				t.Fatalf("Proxy.parseStatusTTLs() Unexpected error: %v", err)
+			}
+
+			if !tt.want.err && !reflect.DeepEqual(p.statusTTLs, tt.want.statusTTLs) {
+				t.Errorf("Proxy.parseStatusTTLs() == '%v', want '%v'", p.statusTTLs, tt.want.statusTTLs)
+			}
+		})
+	}
+}
+
 func TestProxy_parseHeadersRules(t *testing.T) {
 	type args struct {
 		action typeHeaderAction
@@ -702,6 +949,7 @@ func TestProxy_saveBackendResponse(t *testing.T) {
 	}
 
 	cacheKey := []byte("test")
+	host := []byte("www.kratgo.com")
 	path := []byte("/test/")
 	body := []byte("Test Body")
 	headers := map[string][]byte{
@@ -709,15 +957,20 @@ func TestProxy_saveBackendResponse(t *testing.T) {
 		"X-Data-2": []byte("2"),
 		"X-Data-3": []byte("3"),
 	}
-	entry := cache.AcquireEntry()
+	pt := p.acquireTools()
+	entry := pt.entry
 
-	resp := fasthttp.AcquireResponse()
-	resp.SetBody(body)
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.Set("Accept-Language", "es, en")
+	ctx.Response.SetBody(body)
 	for k, v := range headers {
-		resp.Header.SetCanonical([]byte(k), v)
+		ctx.Response.Header.SetCanonical([]byte(k), v)
 	}
+	ctx.Response.Header.Set("Vary", "accept-language")
 
-	err = p.saveBackendResponse(cacheKey, path, resp, entry)
+	ttl := int64(60)
+
+	err = p.saveBackendResponse(cacheKey, host, path, lifetime{ttl: ttl, grace: 30, keep: 120}, ctx, pt)
 	if err != nil {
 		t.Fatalf("Proxy.saveBackendResponse() returns err: %v", err)
 	}
@@ -737,6 +990,40 @@ func TestProxy_saveBackendResponse(t *testing.T) {
 		t.Fatalf("Proxy.saveBackendResponse() cache body == '%s', want '%s'", r.Body, body)
 	}
 
+	if r.TTL != ttl {
+		t.Errorf("Proxy.saveBackendResponse() cache ttl == '%d', want '%d'", r.TTL, ttl)
+	}
+
+	if r.Grace != 30 || r.Keep != 120 {
+		t.Errorf("Proxy.saveBackendResponse() cache grace and keep == '%d %d', want '%d %d'", r.Grace, r.Keep, 30, 120)
+	}
+
+	vary := []cache.ResponseHeader{{Key: []byte("Accept-Language"), Value: []byte("es,en")}}
+	if !reflect.DeepEqual(r.Vary, vary) {
+		t.Errorf("Proxy.saveBackendResponse() cache vary == '%s', want '%s'", r.Vary, vary)
+	}
+
+	if r.StoredAt == 0 {
+		t.Errorf("Proxy.saveBackendResponse() cache stored at time has not been set")
+	}
+
+	if keys := p.cache.HostKeysBytes(host); len(keys) != 1 || keys[0] != string(cacheKey) {
+		t.Errorf("Proxy.saveBackendResponse() host keys == '%v', want '%v'", keys, []string{string(cacheKey)})
+	}
+
+	p.cache.Reset()
+	entry.Reset()
+	ctx.Response.Header.Set("Vary", "*")
+
+	err = p.saveBackendResponse(cacheKey, host, path, lifetime{ttl: ttl, grace: 30, keep: 120}, ctx, pt)
+	if err != nil {
+		t.Fatalf("Proxy.saveBackendResponse() returns err: %v", err)
+	}
+
+	if p.cache.Len() > 0 {
+		t.Errorf("Proxy.saveBackendResponse() response with 'Vary: *' has been saved in cache")
+	}
+
 	for k, v := range headers {
 		for _, h := range r.Headers {
 			if string(h.Key) == k && bytes.Equal(h.Value, v) {
@@ -759,6 +1046,7 @@ func TestProxy_fetchFromBackend(t *testing.T) {
 		statusCode   int
 		noCacheRules []string
 		headersRules []config.Header
+		statuses     map[int]time.Duration
 
 		httpClientError              error
 		forceProcessHeaderRulesError bool
@@ -767,6 +1055,8 @@ func TestProxy_fetchFromBackend(t *testing.T) {
 
 	type want struct {
 		saveInCache bool
+		ttl         int64
+		statusCode  int
 		err         bool
 	}
 
@@ -794,6 +1084,58 @@ func TestProxy_fetchFromBackend(t *testing.T) {
 				err:         false,
 			},
 		},
+		{
+			name: "CacheControlMaxAge",
+			args: args{
+				cacheKey: []byte("test"),
+				path:     []byte("/test/"),
+				body:     []byte("Test Body"),
+				method:   []byte("GET"),
+				headers: map[string][]byte{
+					"Cache-Control": []byte("public, max-age=60"),
+				},
+				statusCode: 200,
+			},
+			want: want{
+				saveInCache: true,
+				ttl:         60,
+				err:         false,
+			},
+		},
+		{
+			name: "NoCacheByCacheControl",
+			args: args{
+				cacheKey: []byte("test"),
+				path:     []byte("/test/"),
+				body:     []byte("Test Body"),
+				method:   []byte("GET"),
+				headers: map[string][]byte{
+					"Cache-Control": []byte("private, max-age=60"),
+				},
+				statusCode: 200,
+			},
+			want: want{
+				saveInCache: false,
+				err:         false,
+			},
+		},
+		{
+			name: "NoCacheBySurrogateControl",
+			args: args{
+				cacheKey: []byte("test"),
+				path:     []byte("/test/"),
+				body:     []byte("Test Body"),
+				method:   []byte("GET"),
+				headers: map[string][]byte{
+					"Surrogate-Control": []byte("no-store"),
+				},
+				statusCode: 200,
+			},
+			want: want{
+				saveInCache: false,
+				err:         false,
+			},
+		},
 		{
 			name: "StatusRedirect",
 			args: args{
@@ -811,6 +1153,63 @@ func TestProxy_fetchFromBackend(t *testing.T) {
 				err:         false,
 			},
 		},
+		{
+			name: "StatusRedirectByStatuses",
+			args: args{
+				cacheKey: []byte("test"),
+				path:     []byte("/test/"),
+				body:     []byte("Test Body"),
+				method:   []byte("GET"),
+				headers: map[string][]byte{
+					headerLocation: []byte("http://www.kratgo.com"),
+				},
+				statusCode: 301,
+				statuses:   map[int]time.Duration{301: time.Hour},
+			},
+			want: want{
+				saveInCache: true,
+				ttl:         3600,
+				statusCode:  301,
+				err:         false,
+			},
+		},
+		{
+			name: "NegativeCachingByStatuses",
+			args: args{
+				cacheKey:   []byte("test"),
+				path:       []byte("/test/"),
+				body:       []byte("Not Found"),
+				method:     []byte("GET"),
+				statusCode: 404,
+				statuses:   map[int]time.Duration{404: 30 * time.Second, 410: 30 * time.Second},
+			},
+			want: want{
+				saveInCache: true,
+				ttl:         30,
+				statusCode:  404,
+				err:         false,
+			},
+		},
+		{
+			name: "StatusExplicitLifetimeOverStatuses",
+			args: args{
+				cacheKey: []byte("test"),
+				path:     []byte("/test/"),
+				body:     []byte("Gone"),
+				method:   []byte("GET"),
+				headers: map[string][]byte{
+					"Cache-Control": []byte("max-age=5"),
+				},
+				statusCode: 410,
+				statuses:   map[int]time.Duration{410: 30 * time.Second},
+			},
+			want: want{
+				saveInCache: true,
+				ttl:         5,
+				statusCode:  410,
+				err:         false,
+			},
+		},
 		{
 			name: "NoCacheByRule",
 			args: args{
@@ -915,6 +1314,7 @@ func TestProxy_fetchFromBackend(t *testing.T) {
 			cfg := testConfig()
 			cfg.FileConfig.Nocache = tt.args.noCacheRules
 			cfg.FileConfig.Response.Headers.Set = tt.args.headersRules
+			cfg.FileConfig.Cache.Statuses = tt.args.statuses
 
 			p, err := New(cfg)
 			if err != nil {
@@ -930,15 +1330,14 @@ func TestProxy_fetchFromBackend(t *testing.T) {
 			}
 
 			p.fileConfig.Nocache = tt.args.noCacheRules
-			p.backends = []fetcher{
+			p.backends = newTestBackends(
 				&mockBackend{
 					body:       tt.args.body,
 					statusCode: tt.args.statusCode,
 					headers:    tt.args.headers,
 					err:        tt.args.httpClientError,
 				},
-			}
-			p.totalBackends = len(tt.args.noCacheRules)
+			)
 
 			pt := p.acquireTools()
 			entry := cache.AcquireEntry()
@@ -950,7 +1349,7 @@ func TestProxy_fetchFromBackend(t *testing.T) {
 				ctx.Request.Header.SetCanonical([]byte(k), v)
 			}
 
-			err = p.fetchFromBackend(tt.args.cacheKey, tt.args.path, ctx, pt)
+			err = p.fetchFromBackend(tt.args.cacheKey, ctx.Host(), tt.args.path, ctx, pt)
 			if (err != nil) != tt.want.err {
 				t.Errorf("Proxy.fetchFromBackend() Unexpected error: %v", err)
 			}
@@ -968,12 +1367,24 @@ func TestProxy_fetchFromBackend(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			if tt.want.saveInCache {
+			if !tt.want.saveInCache {
+				if r := entry.GetResponse(tt.args.path); r != nil {
+					t.Errorf("Proxy.fetchFromBackend() path '%s' has been saved in cache", tt.args.path)
+				}
+			} else {
 				r := entry.GetResponse(tt.args.path)
 				if r == nil {
 					t.Fatalf("Proxy.saveBackendResponse() path '%s' not found in cache", tt.args.path)
 				}
 
+				if r.TTL != tt.want.ttl {
+					t.Errorf("Proxy.fetchFromBackend() cache ttl == '%d', want '%d'", r.TTL, tt.want.ttl)
+				}
+
+				if statusCode := responseStatusCode(r); tt.want.statusCode != 0 && statusCode != tt.want.statusCode {
+					t.Errorf("Proxy.fetchFromBackend() cache status code == '%d', want '%d'", statusCode, tt.want.statusCode)
+				}
+
 				if !bytes.Equal(r.Body, tt.args.body) {
 					t.Fatalf("Proxy.saveBackendResponse() cache body == '%s', want '%s'", r.Body, tt.args.body)
 				}
@@ -998,7 +1409,10 @@ func TestProxy_handler(t *testing.T) {
 		host         []byte
 		path         []byte
 		headers      []cache.ResponseHeader
+		reqHeaders   map[string]string
 		cachePath    []byte
+		cacheVary    []cache.ResponseHeader
+		cacheExpired bool
 		noCacheRules []string
 
 		forceProcessHeaderRulesError bool
@@ -1032,6 +1446,54 @@ func TestProxy_handler(t *testing.T) {
 				err:            false,
 			},
 		},
+		{
+			name: "ResponseFromCacheVariant",
+			args: args{
+				host:       []byte("www.kratgo.com"),
+				path:       []byte("/test/"),
+				reqHeaders: map[string]string{"Accept-Language": "ES"},
+				cachePath:  []byte("/test/"),
+				cacheVary: []cache.ResponseHeader{
+					{Key: []byte("Accept-Language"), Value: []byte("es")},
+				},
+			},
+			want: want{
+				getFromCache:   true,
+				getFromBackend: false,
+				err:            false,
+			},
+		},
+		{
+			name: "ResponseFromCacheVariantNotFound",
+			args: args{
+				host:       []byte("www.kratgo.com"),
+				path:       []byte("/test/"),
+				reqHeaders: map[string]string{"Accept-Language": "en"},
+				cachePath:  []byte("/test/"),
+				cacheVary: []cache.ResponseHeader{
+					{Key: []byte("Accept-Language"), Value: []byte("es")},
+				},
+			},
+			want: want{
+				getFromCache:   true,
+				getFromBackend: true,
+				err:            false,
+			},
+		},
+		{
+			name: "ResponseFromCacheExpired",
+			args: args{
+				host:         []byte("www.kratgo.com"),
+				path:         []byte("/test/"),
+				cachePath:    []byte("/test/"),
+				cacheExpired: true,
+			},
+			want: want{
+				getFromCache:   true,
+				getFromBackend: true,
+				err:            false,
+			},
+		},
 		{
 			name: "ResponseFromCacheNotFound",
 			args: args{
@@ -1114,6 +1576,9 @@ func TestProxy_handler(t *testing.T) {
 			ctx := new(fasthttp.RequestCtx)
 			ctx.Request.SetRequestURIBytes(tt.args.path)
 			ctx.Request.Header.SetHostBytes(tt.args.host)
+			for k, v := range tt.args.reqHeaders {
+				ctx.Request.Header.Set(k, v)
+			}
 
 			entry := cache.AcquireEntry()
 			response := cache.AcquireResponse()
@@ -1121,15 +1586,21 @@ func TestProxy_handler(t *testing.T) {
 			for _, h := range tt.args.headers {
 				response.SetHeader(h.Key, h.Value)
 			}
+			for _, h := range tt.args.cacheVary {
+				response.SetVary(h.Key, h.Value)
+			}
+			if tt.args.cacheExpired {
+				response.StoredAt = time.Now().Add(-2 * time.Minute).Unix()
+				response.TTL = 60
+			}
 			entry.SetResponse(*response)
-			p.cache.SetBytes(tt.args.host, *entry)
+			p.cache.SetBytes(append(append(append([]byte{}, tt.args.host...), tt.args.cachePath...), '?'), *entry)
 
 			httpClientMock := &mockBackend{
 				statusCode: 200,
 				err:        tt.args.httpClientError,
 			}
-			p.backends = []fetcher{httpClientMock}
-			p.totalBackends = len(p.backends)
+			p.backends = newTestBackends(httpClientMock)
 
 			p.handler(ctx)
 
@@ -1165,6 +1636,290 @@ func TestProxy_handler(t *testing.T) {
 	}
 }
 
+func testStaleProxy(t *testing.T, grace, keep int64) (*Proxy, *fasthttp.RequestCtx) {
+	p, err := New(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.SetRequestURI("/stale/")
+	ctx.Request.Header.SetHost("www.kratgo.com")
+
+	response := cache.AcquireResponse()
+	response.Host = []byte("www.kratgo.com")
+	response.Path = []byte("/stale/")
+	response.Body = []byte("Stale")
+	response.StoredAt = time.Now().Add(-2 * time.Minute).Unix()
+	response.TTL = 60
+	response.Grace = grace
+	response.Keep = keep
+
+	entry := cache.AcquireEntry()
+	entry.SetResponse(*response)
+	p.cache.Set("www.kratgo.com/stale/?", *entry)
+
+	return p, ctx
+}
+
+func TestProxy_handlerStaleWhileRevalidate(t *testing.T) {
+	p, ctx := testStaleProxy(t, 3600, 0)
+
+	backend := &slowBackend{
+		delay: 50 * time.Millisecond,
+		body:  []byte("Fresh"),
+	}
+	p.backends = newTestBackends(backend)
+
+	for i := 0; i < 3; i++ {
+		ctx.Response.Reset()
+		p.handler(ctx)
+
+		if body := string(ctx.Response.Body()); body != "Stale" {
+			t.Fatalf("Proxy.handler() body == '%s', want '%s'", body, "Stale")
+		}
+	}
+
+	entry := cache.AcquireEntry()
+	for i := 0; i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+
+		entry.Reset()
+		p.cache.Get("www.kratgo.com/stale/?", entry)
+
+		if r := entry.GetResponse([]byte("/stale/")); r != nil && string(r.Body) == "Fresh" {
+			break
+		}
+	}
+
+	if r := entry.GetResponse([]byte("/stale/")); r == nil || string(r.Body) != "Fresh" {
+		t.Errorf("Proxy.handler() the stale response has not been revalidated in background")
+	}
+
+	if calls := atomic.LoadInt32(&backend.calls); calls != 1 {
+		t.Errorf("Proxy.handler() backend calls == '%d', want '%d'", calls, 1)
+	}
+}
+
+func TestProxy_handlerStaleIfError(t *testing.T) {
+	type args struct {
+		keep            int64
+		statusCode      int
+		httpClientError error
+	}
+
+	type want struct {
+		body       string
+		statusCode int
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "BackendError",
+			args: args{
+				keep:            3600,
+				statusCode:      fasthttp.StatusOK,
+				httpClientError: errors.New("Connection refused"),
+			},
+			want: want{
+				body:       "Stale",
+				statusCode: fasthttp.StatusOK,
+			},
+		},
+		{
+			name: "BackendServerError",
+			args: args{
+				keep:       3600,
+				statusCode: fasthttp.StatusServiceUnavailable,
+			},
+			want: want{
+				body:       "Stale",
+				statusCode: fasthttp.StatusOK,
+			},
+		},
+		{
+			name: "BackendOk",
+			args: args{
+				keep:       3600,
+				statusCode: fasthttp.StatusOK,
+			},
+			want: want{
+				body:       "Fresh",
+				statusCode: fasthttp.StatusOK,
+			},
+		},
+		{
+			name: "OutOfKeep",
+			args: args{
+				keep:            30,
+				statusCode:      fasthttp.StatusOK,
+				httpClientError: errors.New("Connection refused"),
+			},
+			want: want{
+				body:       "Could not fetch response from backend: Connection refused",
+				statusCode: fasthttp.StatusInternalServerError,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, ctx := testStaleProxy(t, 0, tt.args.keep)
+
+			p.backends = newTestBackends(
+				&mockBackend{
+					body:       []byte("Fresh"),
+					statusCode: tt.args.statusCode,
+					err:        tt.args.httpClientError,
+				},
+			)
+
+			p.handler(ctx)
+
+			if statusCode := ctx.Response.StatusCode(); statusCode != tt.want.statusCode {
+				t.Errorf("Proxy.handler() status code == '%d', want '%d'", statusCode, tt.want.statusCode)
+			}
+
+			if body := string(ctx.Response.Body()); body != tt.want.body {
+				t.Errorf("Proxy.handler() body == '%s', want '%s'", body, tt.want.body)
+			}
+		})
+	}
+}
+
+func TestProxy_handlerStatusCodes(t *testing.T) {
+	cfg := testConfig()
+	cfg.FileConfig.Cache.Statuses = map[int]time.Duration{404: 30 * time.Second}
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := &mockBackend{
+		body:       []byte("Not Found"),
+		statusCode: fasthttp.StatusNotFound,
+		headers: map[string][]byte{
+			"Etag": []byte("\"v1\""),
+		},
+	}
+	p.backends = newTestBackends(backend)
+
+	for i := 0; i < 2; i++ {
+		backend.called = false
+
+		ctx := new(fasthttp.RequestCtx)
+		ctx.Request.SetRequestURI("/missing/")
+		ctx.Request.Header.SetHost("www.kratgo.com")
+		ctx.Request.Header.Set(fasthttp.HeaderIfNoneMatch, "\"v1\"")
+
+		p.handler(ctx)
+
+		if statusCode := ctx.Response.StatusCode(); statusCode != fasthttp.StatusNotFound {
+			t.Errorf("Proxy.handler() status code == '%d', want '%d'", statusCode, fasthttp.StatusNotFound)
+		}
+
+		if body := string(ctx.Response.Body()); body != "Not Found" {
+			t.Errorf("Proxy.handler() body == '%s', want '%s'", body, "Not Found")
+		}
+
+		if wantCalled := i == 0; backend.called != wantCalled {
+			t.Errorf("Proxy.handler() backend called == '%v', want '%v'", backend.called, wantCalled)
+		}
+	}
+
+	entry := cache.AcquireEntry()
+	if err := p.cache.Get("www.kratgo.com/missing/?", entry); err != nil {
+		t.Fatal(err)
+	}
+
+	r := entry.GetResponse([]byte("/missing/"))
+	if r == nil {
+		t.Fatal("Proxy.handler() response not found in cache")
+	}
+
+	if r.StatusCode != fasthttp.StatusNotFound || r.TTL != 30 {
+		t.Errorf("Proxy.handler() cache status code and ttl == '%d %d', want '%d %d'", r.StatusCode, r.TTL, fasthttp.StatusNotFound, 30)
+	}
+
+	cache.ReleaseEntry(entry)
+}
+
+func TestProxy_Stats(t *testing.T) {
+	cfg := testConfig()
+	cfg.FileConfig.Nocache = []string{"$(method) == 'POST'"}
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := &mockBackend{
+		body:       []byte("Kratgo body"),
+		statusCode: fasthttp.StatusOK,
+		headers:    map[string][]byte{fasthttp.HeaderCacheControl: []byte("max-age=60")},
+	}
+	p.backends = newTestBackends(backend)
+
+	requests := []struct {
+		method string
+		host   string
+		err    error
+	}{
+		{method: fasthttp.MethodGet, host: "www.kratgo.com"},
+		{method: fasthttp.MethodGet, host: "www.kratgo.com"},
+		{method: fasthttp.MethodGet, host: "www.kratgo.com"},
+		{method: fasthttp.MethodPost, host: "www.kratgo.com"},
+		{method: fasthttp.MethodGet, host: "www.example.com", err: errors.New("Test error")},
+	}
+
+	for _, r := range requests {
+		backend.err = r.err
+
+		ctx := new(fasthttp.RequestCtx)
+		ctx.Request.Header.SetMethod(r.method)
+		ctx.Request.SetRequestURI("/stats/")
+		ctx.Request.Header.SetHost(r.host)
+
+		p.handler(ctx)
+	}
+
+	stats := p.Stats()
+	wantStats := Stats{
+		Hits:            2,
+		Misses:          2,
+		BackendFetches:  3,
+		BackendErrors:   1,
+		NocacheBypasses: 1,
+		Hosts: map[string]HostStats{
+			"www.kratgo.com":  {Hits: 2, Misses: 1, HitRatio: 2.0 / 3},
+			"www.example.com": {Misses: 1},
+		},
+		Admission: []AdmissionStats{},
+	}
+
+	if !reflect.DeepEqual(stats, wantStats) {
+		t.Errorf("Proxy.Stats() == '%+v', want '%+v'", stats, wantStats)
+	}
+
+	// The hosts over the max have not their own stats
+	for i := len(stats.Hosts); i < maxStatsHosts; i++ {
+		p.getHostStats([]byte(fmt.Sprintf("www.kratgo%d.com", i)))
+	}
+
+	if hs := p.getHostStats([]byte("www.other.com")); hs != nil {
+		t.Errorf("Proxy.getHostStats() == '%v', want '%v'", hs, nil)
+	}
+
+	if hs := p.getHostStats([]byte("www.kratgo.com")); hs == nil {
+		t.Error("Proxy.getHostStats() returns nil for a known host")
+	}
+}
+
 func TestProxy_ListenAndServe(t *testing.T) {
 	serverMock := new(mockServer)
 	addr := "localhost:9999"
@@ -1195,7 +1950,7 @@ func BenchmarkHandler(b *testing.B) {
 	if err != nil {
 		b.Fatal(err)
 	}
-	p.backends = []fetcher{
+	p.backends = newTestBackends(
 		&mockBackend{
 			body:       []byte("Benchmark Response Body"),
 			statusCode: 200,
@@ -1203,8 +1958,7 @@ func BenchmarkHandler(b *testing.B) {
 				"X-Data": []byte("Kratgo"),
 			},
 		},
-	}
-	p.totalBackends = len(p.backends)
+	)
 
 	ctx := new(fasthttp.RequestCtx)
 	ctx.Request.SetRequestURI("/bench")
@@ -1231,7 +1985,7 @@ func BenchmarkHandlerWithoutCache(b *testing.B) {
 		b.Fatal(err)
 	}
 
-	p.backends = []fetcher{
+	p.backends = newTestBackends(
 		&mockBackend{
 			body:       []byte("Benchmark Response Body"),
 			statusCode: 200,
@@ -1239,8 +1993,7 @@ func BenchmarkHandlerWithoutCache(b *testing.B) {
 				"X-Data": []byte("Kratgo"),
 			},
 		},
-	}
-	p.totalBackends = len(p.backends)
+	)
 
 	ctx := new(fasthttp.RequestCtx)
 	ctx.Request.SetRequestURI(path)
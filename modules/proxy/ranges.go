@@ -0,0 +1,200 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+
+	gstrconv "github.com/savsgio/gotils/strconv"
+	"github.com/valyala/fasthttp"
+)
+
+// parseByteRange parses a byte range spec ("first-last", "first-" or "-suffix") of a body with the given size.
+// Returns ok false if the spec is invalid, and satisfiable false if it's valid but out of the body.
+func parseByteRange(spec []byte, size int) (br byteRange, ok, satisfiable bool) {
+	i := bytes.IndexByte(spec, '-')
+	if i < 0 {
+		return br, false, false
+	}
+
+	first, last := bytes.TrimSpace(spec[:i]), bytes.TrimSpace(spec[i+1:])
+
+	if len(first) == 0 {
+		suffix, err := strconv.Atoi(gstrconv.B2S(last))
+		if err != nil || suffix < 0 {
+			return br, false, false
+		} else if suffix == 0 || size == 0 {
+			return br, true, false
+		}
+
+		if suffix > size {
+			suffix = size
+		}
+
+		return byteRange{start: size - suffix, end: size - 1}, true, true
+	}
+
+	start, err := strconv.Atoi(gstrconv.B2S(first))
+	if err != nil || start < 0 {
+		return br, false, false
+	}
+
+	end := size - 1
+
+	if len(last) > 0 {
+		if end, err = strconv.Atoi(gstrconv.B2S(last)); err != nil || end < start {
+			return br, false, false
+		}
+
+		if end >= size {
+			end = size - 1
+		}
+	}
+
+	if start >= size {
+		return br, true, false
+	}
+
+	return byteRange{start: start, end: end}, true, true
+}
+
+// appendByteRanges appends to dst the satisfiable ranges of the Range header value for a body with the given size.
+// Returns ok false if the value is invalid or has too many ranges, so it must be ignored.
+func appendByteRanges(dst []byteRange, value []byte, size int) ([]byteRange, bool) {
+	if !bytes.HasPrefix(value, byteRangesPrefix) {
+		return dst, false
+	}
+
+	specs := bytes.Split(value[len(byteRangesPrefix):], []byte(","))
+	if len(specs) > maxByteRanges {
+		return dst, false
+	}
+
+	for _, spec := range specs {
+		br, ok, satisfiable := parseByteRange(bytes.TrimSpace(spec), size)
+		if !ok {
+			return dst, false
+		} else if satisfiable {
+			dst = append(dst, br)
+		}
+	}
+
+	return dst, true
+}
+
+// ifRangeMatch returns true if the If-Range value matches the validator of the response,
+// so the range could be served. Entity tags must be strong and equal, and dates exactly equal.
+func ifRangeMatch(ifRange []byte, h *fasthttp.ResponseHeader) bool {
+	if bytes.HasPrefix(ifRange, []byte("\"")) || isWeakETag(ifRange) {
+		etag := h.PeekBytes(headerETag)
+
+		return !isWeakETag(ifRange) && !isWeakETag(etag) && bytes.Equal(ifRange, etag)
+	}
+
+	lastModified := h.PeekBytes(headerLastModified)
+
+	return len(lastModified) > 0 && bytes.Equal(ifRange, lastModified)
+}
+
+// appendContentRange appends to dst the Content-Range value of the range.
+func appendContentRange(dst []byte, br byteRange, size int) []byte {
+	dst = append(dst, byteRangesUnit...)
+	dst = append(dst, ' ')
+	dst = strconv.AppendInt(dst, int64(br.start), 10)
+	dst = append(dst, '-')
+	dst = strconv.AppendInt(dst, int64(br.end), 10)
+	dst = append(dst, '/')
+
+	return strconv.AppendInt(dst, int64(size), 10)
+}
+
+// appendUnsatisfiedContentRange appends to dst the Content-Range value of a 416 response.
+func appendUnsatisfiedContentRange(dst []byte, size int) []byte {
+	dst = append(dst, byteRangesUnit...)
+	dst = append(dst, " */"...)
+
+	return strconv.AppendInt(dst, int64(size), 10)
+}
+
+// appendMultipartByteRanges appends to dst the multipart/byteranges body with the ranges of the body.
+func appendMultipartByteRanges(dst []byte, boundary, contentType, body []byte, ranges []byteRange) []byte {
+	for _, br := range ranges {
+		dst = append(dst, "--"...)
+		dst = append(dst, boundary...)
+		dst = append(dst, "\r\n"...)
+		dst = append(dst, fasthttp.HeaderContentType...)
+		dst = append(dst, ": "...)
+		dst = append(dst, contentType...)
+		dst = append(dst, "\r\n"...)
+		dst = append(dst, fasthttp.HeaderContentRange...)
+		dst = append(dst, ": "...)
+		dst = appendContentRange(dst, br, len(body))
+		dst = append(dst, "\r\n\r\n"...)
+		dst = append(dst, body[br.start:br.end+1]...)
+		dst = append(dst, "\r\n"...)
+	}
+
+	dst = append(dst, "--"...)
+	dst = append(dst, boundary...)
+
+	return append(dst, "--\r\n"...)
+}
+
+// newMultipartBoundary returns a random boundary for a multipart body.
+func newMultipartBoundary() []byte {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return []byte(hex.EncodeToString(b))
+}
+
+// writeByteRanges answers the Range request header of the client with the requested parts of the full response.
+// It's a no-op if the response is not a 200 OK or it's streamed, the request has not a Range header,
+// or it must be ignored.
+func writeByteRanges(ctx *fasthttp.RequestCtx, pt *proxyTools) {
+	resp := &ctx.Response
+	if resp.StatusCode() != fasthttp.StatusOK || resp.IsBodyStream() {
+		return
+	}
+
+	resp.Header.SetBytesV(fasthttp.HeaderAcceptRanges, byteRangesUnit)
+
+	if len(pt.ranges) == 0 || (len(pt.ifRange) > 0 && !ifRangeMatch(pt.ifRange, &resp.Header)) {
+		return
+	}
+
+	body := resp.Body()
+	size := len(body)
+
+	ranges, ok := appendByteRanges(pt.byteRanges[:0], pt.ranges, size)
+	pt.byteRanges = ranges
+
+	if !ok {
+		return
+	}
+
+	switch len(ranges) {
+	case 0:
+		resp.ResetBody()
+		resp.SetStatusCode(fasthttp.StatusRequestedRangeNotSatisfiable)
+		resp.Header.Del(fasthttp.HeaderContentEncoding)
+		resp.Header.SetBytesV(fasthttp.HeaderContentRange, appendUnsatisfiedContentRange(nil, size))
+
+	case 1:
+		br := ranges[0]
+		part := append([]byte(nil), body[br.start:br.end+1]...)
+
+		resp.SetStatusCode(fasthttp.StatusPartialContent)
+		resp.Header.SetBytesV(fasthttp.HeaderContentRange, appendContentRange(nil, br, size))
+		resp.SetBody(part)
+
+	default:
+		boundary := newMultipartBoundary()
+		multipart := appendMultipartByteRanges(nil, boundary, resp.Header.ContentType(), body, ranges)
+
+		resp.SetStatusCode(fasthttp.StatusPartialContent)
+		resp.Header.SetContentType(multipartByteRangesContentType + string(boundary))
+		resp.SetBody(multipart)
+	}
+}
@@ -0,0 +1,270 @@
+package proxy
+
+import (
+	"bytes"
+	"mime"
+	"mime/multipart"
+	"reflect"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func Test_appendByteRanges(t *testing.T) {
+	type args struct {
+		value string
+		size  int
+	}
+
+	type want struct {
+		ranges []byteRange
+		ok     bool
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Range",
+			args: args{value: "bytes=0-9", size: 100},
+			want: want{ranges: []byteRange{{start: 0, end: 9}}, ok: true},
+		},
+		{
+			name: "OpenRange",
+			args: args{value: "bytes=90-", size: 100},
+			want: want{ranges: []byteRange{{start: 90, end: 99}}, ok: true},
+		},
+		{
+			name: "SuffixRange",
+			args: args{value: "bytes=-10", size: 100},
+			want: want{ranges: []byteRange{{start: 90, end: 99}}, ok: true},
+		},
+		{
+			name: "SuffixGreaterThanSize",
+			args: args{value: "bytes=-200", size: 100},
+			want: want{ranges: []byteRange{{start: 0, end: 99}}, ok: true},
+		},
+		{
+			name: "EndGreaterThanSize",
+			args: args{value: "bytes=50-200", size: 100},
+			want: want{ranges: []byteRange{{start: 50, end: 99}}, ok: true},
+		},
+		{
+			name: "MultipleRanges",
+			args: args{value: "bytes=0-9, 200-300, 20-29", size: 100},
+			want: want{ranges: []byteRange{{start: 0, end: 9}, {start: 20, end: 29}}, ok: true},
+		},
+		{
+			name: "Unsatisfiable",
+			args: args{value: "bytes=100-", size: 100},
+			want: want{ok: true},
+		},
+		{
+			name: "OtherUnit",
+			args: args{value: "items=0-9", size: 100},
+			want: want{ok: false},
+		},
+		{
+			name: "Invalid",
+			args: args{value: "bytes=9-0", size: 100},
+			want: want{ok: false},
+		},
+		{
+			name: "TooManyRanges",
+			args: args{value: "bytes=0-1,2-3,4-5,6-7,8-9,10-11,12-13,14-15,16-17,18-19,20-21,22-23,24-25,26-27,28-29,30-31,32-33", size: 100},
+			want: want{ok: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranges, ok := appendByteRanges(nil, []byte(tt.args.value), tt.args.size)
+			if ok != tt.want.ok {
+				t.Fatalf("appendByteRanges() ok == '%v', want '%v'", ok, tt.want.ok)
+			}
+
+			if ok && !reflect.DeepEqual(ranges, tt.want.ranges) {
+				t.Errorf("appendByteRanges() == '%v', want '%v'", ranges, tt.want.ranges)
+			}
+		})
+	}
+}
+
+func Test_ifRangeMatch(t *testing.T) {
+	h := new(fasthttp.ResponseHeader)
+	h.Set(fasthttp.HeaderETag, "\"v1\"")
+	h.Set(fasthttp.HeaderLastModified, "Wed, 21 Oct 2015 07:28:00 GMT")
+
+	tests := []struct {
+		ifRange string
+		want    bool
+	}{
+		{ifRange: "\"v1\"", want: true},
+		{ifRange: "\"v0\"", want: false},
+		{ifRange: "W/\"v1\"", want: false},
+		{ifRange: "Wed, 21 Oct 2015 07:28:00 GMT", want: true},
+		{ifRange: "Thu, 22 Oct 2015 07:28:00 GMT", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ifRange, func(t *testing.T) {
+			if got := ifRangeMatch([]byte(tt.ifRange), h); got != tt.want {
+				t.Errorf("ifRangeMatch() == '%v', want '%v'", got, tt.want)
+			}
+		})
+	}
+
+	h.Set(fasthttp.HeaderETag, "W/\"v1\"")
+	if ifRangeMatch([]byte("\"v1\""), h) {
+		t.Errorf("ifRangeMatch() matches a weak entity tag")
+	}
+}
+
+type rangesBackend struct {
+	slowBackend
+
+	ranges int32
+}
+
+func (mock *rangesBackend) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	if len(req.Header.Peek(fasthttp.HeaderRange)) > 0 {
+		mock.ranges++
+	}
+
+	return mock.slowBackend.Do(req, resp)
+}
+
+func TestProxy_handlerByteRanges(t *testing.T) {
+	p, err := New(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+
+	backend := &rangesBackend{
+		slowBackend: slowBackend{
+			body: body,
+			headers: map[string]string{
+				fasthttp.HeaderContentType:  "application/pdf",
+				fasthttp.HeaderCacheControl: "max-age=60",
+				fasthttp.HeaderETag:         "\"v1\"",
+			},
+		},
+	}
+	p.backends = newTestBackends(backend)
+
+	newCtx := func(ranges, ifRange string) *fasthttp.RequestCtx {
+		ctx := new(fasthttp.RequestCtx)
+		ctx.Request.SetRequestURI("/file.pdf")
+		ctx.Request.Header.SetHost("www.kratgo.com")
+		ctx.Request.Header.Set(fasthttp.HeaderRange, ranges)
+		if ifRange != "" {
+			ctx.Request.Header.Set(fasthttp.HeaderIfRange, ifRange)
+		}
+
+		return ctx
+	}
+
+	// Cache miss
+	ctx := newCtx("bytes=0-9", "")
+	p.handler(ctx)
+
+	if statusCode := ctx.Response.StatusCode(); statusCode != fasthttp.StatusPartialContent {
+		t.Fatalf("Proxy.handler() status code == '%d', want '%d'", statusCode, fasthttp.StatusPartialContent)
+	}
+
+	if v := string(ctx.Response.Body()); v != "0123456789" {
+		t.Errorf("Proxy.handler() body == '%s', want '%s'", v, "0123456789")
+	}
+
+	if v := string(ctx.Response.Header.Peek(fasthttp.HeaderContentRange)); v != "bytes 0-9/36" {
+		t.Errorf("Proxy.handler() Content-Range == '%s', want '%s'", v, "bytes 0-9/36")
+	}
+
+	// Cache hit
+	ctx = newCtx("bytes=-6", "\"v1\"")
+	p.handler(ctx)
+
+	if statusCode := ctx.Response.StatusCode(); statusCode != fasthttp.StatusPartialContent {
+		t.Fatalf("Proxy.handler() status code == '%d', want '%d'", statusCode, fasthttp.StatusPartialContent)
+	}
+
+	if v := string(ctx.Response.Body()); v != "uvwxyz" {
+		t.Errorf("Proxy.handler() body == '%s', want '%s'", v, "uvwxyz")
+	}
+
+	// Multiple ranges
+	ctx = newCtx("bytes=0-1, 10-12", "")
+	p.handler(ctx)
+
+	if statusCode := ctx.Response.StatusCode(); statusCode != fasthttp.StatusPartialContent {
+		t.Fatalf("Proxy.handler() status code == '%d', want '%d'", statusCode, fasthttp.StatusPartialContent)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(string(ctx.Response.Header.ContentType()))
+	if err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("Proxy.handler() Content-Type == '%s', want '%s'", ctx.Response.Header.ContentType(), "multipart/byteranges")
+	}
+
+	mr := multipart.NewReader(bytes.NewReader(ctx.Response.Body()), params["boundary"])
+	wantParts := []struct {
+		contentRange string
+		body         string
+	}{
+		{contentRange: "bytes 0-1/36", body: "01"},
+		{contentRange: "bytes 10-12/36", body: "abc"},
+	}
+
+	for _, want := range wantParts {
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatalf("Proxy.handler() could not read multipart: %v", err)
+		}
+
+		partBody := new(bytes.Buffer)
+		partBody.ReadFrom(part)
+
+		if v := part.Header.Get(fasthttp.HeaderContentRange); v != want.contentRange {
+			t.Errorf("Proxy.handler() part Content-Range == '%s', want '%s'", v, want.contentRange)
+		}
+
+		if v := part.Header.Get(fasthttp.HeaderContentType); v != "application/pdf" {
+			t.Errorf("Proxy.handler() part Content-Type == '%s', want '%s'", v, "application/pdf")
+		}
+
+		if partBody.String() != want.body {
+			t.Errorf("Proxy.handler() part body == '%s', want '%s'", partBody.String(), want.body)
+		}
+	}
+
+	// Unsatisfiable
+	ctx = newCtx("bytes=100-", "")
+	p.handler(ctx)
+
+	if statusCode := ctx.Response.StatusCode(); statusCode != fasthttp.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("Proxy.handler() status code == '%d', want '%d'", statusCode, fasthttp.StatusRequestedRangeNotSatisfiable)
+	}
+
+	if v := string(ctx.Response.Header.Peek(fasthttp.HeaderContentRange)); v != "bytes */36" {
+		t.Errorf("Proxy.handler() Content-Range == '%s', want '%s'", v, "bytes */36")
+	}
+
+	// If-Range mismatch
+	ctx = newCtx("bytes=0-9", "\"v0\"")
+	p.handler(ctx)
+
+	if statusCode := ctx.Response.StatusCode(); statusCode != fasthttp.StatusOK {
+		t.Errorf("Proxy.handler() status code == '%d', want '%d'", statusCode, fasthttp.StatusOK)
+	}
+
+	if v := ctx.Response.Body(); !bytes.Equal(v, body) {
+		t.Errorf("Proxy.handler() body == '%s', want '%s'", v, body)
+	}
+
+	if backend.calls != 1 || backend.ranges != 0 {
+		t.Errorf("Proxy.handler() backend calls and range requests == '%d %d', want '%d %d'", backend.calls, backend.ranges, 1, 0)
+	}
+}
@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func newRetryBudget(ratio float64, minRetries int) *retryBudget {
+	return &retryBudget{ratio: ratio, minRetries: minRetries}
+}
+
+func (rb *retryBudget) resetWindowWithoutLock(now time.Time) {
+	if now.Before(rb.windowStart.Add(retryBudgetWindow)) {
+		return
+	}
+
+	rb.windowStart = now
+	rb.requests = 0
+	rb.retries = 0
+}
+
+func (rb *retryBudget) addRequest(now time.Time) {
+	rb.mu.Lock()
+	rb.resetWindowWithoutLock(now)
+	rb.requests++
+	rb.mu.Unlock()
+}
+
+// allowRetry returns true and counts the retry if it's under the budget of the current window.
+func (rb *retryBudget) allowRetry(now time.Time) bool {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	rb.resetWindowWithoutLock(now)
+
+	if rb.retries >= rb.minRetries && float64(rb.retries+1) > rb.ratio*float64(rb.requests) {
+		return false
+	}
+
+	rb.retries++
+
+	return true
+}
+
+func (p *Proxy) parseRetry() error {
+	retry := p.fileConfig.Retry
+
+	switch {
+	case retry.Attempts < 0:
+		return fmt.Errorf("The attempts of the retries must be greater than or equal to 0")
+	case retry.Budget < 0:
+		return fmt.Errorf("The budget of the retries must be greater than or equal to 0")
+	case retry.MinRetries < 0:
+		return fmt.Errorf("The min retries must be greater than or equal to 0")
+	}
+
+	for _, statusCode := range retry.Statuses {
+		if statusCode < fasthttp.StatusInternalServerError || statusCode > 599 {
+			return fmt.Errorf("Invalid retry status code '%d', only the 5xx are allowed", statusCode)
+		}
+	}
+
+	// The retries are disabled with a single attempt
+	if retry.Attempts <= 1 {
+		return nil
+	}
+
+	if retry.Budget == 0 {
+		retry.Budget = defaultRetryBudget
+	}
+
+	if retry.MinRetries == 0 {
+		retry.MinRetries = defaultRetryMinRetries
+	}
+
+	p.retry = retry
+	p.retryBudget = newRetryBudget(retry.Budget, retry.MinRetries)
+
+	return nil
+}
+
+// shouldRetry returns true if the request failed with an error or with one of the retry status codes.
+func (p *Proxy) shouldRetry(err error, resp *fasthttp.Response) bool {
+	return err != nil || intSliceInclude(p.retry.Statuses, resp.StatusCode())
+}
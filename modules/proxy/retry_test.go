@@ -0,0 +1,312 @@
+package proxy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/savsgio/kratgo/modules/config"
+	"github.com/valyala/fasthttp"
+)
+
+func Test_retryBudget(t *testing.T) {
+	now := time.Now()
+	rb := newRetryBudget(0.5, 2)
+
+	// The min retries are allowed without requests
+	for i := 0; i < 2; i++ {
+		if !rb.allowRetry(now) {
+			t.Errorf("retryBudget.allowRetry() retry %d == '%v', want '%v'", i, false, true)
+		}
+	}
+
+	if rb.allowRetry(now) {
+		t.Errorf("retryBudget.allowRetry() over the min retries == '%v', want '%v'", true, false)
+	}
+
+	for i := 0; i < 10; i++ {
+		rb.addRequest(now)
+	}
+
+	for i := 0; i < 3; i++ {
+		if !rb.allowRetry(now) {
+			t.Errorf("retryBudget.allowRetry() retry %d under the ratio == '%v', want '%v'", i, false, true)
+		}
+	}
+
+	if rb.allowRetry(now) {
+		t.Errorf("retryBudget.allowRetry() over the ratio == '%v', want '%v'", true, false)
+	}
+
+	if !rb.allowRetry(now.Add(retryBudgetWindow)) {
+		t.Errorf("retryBudget.allowRetry() in a new window == '%v', want '%v'", false, true)
+	}
+}
+
+func TestProxy_parseRetry(t *testing.T) {
+	type args struct {
+		retry config.Retry
+	}
+
+	type want struct {
+		retry   config.Retry
+		enabled bool
+		err     bool
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Disabled",
+			args: args{retry: config.Retry{Attempts: 1, Statuses: []int{502}}},
+			want: want{retry: config.Retry{}},
+		},
+		{
+			name: "Defaults",
+			args: args{retry: config.Retry{Attempts: 3, Statuses: []int{502, 503}}},
+			want: want{
+				retry: config.Retry{
+					Attempts:   3,
+					Statuses:   []int{502, 503},
+					Budget:     defaultRetryBudget,
+					MinRetries: defaultRetryMinRetries,
+				},
+				enabled: true,
+			},
+		},
+		{
+			name: "InvalidAttempts",
+			args: args{retry: config.Retry{Attempts: -1}},
+			want: want{err: true},
+		},
+		{
+			name: "InvalidBudget",
+			args: args{retry: config.Retry{Attempts: 2, Budget: -0.1}},
+			want: want{err: true},
+		},
+		{
+			name: "InvalidStatus",
+			args: args{retry: config.Retry{Attempts: 2, Statuses: []int{404}}},
+			want: want{err: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.FileConfig.Retry = tt.args.retry
+
+			p, err := New(cfg)
+			if (err != nil) != tt.want.err {
+				t.Fatalf("New() Unexpected error: %v", err)
+			}
+
+			if tt.want.err {
+				return
+			}
+
+			if p.retry.Attempts != tt.want.retry.Attempts || p.retry.Budget != tt.want.retry.Budget ||
+				p.retry.MinRetries != tt.want.retry.MinRetries || len(p.retry.Statuses) != len(tt.want.retry.Statuses) {
+				t.Errorf("Proxy.parseRetry() == '%+v', want '%+v'", p.retry, tt.want.retry)
+			}
+
+			if enabled := p.retryBudget != nil; enabled != tt.want.enabled {
+				t.Errorf("Proxy.parseRetry() enabled == '%v', want '%v'", enabled, tt.want.enabled)
+			}
+		})
+	}
+}
+
+func TestProxy_doBackendRetry(t *testing.T) {
+	errBackend := errors.New("connection refused")
+
+	type args struct {
+		method   string
+		attempts int
+		clients  []*mockBackend
+	}
+
+	type want struct {
+		statusCode int
+		body       string
+		err        bool
+		called     []bool
+		retries    int64
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "RetryError",
+			args: args{
+				method:   fasthttp.MethodGet,
+				attempts: 3,
+				clients: []*mockBackend{
+					{err: errBackend},
+					{body: []byte("Backend 2"), statusCode: fasthttp.StatusOK},
+				},
+			},
+			want: want{statusCode: fasthttp.StatusOK, body: "Backend 2", called: []bool{true, true}, retries: 1},
+		},
+		{
+			name: "RetryStatus",
+			args: args{
+				method:   fasthttp.MethodPut,
+				attempts: 3,
+				clients: []*mockBackend{
+					{body: []byte("Backend 1"), statusCode: fasthttp.StatusBadGateway},
+					{body: []byte("Backend 2"), statusCode: fasthttp.StatusOK},
+				},
+			},
+			want: want{statusCode: fasthttp.StatusOK, body: "Backend 2", called: []bool{true, true}, retries: 1},
+		},
+		{
+			name: "NoRetryStatus",
+			args: args{
+				method:   fasthttp.MethodGet,
+				attempts: 3,
+				clients: []*mockBackend{
+					{body: []byte("Backend 1"), statusCode: fasthttp.StatusInternalServerError},
+					{body: []byte("Backend 2"), statusCode: fasthttp.StatusOK},
+				},
+			},
+			want: want{statusCode: fasthttp.StatusInternalServerError, body: "Backend 1", called: []bool{true, false}},
+		},
+		{
+			name: "NotIdempotent",
+			args: args{
+				method:   fasthttp.MethodPost,
+				attempts: 3,
+				clients: []*mockBackend{
+					{err: errBackend},
+					{body: []byte("Backend 2"), statusCode: fasthttp.StatusOK},
+				},
+			},
+			want: want{err: true, called: []bool{true, false}},
+		},
+		{
+			name: "AttemptsLimit",
+			args: args{
+				method:   fasthttp.MethodGet,
+				attempts: 2,
+				clients: []*mockBackend{
+					{err: errBackend},
+					{body: []byte("Backend 2"), statusCode: fasthttp.StatusServiceUnavailable},
+					{body: []byte("Backend 3"), statusCode: fasthttp.StatusOK},
+				},
+			},
+			want: want{
+				statusCode: fasthttp.StatusServiceUnavailable,
+				body:       "Backend 2",
+				called:     []bool{true, true, false},
+				retries:    1,
+			},
+		},
+		{
+			name: "AllBackendsTried",
+			args: args{
+				method:   fasthttp.MethodGet,
+				attempts: 5,
+				clients: []*mockBackend{
+					{body: []byte("Backend 1"), statusCode: fasthttp.StatusBadGateway},
+					{body: []byte("Backend 2"), statusCode: fasthttp.StatusBadGateway},
+				},
+			},
+			want: want{statusCode: fasthttp.StatusBadGateway, body: "Backend 2", called: []bool{true, true}, retries: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.FileConfig.Retry = config.Retry{
+				Attempts: tt.args.attempts,
+				Statuses: []int{fasthttp.StatusBadGateway, fasthttp.StatusServiceUnavailable},
+			}
+
+			p, err := New(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			clients := make([]fetcher, len(tt.args.clients))
+			for i, client := range tt.args.clients {
+				clients[i] = client
+			}
+
+			p.backends = newTestBackends(clients...)
+
+			// The backends are tried in order
+			p.balancer = &leastConnBalancer{}
+			for i, b := range p.backends {
+				b.active.Store(int64(i))
+			}
+
+			ctx := new(fasthttp.RequestCtx)
+			ctx.Request.Header.SetMethod(tt.args.method)
+			ctx.Request.SetRequestURI("/retry/")
+
+			err = p.doBackend(ctx, nil)
+			if (err != nil) != tt.want.err {
+				t.Fatalf("Proxy.doBackend() Unexpected error: %v", err)
+			}
+
+			if !tt.want.err {
+				if statusCode := ctx.Response.StatusCode(); statusCode != tt.want.statusCode {
+					t.Errorf("Proxy.doBackend() status code == '%d', want '%d'", statusCode, tt.want.statusCode)
+				}
+
+				if body := string(ctx.Response.Body()); body != tt.want.body {
+					t.Errorf("Proxy.doBackend() body == '%s', want '%s'", body, tt.want.body)
+				}
+			}
+
+			for i, client := range tt.args.clients {
+				if client.called != tt.want.called[i] {
+					t.Errorf("Proxy.doBackend() backend %d called == '%v', want '%v'", i, client.called, tt.want.called[i])
+				}
+			}
+
+			if retries := p.Stats().BackendRetries; retries != tt.want.retries {
+				t.Errorf("Proxy.doBackend() retries == '%d', want '%d'", retries, tt.want.retries)
+			}
+		})
+	}
+}
+
+func TestProxy_doBackendRetryBudget(t *testing.T) {
+	cfg := testConfig()
+	cfg.FileConfig.Retry = config.Retry{Attempts: 2, Budget: 0.1, MinRetries: 1}
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failing := &mockBackend{err: errors.New("timeout")}
+	p.backends = newTestBackends(failing, failing)
+
+	for i := 0; i < 5; i++ {
+		ctx := new(fasthttp.RequestCtx)
+		ctx.Request.SetRequestURI("/retry/")
+
+		if err := p.doBackend(ctx, nil); err == nil {
+			t.Fatalf("Proxy.doBackend() Expected error")
+		}
+	}
+
+	// Only the min retries, since the budget of 5 requests is under a retry
+	if retries := p.Stats().BackendRetries; retries != 1 {
+		t.Errorf("Proxy.doBackend() retries == '%d', want '%d'", retries, 1)
+	}
+
+	if fetches := p.Stats().BackendFetches; fetches != 6 {
+		t.Errorf("Proxy.doBackend() fetches == '%d', want '%d'", fetches, 6)
+	}
+}
@@ -0,0 +1,204 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	gstrconv "github.com/savsgio/gotils/strconv"
+	"github.com/valyala/fasthttp"
+)
+
+// backendPool returns the backend pool of the route, or nil for the default one.
+func (r *route) backendPool() *backendPool {
+	if r == nil {
+		return nil
+	}
+
+	return r.pool
+}
+
+// matchHost returns true if the host, without port, matches the host of the route.
+func (r *route) matchHost(host []byte) bool {
+	switch {
+	case r.host == "":
+		return true
+	case strings.HasPrefix(r.host, "*."):
+		suffix := r.host[1:]
+
+		return len(host) > len(suffix) && strings.EqualFold(gstrconv.B2S(host[len(host)-len(suffix):]), suffix)
+	default:
+		return strings.EqualFold(gstrconv.B2S(host), r.host)
+	}
+}
+
+// hostWithoutPort returns the host without the port, if any.
+func hostWithoutPort(host []byte) []byte {
+	if i := bytes.LastIndexByte(host, ':'); i >= 0 && bytes.IndexByte(host[i:], ']') < 0 {
+		return host[:i]
+	}
+
+	return host
+}
+
+// allBackends returns the backends of the default pool and of the named pools, without duplicates.
+func (p *Proxy) allBackends() []*backend {
+	backends := make([]*backend, 0, len(p.backends))
+
+	for _, b := range p.backends {
+		if !containsBackend(backends, b) {
+			backends = append(backends, b)
+		}
+	}
+
+	for _, pool := range p.pools {
+		for _, b := range pool.backends {
+			if !containsBackend(backends, b) {
+				backends = append(backends, b)
+			}
+		}
+	}
+
+	return backends
+}
+
+func (p *Proxy) getPool(name string) *backendPool {
+	for _, pool := range p.pools {
+		if pool.name == name {
+			return pool
+		}
+	}
+
+	return nil
+}
+
+func (p *Proxy) parsePools(backends map[string]*backend) error {
+	for _, cfg := range p.fileConfig.Pools {
+		if cfg.Name == "" {
+			return fmt.Errorf("The name of the backend pools is mandatory")
+		} else if p.getPool(cfg.Name) != nil {
+			return fmt.Errorf("Duplicated backend pool '%s'", cfg.Name)
+		} else if len(cfg.BackendAddrs) == 0 {
+			return fmt.Errorf("The backend pool '%s' has not backend addrs", cfg.Name)
+		}
+
+		p.pools = append(p.pools, &backendPool{
+			name:     cfg.Name,
+			backends: p.newBackends(cfg.BackendAddrs, backends),
+		})
+	}
+
+	return nil
+}
+
+func (p *Proxy) parseRoutes() error {
+	for i, cfg := range p.fileConfig.Routes {
+		r := &route{
+			host: strings.ToLower(cfg.Host),
+			path: cfg.Path,
+			ttl:  int64(cfg.TTL / time.Second),
+		}
+
+		if strings.Contains(strings.TrimPrefix(r.host, "*."), "*") {
+			return fmt.Errorf("Invalid host '%s' of route %d, only a leading '*.' is allowed", cfg.Host, i)
+		} else if cfg.TTL < 0 {
+			return fmt.Errorf("The ttl of route %d must be greater than or equal to 0", i)
+		}
+
+		if cfg.Pool != "" {
+			if r.pool = p.getPool(cfg.Pool); r.pool == nil {
+				return fmt.Errorf("Unknown backend pool '%s' of route %d", cfg.Pool, i)
+			}
+		}
+
+		if cfg.When != "" {
+			expr, params, err := p.newEvaluableExpression(cfg.When)
+			if err != nil {
+				return fmt.Errorf("Could not get the evaluable expression for rule '%s': %v", cfg.When, err)
+			}
+
+			for _, param := range params {
+				for _, v := range cacheKeyForbiddenVars {
+					if strings.HasPrefix(param.name, v) {
+						return fmt.Errorf("The variable '%s' is not available to route the requests", param.name)
+					}
+				}
+			}
+
+			r.when = &rule{expr: expr, params: params}
+		}
+
+		var err error
+
+		if r.nocacheRules, err = p.newNocacheRules(cfg.Nocache); err != nil {
+			return err
+		}
+
+		if len(cfg.Headers.Set) > 0 || len(cfg.Headers.Unset) > 0 {
+			set, err := p.newHeadersRules(setHeaderAction, cfg.Headers.Set)
+			if err != nil {
+				return err
+			}
+
+			unset, err := p.newHeadersRules(unsetHeaderAction, cfg.Headers.Unset)
+			if err != nil {
+				return err
+			}
+
+			r.headersRules = append(set, unset...)
+		}
+
+		p.routes = append(p.routes, r)
+	}
+
+	return nil
+}
+
+// matchRoute returns the first route which matches the host, path and expression of the request,
+// or nil if none matches.
+func (p *Proxy) matchRoute(ctx *fasthttp.RequestCtx, params *evalParams) (*route, error) {
+	if len(p.routes) == 0 {
+		return nil, nil
+	}
+
+	host := hostWithoutPort(ctx.Host())
+	path := gstrconv.B2S(ctx.Path())
+
+	for _, r := range p.routes {
+		if !r.matchHost(host) || !strings.HasPrefix(path, r.path) {
+			continue
+		}
+
+		if r.when != nil {
+			match, err := evalRule(ctx, *r.when, params)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid route rule: %v", err)
+			} else if !match {
+				continue
+			}
+		}
+
+		return r, nil
+	}
+
+	return nil, nil
+}
+
+// nocacheRulesOf returns the nocache rules of the route, or the global ones if it does not override them.
+func (p *Proxy) nocacheRulesOf(r *route) []nocacheRule {
+	if r != nil && r.nocacheRules != nil {
+		return r.nocacheRules
+	}
+
+	return p.nocacheRules
+}
+
+// headersRulesOf returns the headers rules of the route, or the global ones if it does not override them.
+func (p *Proxy) headersRulesOf(r *route) []headerRule {
+	if r != nil && r.headersRules != nil {
+		return r.headersRules
+	}
+
+	return p.headersRules
+}
@@ -0,0 +1,329 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/savsgio/kratgo/modules/cache"
+	"github.com/savsgio/kratgo/modules/config"
+	"github.com/valyala/fasthttp"
+)
+
+func Test_route_matchHost(t *testing.T) {
+	tests := []struct {
+		route string
+		host  string
+		want  bool
+	}{
+		{route: "", host: "www.kratgo.com", want: true},
+		{route: "www.kratgo.com", host: "www.kratgo.com", want: true},
+		{route: "www.kratgo.com", host: "WWW.Kratgo.com", want: true},
+		{route: "www.kratgo.com", host: "api.kratgo.com", want: false},
+		{route: "*.kratgo.com", host: "api.kratgo.com", want: true},
+		{route: "*.kratgo.com", host: "v1.api.kratgo.com", want: true},
+		{route: "*.kratgo.com", host: "kratgo.com", want: false},
+		{route: "*.kratgo.com", host: "notkratgo.com", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.route+"-"+tt.host, func(t *testing.T) {
+			r := &route{host: tt.route}
+
+			if got := r.matchHost([]byte(tt.host)); got != tt.want {
+				t.Errorf("route.matchHost() == '%v', want '%v'", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_hostWithoutPort(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "www.kratgo.com", want: "www.kratgo.com"},
+		{host: "www.kratgo.com:8080", want: "www.kratgo.com"},
+		{host: "[::1]:8080", want: "[::1]"},
+		{host: "[::1]", want: "[::1]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := string(hostWithoutPort([]byte(tt.host))); got != tt.want {
+				t.Errorf("hostWithoutPort() == '%s', want '%s'", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxy_parseRoutes(t *testing.T) {
+	pools := []config.BackendPool{
+		{Name: "api", BackendAddrs: []string{"localhost:9990", "localhost:9995"}},
+	}
+
+	type args struct {
+		pools  []config.BackendPool
+		routes []config.Route
+	}
+
+	type want struct {
+		pool     string
+		ttl      int64
+		nocache  int
+		headers  int
+		backends int
+		err      bool
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Route",
+			args: args{
+				pools: pools,
+				routes: []config.Route{
+					{
+						Host:    "*.kratgo.com",
+						Path:    "/api/",
+						When:    "$(method) == 'GET'",
+						Pool:    "api",
+						TTL:     time.Minute,
+						Nocache: []string{"$(query::nocache) == '1'"},
+						Headers: config.ProxyResponseHeaders{
+							Set:   []config.Header{{Name: "X-Route", Value: "api"}},
+							Unset: []config.Header{{Name: "Set-Cookie"}},
+						},
+					},
+				},
+			},
+			want: want{pool: "api", ttl: 60, nocache: 1, headers: 2, backends: 5},
+		},
+		{
+			name: "DefaultPool",
+			args: args{
+				routes: []config.Route{{Host: "www.kratgo.com"}},
+			},
+			want: want{backends: 4},
+		},
+		{
+			name: "UnknownPool",
+			args: args{
+				routes: []config.Route{{Host: "www.kratgo.com", Pool: "api"}},
+			},
+			want: want{err: true},
+		},
+		{
+			name: "InvalidHost",
+			args: args{
+				pools:  pools,
+				routes: []config.Route{{Host: "www.*.com", Pool: "api"}},
+			},
+			want: want{err: true},
+		},
+		{
+			name: "InvalidTTL",
+			args: args{
+				routes: []config.Route{{Path: "/api/", TTL: -time.Second}},
+			},
+			want: want{err: true},
+		},
+		{
+			name: "ResponseVariable",
+			args: args{
+				routes: []config.Route{{When: "$(resp.header::X-Data) == '1'"}},
+			},
+			want: want{err: true},
+		},
+		{
+			name: "DuplicatedPool",
+			args: args{
+				pools: append([]config.BackendPool{{Name: "api", BackendAddrs: []string{"localhost:9995"}}}, pools...),
+			},
+			want: want{err: true},
+		},
+		{
+			name: "EmptyPool",
+			args: args{
+				pools: []config.BackendPool{{Name: "api"}},
+			},
+			want: want{err: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.FileConfig.Pools = tt.args.pools
+			cfg.FileConfig.Routes = tt.args.routes
+
+			p, err := New(cfg)
+			if (err != nil) != tt.want.err {
+				t.Fatalf("New() Unexpected error: %v", err)
+			}
+
+			if tt.want.err {
+				return
+			}
+
+			if len(p.routes) != len(tt.args.routes) {
+				t.Fatalf("Proxy.parseRoutes() parsed %d routes, want %d", len(p.routes), len(tt.args.routes))
+			}
+
+			r := p.routes[0]
+
+			pool := ""
+			if r.pool != nil {
+				pool = r.pool.name
+			}
+
+			if pool != tt.want.pool {
+				t.Errorf("Proxy.parseRoutes() pool == '%s', want '%s'", pool, tt.want.pool)
+			}
+
+			if r.ttl != tt.want.ttl {
+				t.Errorf("Proxy.parseRoutes() ttl == '%d', want '%d'", r.ttl, tt.want.ttl)
+			}
+
+			if len(r.nocacheRules) != tt.want.nocache {
+				t.Errorf("Proxy.parseRoutes() nocache rules == '%d', want '%d'", len(r.nocacheRules), tt.want.nocache)
+			}
+
+			if len(r.headersRules) != tt.want.headers {
+				t.Errorf("Proxy.parseRoutes() headers rules == '%d', want '%d'", len(r.headersRules), tt.want.headers)
+			}
+
+			// The backends with the same addr are shared by the pools
+			if backends := len(p.allBackends()); backends != tt.want.backends {
+				t.Errorf("Proxy.allBackends() == '%d', want '%d'", backends, tt.want.backends)
+			}
+		})
+	}
+}
+
+func TestProxy_handlerRouting(t *testing.T) {
+	cfg := testConfig()
+	cfg.FileConfig.Response.Headers.Set = []config.Header{{Name: "X-Kratgo", Value: "true"}}
+	cfg.FileConfig.Pools = []config.BackendPool{
+		{Name: "api", BackendAddrs: []string{"localhost:9995"}},
+		{Name: "static", BackendAddrs: []string{"localhost:9996"}},
+		{Name: "beta", BackendAddrs: []string{"localhost:9997"}},
+	}
+	cfg.FileConfig.Routes = []config.Route{
+		{When: "$(req.header::X-Beta) == 'true'", Pool: "beta", Nocache: []string{"$(method) == 'GET'"}},
+		{Host: "*.api.kratgo.com", Pool: "api"},
+		{
+			Host: "www.kratgo.com",
+			Path: "/static/",
+			Pool: "static",
+			TTL:  time.Hour,
+			Headers: config.ProxyResponseHeaders{
+				Set: []config.Header{{Name: "X-Route", Value: "static"}},
+			},
+		},
+	}
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defaultBackend := &mockBackend{body: []byte("Default"), statusCode: fasthttp.StatusOK}
+	p.backends = newTestBackends(defaultBackend)
+
+	poolBackends := make(map[string]*mockBackend)
+	for _, pool := range p.pools {
+		poolBackends[pool.name] = &mockBackend{body: []byte(pool.name), statusCode: fasthttp.StatusOK}
+		pool.backends = newTestBackends(poolBackends[pool.name])
+	}
+
+	type args struct {
+		host   string
+		path   string
+		header string
+	}
+
+	type want struct {
+		body     string
+		xKratgo  string
+		xRoute   string
+		ttl      int64
+		cacheHit bool // The response is saved in cache
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Default",
+			args: args{host: "www.kratgo.com", path: "/data/"},
+			want: want{body: "Default", xKratgo: "true", cacheHit: true},
+		},
+		{
+			name: "Wildcard",
+			args: args{host: "v1.api.kratgo.com:8080", path: "/data/"},
+			want: want{body: "api", xKratgo: "true", cacheHit: true},
+		},
+		{
+			name: "PathPrefix",
+			args: args{host: "www.kratgo.com", path: "/static/app.js"},
+			want: want{body: "static", xRoute: "static", ttl: 3600, cacheHit: true},
+		},
+		{
+			name: "PathPrefixOtherHost",
+			args: args{host: "cdn.kratgo.com", path: "/static/app.js"},
+			want: want{body: "Default", xKratgo: "true", cacheHit: true},
+		},
+		{
+			name: "Expression",
+			args: args{host: "www.kratgo.com", path: "/beta/", header: "true"},
+			want: want{body: "beta", xKratgo: "true"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := new(fasthttp.RequestCtx)
+			ctx.Request.SetRequestURI(tt.args.path)
+			ctx.Request.Header.SetHost(tt.args.host)
+
+			if tt.args.header != "" {
+				ctx.Request.Header.Set("X-Beta", tt.args.header)
+			}
+
+			p.handler(ctx)
+
+			if body := string(ctx.Response.Body()); body != tt.want.body {
+				t.Errorf("Proxy.handler() body == '%s', want '%s'", body, tt.want.body)
+			}
+
+			if xKratgo := string(ctx.Response.Header.Peek("X-Kratgo")); xKratgo != tt.want.xKratgo {
+				t.Errorf("Proxy.handler() X-Kratgo == '%s', want '%s'", xKratgo, tt.want.xKratgo)
+			}
+
+			if xRoute := string(ctx.Response.Header.Peek("X-Route")); xRoute != tt.want.xRoute {
+				t.Errorf("Proxy.handler() X-Route == '%s', want '%s'", xRoute, tt.want.xRoute)
+			}
+
+			entry := cache.AcquireEntry()
+			defer cache.ReleaseEntry(entry)
+
+			if err := p.cache.Get(tt.args.host+tt.args.path+"?", entry); err != nil {
+				t.Fatal(err)
+			}
+
+			r := entry.GetResponse([]byte(tt.args.path))
+			if (r != nil) != tt.want.cacheHit {
+				t.Fatalf("Proxy.handler() response saved in cache == '%v', want '%v'", r != nil, tt.want.cacheHit)
+			}
+
+			if r != nil && r.TTL != tt.want.ttl {
+				t.Errorf("Proxy.handler() cached response ttl == '%d', want '%d'", r.TTL, tt.want.ttl)
+			}
+		})
+	}
+}
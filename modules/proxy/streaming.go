@@ -0,0 +1,282 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http/httputil"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func newStreamingClient(addr string, maxBodySize int) *streamingClient {
+	return &streamingClient{
+		addr:                addr,
+		maxBodySize:         maxBodySize,
+		maxConns:            streamingMaxConns,
+		maxIdleConnDuration: streamingMaxIdleConnDuration,
+		readTimeout:         streamingReadTimeout,
+		writeTimeout:        streamingWriteTimeout,
+	}
+}
+
+func (c *streamingClient) acquireConn(fresh bool) (*streamingConn, bool, error) {
+	c.mu.Lock()
+
+	if !fresh {
+		if n := len(c.conns); n > 0 {
+			conn := c.conns[n-1]
+			c.conns[n-1] = nil
+			c.conns = c.conns[:n-1]
+
+			c.mu.Unlock()
+
+			return conn, true, nil
+		}
+	}
+
+	if c.connsCount >= c.maxConns {
+		c.mu.Unlock()
+
+		return nil, false, fasthttp.ErrNoFreeConns
+	}
+
+	c.connsCount++
+	c.mu.Unlock()
+
+	conn, err := fasthttp.Dial(c.addr)
+	if err != nil {
+		c.mu.Lock()
+		c.connsCount--
+		c.mu.Unlock()
+
+		return nil, false, err
+	}
+
+	sc := &streamingConn{
+		Conn:   conn,
+		client: c,
+	}
+	sc.br = bufio.NewReaderSize(sc, streamingBufferSize)
+	sc.bw = bufio.NewWriterSize(sc, streamingBufferSize)
+
+	return sc, false, nil
+}
+
+func (c *streamingClient) releaseConn(conn *streamingConn) {
+	conn.lastUsed = time.Now()
+
+	c.mu.Lock()
+
+	c.conns = append(c.conns, conn)
+
+	startCleaner := !c.connsCleanerOn
+	c.connsCleanerOn = true
+
+	c.mu.Unlock()
+
+	if startCleaner {
+		go c.connsCleaner()
+	}
+}
+
+// connsCleaner closes the connections idle for longer than the max idle duration,
+// until there are not idle connections.
+func (c *streamingClient) connsCleaner() {
+	var expired []*streamingConn
+
+	for {
+		time.Sleep(c.maxIdleConnDuration)
+
+		c.mu.Lock()
+
+		limit := time.Now().Add(-c.maxIdleConnDuration)
+
+		i := 0
+		for i < len(c.conns) && c.conns[i].lastUsed.Before(limit) {
+			i++
+		}
+
+		expired = append(expired[:0], c.conns[:i]...)
+
+		n := copy(c.conns, c.conns[i:])
+		for j := n; j < len(c.conns); j++ {
+			c.conns[j] = nil
+		}
+		c.conns = c.conns[:n]
+
+		stop := len(c.conns) == 0
+		if stop {
+			c.connsCleanerOn = false
+		}
+
+		c.mu.Unlock()
+
+		for j := range expired {
+			expired[j].Close()
+			expired[j] = nil
+		}
+
+		if stop {
+			return
+		}
+	}
+}
+
+// Do sends the request to the backend and reads its response.
+// If the body of the response exceeds the max size, it's set as a body stream of the response,
+// so it's written to the client while it's read from the backend.
+func (c *streamingClient) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	for attempt := 0; ; attempt++ {
+		conn, reused, err := c.acquireConn(attempt > 0)
+		if err != nil {
+			return err
+		}
+
+		resp.Reset()
+
+		if err = req.Write(conn.bw); err == nil {
+			if err = conn.bw.Flush(); err == nil {
+				err = resp.Header.Read(conn.br)
+			}
+		}
+
+		if err != nil {
+			conn.Close()
+
+			// The idle connections could have been closed by the backend, so the idempotent requests
+			// are retried once with a new one
+			if reused && attempt == 0 && isIdempotentMethod(req.Header.Method()) {
+				continue
+			}
+
+			return err
+		}
+
+		return c.readBody(conn, req, resp)
+	}
+}
+
+func (c *streamingClient) readBody(conn *streamingConn, req *fasthttp.Request, resp *fasthttp.Response) error {
+	statusCode := resp.StatusCode()
+
+	if req.Header.IsHead() || statusCode < fasthttp.StatusOK ||
+		statusCode == fasthttp.StatusNoContent || statusCode == fasthttp.StatusNotModified {
+		c.releaseOrClose(conn, resp)
+
+		return nil
+	}
+
+	contentLength := resp.Header.ContentLength()
+
+	var body io.Reader
+
+	switch {
+	case contentLength >= 0:
+		body = io.LimitReader(conn.br, int64(contentLength))
+	case contentLength == -1:
+		body = httputil.NewChunkedReader(conn.br)
+	default:
+		// The body is read until the backend closes the connection
+		body = conn.br
+	}
+
+	if contentLength > c.maxBodySize {
+		resp.SetBodyStream(&streamedBody{Reader: body, conn: conn}, contentLength)
+
+		return nil
+	}
+
+	n, err := io.CopyN(resp.BodyWriter(), body, int64(c.maxBodySize)+1)
+	if err == io.EOF {
+		if contentLength >= 0 && int(n) != contentLength {
+			conn.Close()
+
+			return fmt.Errorf("Unexpected EOF reading the response body (read: %d, content length: %d)", n, contentLength)
+		} else if contentLength == -1 {
+			if err := discardTrailer(conn.br); err != nil {
+				conn.Close()
+
+				return err
+			}
+		}
+
+		c.releaseOrClose(conn, resp)
+
+		return nil
+
+	} else if err != nil {
+		conn.Close()
+
+		return err
+	}
+
+	// The body exceeds the max size, so the read part is streamed before the rest
+	buffered := append([]byte(nil), resp.Body()...)
+	resp.ResetBody()
+	resp.SetBodyStream(&streamedBody{Reader: io.MultiReader(bytes.NewReader(buffered), body), conn: conn}, -1)
+
+	return nil
+}
+
+// releaseOrClose keeps the connection to reuse it, unless the backend has closed it.
+func (c *streamingClient) releaseOrClose(conn *streamingConn, resp *fasthttp.Response) {
+	if resp.Header.ConnectionClose() || resp.Header.ContentLength() == -2 {
+		conn.Close()
+		return
+	}
+
+	c.releaseConn(conn)
+}
+
+// discardTrailer reads the trailer of a chunked body until the final empty line.
+func discardTrailer(br *bufio.Reader) error {
+	for {
+		line, err := br.ReadSlice('\n')
+		if err != nil {
+			return fmt.Errorf("Could not read the chunked body trailer: %v", err)
+		}
+
+		if len(bytes.TrimSpace(line)) == 0 {
+			return nil
+		}
+	}
+}
+
+// Read reads from the connection, renewing its read deadline.
+func (conn *streamingConn) Read(p []byte) (int, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(conn.client.readTimeout)); err != nil {
+		return 0, err
+	}
+
+	return conn.Conn.Read(p)
+}
+
+// Write writes to the connection, renewing its write deadline.
+func (conn *streamingConn) Write(p []byte) (int, error) {
+	if err := conn.SetWriteDeadline(time.Now().Add(conn.client.writeTimeout)); err != nil {
+		return 0, err
+	}
+
+	return conn.Conn.Write(p)
+}
+
+// Close closes the connection, and frees its place in the open connections of the client.
+func (conn *streamingConn) Close() error {
+	if !conn.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	conn.client.mu.Lock()
+	conn.client.connsCount--
+	conn.client.mu.Unlock()
+
+	return conn.Conn.Close()
+}
+
+// Close closes the backend connection, since the rest of the body could not have been read.
+func (b *streamedBody) Close() error {
+	return b.conn.Close()
+}
@@ -0,0 +1,480 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/savsgio/kratgo/modules/cache"
+	"github.com/valyala/fasthttp"
+)
+
+const testStreamingMaxBodySize = 50
+
+var (
+	testStreamingSmallBody = bytes.Repeat([]byte("s"), 10)
+	testStreamingLargeBody = bytes.Repeat([]byte("l"), 100)
+)
+
+func testStreamingBackend(t *testing.T) (string, *int32) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conns := new(int32)
+
+	s := &fasthttp.Server{
+		Handler: func(ctx *fasthttp.RequestCtx) {
+			ctx.Response.Header.Set(fasthttp.HeaderCacheControl, "max-age=60")
+
+			switch string(ctx.Path()) {
+			case "/small":
+				ctx.SetBody(testStreamingSmallBody)
+			case "/large":
+				ctx.SetBody(testStreamingLargeBody)
+			case "/chunked-small", "/chunked-large":
+				body := testStreamingSmallBody
+				if string(ctx.Path()) == "/chunked-large" {
+					body = testStreamingLargeBody
+				}
+
+				ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+					for i := range body {
+						w.WriteByte(body[i])
+						w.Flush()
+					}
+				})
+			case "/ranges":
+				if len(ctx.Request.Header.Peek(fasthttp.HeaderRange)) > 0 {
+					ctx.SetStatusCode(fasthttp.StatusPartialContent)
+					ctx.Response.Header.Set(fasthttp.HeaderContentRange, "bytes 0-4/100")
+					ctx.SetBody(testStreamingLargeBody[:5])
+				} else {
+					ctx.SetBody(testStreamingLargeBody)
+				}
+			}
+		},
+		ConnState: func(conn net.Conn, state fasthttp.ConnState) {
+			if state == fasthttp.StateNew {
+				atomic.AddInt32(conns, 1)
+			}
+		},
+	}
+
+	go s.Serve(ln)
+
+	t.Cleanup(func() {
+		s.Shutdown()
+	})
+
+	return ln.Addr().String(), conns
+}
+
+func TestStreamingClient_Do(t *testing.T) {
+	addr, conns := testStreamingBackend(t)
+	c := newStreamingClient(addr, testStreamingMaxBodySize)
+
+	type args struct {
+		path   string
+		method string
+	}
+
+	type want struct {
+		body     []byte
+		streamed bool
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Small",
+			args: args{path: "/small"},
+			want: want{body: testStreamingSmallBody, streamed: false},
+		},
+		{
+			name: "Large",
+			args: args{path: "/large"},
+			want: want{body: testStreamingLargeBody, streamed: true},
+		},
+		{
+			name: "ChunkedSmall",
+			args: args{path: "/chunked-small"},
+			want: want{body: testStreamingSmallBody, streamed: false},
+		},
+		{
+			name: "ChunkedLarge",
+			args: args{path: "/chunked-large"},
+			want: want{body: testStreamingLargeBody, streamed: true},
+		},
+		{
+			name: "Head",
+			args: args{path: "/large", method: fasthttp.MethodHead},
+			want: want{body: nil, streamed: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := fasthttp.AcquireRequest()
+			resp := fasthttp.AcquireResponse()
+
+			req.SetRequestURI(tt.args.path)
+			req.Header.SetHost("www.kratgo.com")
+			if tt.args.method != "" {
+				req.Header.SetMethod(tt.args.method)
+			}
+
+			if err := c.Do(req, resp); err != nil {
+				t.Fatalf("streamingClient.Do() returns err: %v", err)
+			}
+
+			if streamed := resp.IsBodyStream(); streamed != tt.want.streamed {
+				t.Errorf("streamingClient.Do() streamed == '%v', want '%v'", streamed, tt.want.streamed)
+			}
+
+			if body := resp.Body(); !bytes.Equal(body, tt.want.body) {
+				t.Errorf("streamingClient.Do() body == '%s', want '%s'", body, tt.want.body)
+			}
+
+			fasthttp.ReleaseRequest(req)
+			fasthttp.ReleaseResponse(resp)
+		})
+	}
+
+	// The buffered responses reuse the connection, and the streamed ones close it
+	if n := atomic.LoadInt32(conns); n != 3 {
+		t.Errorf("streamingClient.Do() backend connections == '%d', want '%d'", n, 3)
+	}
+}
+
+func TestProxy_handlerStreaming(t *testing.T) {
+	addr, _ := testStreamingBackend(t)
+
+	cfg := testConfig()
+	cfg.FileConfig.BackendAddrs = []string{addr}
+	cfg.FileConfig.Cache.MaxObjectSize = testStreamingMaxBodySize
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type args struct {
+		path   string
+		ranges string
+	}
+
+	type want struct {
+		statusCode  int
+		body        []byte
+		streamed    bool
+		saveInCache bool
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Small",
+			args: args{path: "/small"},
+			want: want{statusCode: fasthttp.StatusOK, body: testStreamingSmallBody, saveInCache: true},
+		},
+		{
+			name: "Large",
+			args: args{path: "/large"},
+			want: want{statusCode: fasthttp.StatusOK, body: testStreamingLargeBody, streamed: true},
+		},
+		{
+			name: "ChunkedLarge",
+			args: args{path: "/chunked-large"},
+			want: want{statusCode: fasthttp.StatusOK, body: testStreamingLargeBody, streamed: true},
+		},
+		{
+			name: "Ranges",
+			args: args{path: "/ranges", ranges: "bytes=0-4"},
+			want: want{statusCode: fasthttp.StatusPartialContent, body: testStreamingLargeBody[:5]},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := new(fasthttp.RequestCtx)
+			ctx.Request.SetRequestURI(tt.args.path)
+			ctx.Request.Header.SetHost("www.kratgo.com")
+			if tt.args.ranges != "" {
+				ctx.Request.Header.Set(fasthttp.HeaderRange, tt.args.ranges)
+			}
+
+			p.handler(ctx)
+
+			if statusCode := ctx.Response.StatusCode(); statusCode != tt.want.statusCode {
+				t.Errorf("Proxy.handler() status code == '%d', want '%d'", statusCode, tt.want.statusCode)
+			}
+
+			if streamed := ctx.Response.IsBodyStream(); streamed != tt.want.streamed {
+				t.Errorf("Proxy.handler() streamed == '%v', want '%v'", streamed, tt.want.streamed)
+			}
+
+			if body := ctx.Response.Body(); !bytes.Equal(body, tt.want.body) {
+				t.Errorf("Proxy.handler() body == '%s', want '%s'", body, tt.want.body)
+			}
+
+			entry := cache.AcquireEntry()
+			if err := p.cache.Get("www.kratgo.com"+tt.args.path+"?", entry); err != nil {
+				t.Fatal(err)
+			}
+
+			if saved := entry.HasResponse([]byte(tt.args.path)); saved != tt.want.saveInCache {
+				t.Errorf("Proxy.handler() saved in cache == '%v', want '%v'", saved, tt.want.saveInCache)
+			}
+
+			cache.ReleaseEntry(entry)
+		})
+	}
+}
+
+type streamedBackendBody struct {
+	io.Reader
+	closed int32
+}
+
+func (b *streamedBackendBody) Close() error {
+	atomic.StoreInt32(&b.closed, 1)
+
+	return nil
+}
+
+type streamedBackend struct {
+	body *streamedBackendBody
+}
+
+func (mock *streamedBackend) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	resp.SetStatusCode(fasthttp.StatusOK)
+	resp.SetBodyStream(mock.body, len(testStreamingLargeBody))
+
+	return nil
+}
+
+func TestProxy_revalidateStreaming(t *testing.T) {
+	p, ctx := testStaleProxy(t, 3600, 0)
+
+	backend := &streamedBackend{
+		body: &streamedBackendBody{Reader: bytes.NewReader(testStreamingLargeBody)},
+	}
+	p.backends = newTestBackends(backend)
+
+	p.handler(ctx)
+
+	if body := string(ctx.Response.Body()); body != "Stale" {
+		t.Fatalf("Proxy.handler() body == '%s', want '%s'", body, "Stale")
+	}
+
+	for i := 0; i < 100 && atomic.LoadInt32(&backend.body.closed) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if atomic.LoadInt32(&backend.body.closed) == 0 {
+		t.Errorf("Proxy.revalidate() the streamed body of the backend has not been closed")
+	}
+}
+
+// testRawBackend accepts the connections and handles them with fn, without a http server.
+func testRawBackend(t *testing.T, fn func(conn net.Conn)) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		ln.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				fn(conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func TestStreamingClient_DoTimeout(t *testing.T) {
+	hung := make(chan struct{})
+	defer close(hung)
+
+	addr := testRawBackend(t, func(conn net.Conn) {
+		<-hung
+	})
+
+	c := newStreamingClient(addr, testStreamingMaxBodySize)
+	c.readTimeout = 50 * time.Millisecond
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI("/hung")
+	req.Header.SetHost("www.kratgo.com")
+
+	start := time.Now()
+
+	if err := c.Do(req, resp); err != fasthttp.ErrTimeout {
+		t.Fatalf("streamingClient.Do() err == '%v', want '%v'", err, fasthttp.ErrTimeout)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("streamingClient.Do() timed out after '%s', want '%s'", elapsed, c.readTimeout)
+	}
+
+	if c.connsCount != 0 {
+		t.Errorf("streamingClient.Do() open connections == '%d', want '%d'", c.connsCount, 0)
+	}
+}
+
+func TestStreamingClient_DoMaxConns(t *testing.T) {
+	addr, _ := testStreamingBackend(t)
+
+	c := newStreamingClient(addr, testStreamingMaxBodySize)
+	c.maxConns = 1
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	req.SetRequestURI("/large")
+	req.Header.SetHost("www.kratgo.com")
+
+	streamed := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(streamed)
+
+	if err := c.Do(req, streamed); err != nil {
+		t.Fatalf("streamingClient.Do() returns err: %v", err)
+	}
+
+	// The connection of the streamed body is busy until the body is closed
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	if err := c.Do(req, resp); err != fasthttp.ErrNoFreeConns {
+		t.Errorf("streamingClient.Do() err == '%v', want '%v'", err, fasthttp.ErrNoFreeConns)
+	}
+
+	streamed.Reset()
+
+	if err := c.Do(req, resp); err != nil {
+		t.Errorf("streamingClient.Do() returns err: %v", err)
+	}
+}
+
+func TestStreamingClient_idleConns(t *testing.T) {
+	addr, _ := testStreamingBackend(t)
+
+	c := newStreamingClient(addr, testStreamingMaxBodySize)
+	c.maxIdleConnDuration = 50 * time.Millisecond
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI("/small")
+	req.Header.SetHost("www.kratgo.com")
+
+	if err := c.Do(req, resp); err != nil {
+		t.Fatalf("streamingClient.Do() returns err: %v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+
+		c.mu.Lock()
+		idle, open := len(c.conns), c.connsCount
+		c.mu.Unlock()
+
+		if idle == 0 && open == 0 {
+			return
+		}
+	}
+
+	t.Errorf("streamingClient.connsCleaner() the idle connection has not been closed")
+}
+
+func TestStreamingClient_DoRetry(t *testing.T) {
+	conns := new(int32)
+
+	// The backend closes every connection after its first response
+	addr := testRawBackend(t, func(conn net.Conn) {
+		atomic.AddInt32(conns, 1)
+
+		req := fasthttp.AcquireRequest()
+		defer fasthttp.ReleaseRequest(req)
+
+		if err := req.Read(bufio.NewReader(conn)); err != nil {
+			return
+		}
+
+		conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok"))
+	})
+
+	tests := []struct {
+		method string
+		err    bool
+		conns  int32
+	}{
+		{method: fasthttp.MethodGet, conns: 1},
+		{method: fasthttp.MethodPost, err: true, conns: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			c := newStreamingClient(addr, testStreamingMaxBodySize)
+
+			req := fasthttp.AcquireRequest()
+			defer fasthttp.ReleaseRequest(req)
+
+			resp := fasthttp.AcquireResponse()
+			defer fasthttp.ReleaseResponse(resp)
+
+			req.SetRequestURI("/retry")
+			req.Header.SetHost("www.kratgo.com")
+
+			if err := c.Do(req, resp); err != nil {
+				t.Fatalf("streamingClient.Do() returns err: %v", err)
+			}
+
+			// Wait for the backend to close the idle connection
+			time.Sleep(50 * time.Millisecond)
+			atomic.StoreInt32(conns, 0)
+
+			req.Header.SetMethod(tt.method)
+
+			if err := c.Do(req, resp); (err != nil) != tt.err {
+				t.Errorf("streamingClient.Do() err == '%v', want error '%v'", err, tt.err)
+			}
+
+			if n := atomic.LoadInt32(conns); n != tt.conns {
+				t.Errorf("streamingClient.Do() new backend connections == '%d', want '%d'", n, tt.conns)
+			}
+		})
+	}
+}
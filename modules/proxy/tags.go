@@ -0,0 +1,20 @@
+package proxy
+
+import (
+	"bytes"
+
+	"github.com/savsgio/kratgo/modules/cache"
+)
+
+// isCacheTagSeparator returns true if the char separates the cache tags of a header value.
+// "Surrogate-Key" separates them by spaces and "Cache-Tag" by commas.
+func isCacheTagSeparator(c rune) bool {
+	return c == ' ' || c == ',' || c == '\t'
+}
+
+// setResponseTags saves in the response the cache tags of the header value.
+func setResponseTags(r *cache.Response, value []byte) {
+	for _, tag := range bytes.FieldsFunc(value, isCacheTagSeparator) {
+		r.SetTag(tag)
+	}
+}
@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/savsgio/kratgo/modules/cache"
+	"github.com/valyala/fasthttp"
+)
+
+func Test_setResponseTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{
+			name:  "SurrogateKey",
+			value: "product-42 category-7",
+			want:  []string{"product-42", "category-7"},
+		},
+		{
+			name:  "CacheTag",
+			value: "product-42,category-7, home",
+			want:  []string{"product-42", "category-7", "home"},
+		},
+		{
+			name:  "Duplicated",
+			value: "product-42  product-42",
+			want:  []string{"product-42"},
+		},
+		{
+			name:  "Empty",
+			value: "",
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := cache.AcquireResponse()
+			setResponseTags(r, []byte(tt.value))
+
+			var tags []string
+			for _, tag := range r.Tags {
+				tags = append(tags, string(tag))
+			}
+
+			if !reflect.DeepEqual(tags, tt.want) {
+				t.Errorf("setResponseTags() == '%v', want '%v'", tags, tt.want)
+			}
+
+			cache.ReleaseResponse(r)
+		})
+	}
+}
+
+func TestProxy_handlerCacheTags(t *testing.T) {
+	cfg := testConfig()
+	cfg.FileConfig.Cache.TagsHeader = "Surrogate-Key"
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.backends = newTestBackends(&slowBackend{
+		body: []byte("Kratgo body"),
+		headers: map[string]string{
+			fasthttp.HeaderCacheControl: "max-age=60",
+			"Surrogate-Key":             "product-42 category-7",
+		},
+	})
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.SetRequestURI("/product/42")
+	ctx.Request.Header.SetHost("www.kratgo.com")
+
+	p.handler(ctx)
+
+	if v := ctx.Response.Header.Peek("Surrogate-Key"); len(v) > 0 {
+		t.Errorf("Proxy.handler() Surrogate-Key header == '%s', want ''", v)
+	}
+
+	key := "www.kratgo.com/product/42?"
+
+	for _, tag := range []string{"product-42", "category-7"} {
+		if keys := p.cache.TagKeys(tag); len(keys) != 1 || keys[0] != key {
+			t.Errorf("Proxy.handler() cache keys of tag '%s' == '%v', want '%v'", tag, keys, []string{key})
+		}
+	}
+
+	entry := cache.AcquireEntry()
+	if err := p.cache.Get(key, entry); err != nil {
+		t.Fatal(err)
+	}
+
+	r := entry.GetResponse([]byte("/product/42"))
+	if r == nil {
+		t.Fatal("Proxy.handler() response not found in cache")
+	}
+
+	if r.GetHeader([]byte("Surrogate-Key")) != nil {
+		t.Errorf("Proxy.handler() cached Surrogate-Key header == '%s', want ''", r.GetHeader([]byte("Surrogate-Key")))
+	}
+
+	cache.ReleaseEntry(entry)
+}
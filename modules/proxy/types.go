@@ -1,8 +1,12 @@
 package proxy
 
 import (
+	"bufio"
 	"io"
+	"net"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	logger "github.com/savsgio/go-logger/v4"
 	"github.com/savsgio/govaluate/v3"
@@ -29,23 +33,297 @@ type Proxy struct {
 	server server
 	cache  *cache.Cache
 
-	backends       []fetcher
-	totalBackends  int
-	currentBackend int
+	backends       []*backend // Default pool, for the requests without a route to other pool
+	balancer       balancer
+	pools          []*backendPool
+	routes         []*route
+	retry          config.Retry
+	retryBudget    *retryBudget
+	healthCheck    config.HealthCheck
+	circuitBreaker config.CircuitBreaker
+	errorPage      []byte // Page served when no backend is available
 
 	httpScheme string
 
-	nocacheRules []rule
-	headersRules []headerRule
+	defaultCacheKey cacheKeyTemplate
+	cacheKeyRules   []cacheKeyRule
+	nocacheRules    []nocacheRule
+	headersRules    []headerRule
+
+	coalescer         *coalescer
+	coalescingTimeout time.Duration
+
+	revalidations *coalescer
+	staleGrace    int64
+	staleKeep     int64
+
+	revalidationRetain int64
+
+	tagsHeader []byte
+	statusTTLs map[int]int64
+
+	admissionRules []*admissionRule
+
+	unsavedEncodings unsavedEncodings
+
+	xCache          bool
+	age             bool
+	cacheStatusNode []byte // Cache identifier of the Cache-Status header, empty if it's disabled
+
+	stats proxyStats
 
 	log   *logger.Logger
 	tools sync.Pool
-	mu    sync.RWMutex
+}
+
+// unsavedEncodings remembers the encoded bodies which could not be saved in cache,
+// so the save is not tried again on every hit of the same stored response.
+type unsavedEncodings struct {
+	mu    sync.Mutex
+	items map[string]int64 // Stored time of the response, by cache key and encoding
 }
 
 type proxyTools struct {
-	params *evalParams
-	entry  *cache.Entry
+	params   *evalParams
+	entry    *cache.Entry
+	cacheKey []byte
+
+	body     []byte // Identity body of an encoded backend response
+	encoding []byte // Encoding of the backend response
+	tags     []byte // Cache tags header of the backend response, removed from the client response
+
+	route *route // Route of the request, nil if none matches
+
+	expired *cache.Response // Expired cached response to revalidate with the backend
+	status  cacheStatus
+
+	ifNoneMatch     []byte // If-None-Match header of the client, replaced in the revalidation request
+	ifModifiedSince []byte // If-Modified-Since header of the client, replaced in the revalidation request
+
+	fwd         string // Reason to forward the request to the backend
+	fwdStatus   int    // Status code of the backend response
+	stored      bool   // The backend response has been saved in cache
+	collapsed   bool   // The response has been got from the coalesced request of other client
+	nocacheRule string // Nocache rule which matched the request or the backend response
+	storedAt    int64  // Stored time of the cached response served to the client
+	ttl         int64  // Ttl of the cached response served to the client
+
+	ranges     []byte // Range header of the client, removed from the backend request
+	ifRange    []byte // If-Range header of the client, removed from the backend request
+	byteRanges []byteRange
+}
+
+// Stats ...
+type Stats struct {
+	Hits        int64 `json:"hits"`
+	Revalidated int64 `json:"revalidated"`
+	Misses      int64 `json:"misses"`
+
+	BackendFetches  int64 `json:"backend_fetches"`
+	BackendErrors   int64 `json:"backend_errors"`
+	BackendRetries  int64 `json:"backend_retries"`
+	NocacheBypasses int64 `json:"nocache_bypasses"`
+
+	Hosts     map[string]HostStats `json:"hosts"`
+	Admission []AdmissionStats     `json:"admission"`
+}
+
+// HostStats ...
+type HostStats struct {
+	Hits     int64   `json:"hits"`
+	Misses   int64   `json:"misses"`
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+// AdmissionStats ...
+type AdmissionStats struct {
+	Rule     string `json:"rule"`
+	Policy   string `json:"policy"`
+	Admitted int64  `json:"admitted"`
+	Rejected int64  `json:"rejected"`
+}
+
+// BackendStatus ...
+type BackendStatus struct {
+	Addr      string `json:"addr"`
+	Healthy   bool   `json:"healthy"`
+	Successes int    `json:"successes"` // Consecutive successful health checks
+	Failures  int    `json:"failures"`  // Consecutive failed health checks
+	LastCheck int64  `json:"last_check"`
+	LastError string `json:"last_error,omitempty"`
+	Circuit   string `json:"circuit,omitempty"`
+}
+
+type proxyStats struct {
+	hits        atomic.Int64
+	revalidated atomic.Int64
+	misses      atomic.Int64
+
+	backendFetches  atomic.Int64
+	backendErrors   atomic.Int64
+	backendRetries  atomic.Int64
+	nocacheBypasses atomic.Int64
+
+	hosts   map[string]*hostStats
+	hostsMu sync.RWMutex
+}
+
+type hostStats struct {
+	hits   atomic.Int64 // Includes the revalidated responses
+	misses atomic.Int64
+}
+
+type cacheStatus int
+
+// streamingClient is a backend client which buffers the response bodies up to a max size,
+// and streams the larger ones to the client instead of buffering them.
+type streamingClient struct {
+	addr        string
+	maxBodySize int
+
+	maxConns            int
+	maxIdleConnDuration time.Duration
+	readTimeout         time.Duration
+	writeTimeout        time.Duration
+
+	conns          []*streamingConn // Idle connections, from the least to the most recently used
+	connsCount     int              // Open connections, including the idle ones
+	connsCleanerOn bool
+	mu             sync.Mutex
+}
+
+// streamingConn is a backend connection, with the read and write deadlines of the client
+// renewed on every read and write.
+type streamingConn struct {
+	net.Conn
+
+	client   *streamingClient
+	lastUsed time.Time
+	closed   atomic.Bool
+
+	br *bufio.Reader
+	bw *bufio.Writer
+}
+
+// streamedBody is a backend response body streamed to the client,
+// which closes the backend connection once it's written.
+type streamedBody struct {
+	io.Reader
+
+	conn *streamingConn
+}
+
+// backend is a backend server of the proxy, out of the rotation while its health checks fail.
+type backend struct {
+	addr   string
+	client fetcher
+	weight int
+
+	active atomic.Int64 // Requests in flight
+
+	checkClient healthCheckClient
+	circuit     *circuitBreaker // Nil if the circuit breaking is disabled
+
+	healthy   atomic.Bool
+	successes int
+	failures  int
+	lastCheck int64
+	lastError string
+	mu        sync.Mutex
+}
+
+// backendPool is a named group of backends, balanced apart from the other pools.
+type backendPool struct {
+	name     string
+	backends []*backend
+	balancer balancer
+}
+
+// route sends the matching requests to a backend pool, and overrides the cache and headers settings.
+type route struct {
+	host string // Lowercase, with a leading "*." to match the subdomains
+	path string // Path prefix
+	when *rule  // Nil if the route has not an expression
+
+	pool *backendPool // Nil for the default pool
+
+	ttl          int64         // Seconds, 0 to keep the default one
+	nocacheRules []nocacheRule // Nil to apply the global ones
+	headersRules []headerRule  // Nil to apply the global ones
+}
+
+// circuitBreaker watches the results of the requests to a backend, and opens its circuit
+// when the failure rate exceeds the threshold, so no requests are sent to it during the cool-down.
+// Then a few trial requests (half-open) decide if the circuit is closed or opened again.
+type circuitBreaker struct {
+	cfg config.CircuitBreaker
+
+	state circuitState
+
+	windowStart time.Time
+	requests    int
+	failures    int
+
+	openedAt  time.Time
+	probes    int // Trial requests sent while half-open
+	successes int // Successful trial requests
+
+	mu sync.Mutex
+}
+
+type circuitState int
+
+// roundRobinBalancer is a smooth weighted round-robin, which spreads the requests of the heavier backends
+// between the ones of the others.
+type roundRobinBalancer struct {
+	currentWeights []int
+	mu             sync.Mutex
+}
+
+// leastConnBalancer chooses the backend with the fewest requests in flight relative to its weight.
+type leastConnBalancer struct {
+	offset atomic.Uint64 // Rotates the first backend checked, to break the ties
+}
+
+// twoChoicesBalancer chooses two random backends, and then the one with the fewest requests in flight
+// relative to its weight.
+type twoChoicesBalancer struct{}
+
+// hashBalancer chooses the backend by the hash of a key in a consistent hashing ring, so the requests
+// with the same key go to the same backend while it's available.
+type hashBalancer struct {
+	key  cacheKeyTemplate
+	ring []ringPoint // Sorted by hash
+
+	fallback balancer // Balances the requests with an empty key
+}
+
+type ringPoint struct {
+	hash    uint64
+	backend int // Index of the backend
+}
+
+// retryBudget limits the retries to a ratio of the requests to the backends in a time window,
+// so the retries do not multiply the load of the backends during an outage.
+type retryBudget struct {
+	ratio      float64
+	minRetries int // Retries always allowed in a window, whatever the number of requests
+
+	windowStart time.Time
+	requests    int
+	retries     int
+
+	mu sync.Mutex
+}
+
+type coalescer struct {
+	calls map[string]*coalescedCall
+	mu    sync.Mutex
+}
+
+type coalescedCall struct {
+	done chan struct{}
+	err  error
 }
 
 type httpClient struct {
@@ -55,6 +333,32 @@ type httpClient struct {
 	executeHeaderRule bool
 }
 
+type byteRange struct {
+	start int
+	end   int // Inclusive
+}
+
+type lifetime struct {
+	ttl   int64 // Seconds, 0 if the response has not an explicit lifetime
+	grace int64 // Seconds after the expiration to serve stale while revalidating
+	keep  int64 // Seconds after the expiration to serve stale if the backend fails
+
+	retain int64 // Seconds after the expiration to keep the response to revalidate it
+
+	revalidate bool // The response must be revalidated before each use
+}
+
+type cacheControl struct {
+	noStore bool
+	noCache bool
+	private bool
+	maxAge  int64 // -1 if not present
+	sMaxAge int64 // -1 if not present
+
+	staleWhileRevalidate int64 // -1 if not present
+	staleIfError         int64 // -1 if not present
+}
+
 type evalParams struct {
 	p map[string]interface{}
 }
@@ -74,6 +378,52 @@ type rule struct {
 	params []ruleParam
 }
 
+type nocacheRule struct {
+	rule
+
+	when string
+}
+
+type cacheKeyPart struct {
+	literal string
+	param   ruleParam
+}
+
+type cacheKeyTemplate []cacheKeyPart
+
+type cacheKeyRule struct {
+	rule
+
+	key cacheKeyTemplate
+}
+
+// frequencySketch is a count-min sketch which estimates how many times a key has been added,
+// aged by halving its counters after a number of additions or by resetting them after a time window.
+type frequencySketch struct {
+	counters [sketchDepth][]uint8
+
+	additions  int
+	sampleSize int // Additions before halving the counters, 0 to disable it
+
+	window  time.Duration // Time before resetting the counters, 0 to disable it
+	resetAt time.Time
+
+	mu sync.Mutex
+}
+
+type admissionRule struct {
+	rule
+
+	when     string
+	policy   string
+	requests uint8
+
+	sketch *frequencySketch
+
+	admitted atomic.Int64
+	rejected atomic.Int64
+}
+
 type typeHeaderAction int
 
 type headerRule struct {
@@ -90,6 +440,16 @@ type fetcher interface {
 	Do(req *fasthttp.Request, resp *fasthttp.Response) error
 }
 
+type balancer interface {
+	// next returns the backend for the request among the available ones not tried yet,
+	// or nil if there is none.
+	next(backends []*backend, ctx *fasthttp.RequestCtx, tried []*backend, now time.Time) *backend
+}
+
+type healthCheckClient interface {
+	DoTimeout(req *fasthttp.Request, resp *fasthttp.Response, timeout time.Duration) error
+}
+
 // Server ...
 type server interface {
 	ListenAndServe(addr string) error
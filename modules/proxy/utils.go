@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	gstrconv "github.com/savsgio/gotils/strconv"
+	"github.com/savsgio/kratgo/modules/cache"
 	"github.com/savsgio/kratgo/modules/config"
 	"github.com/valyala/fasthttp"
 )
@@ -62,6 +63,39 @@ func cloneHeaders(dst, src *fasthttp.RequestHeader) {
 	})
 }
 
+// isCacheableStatusCode returns true if the responses with the status code could be saved in cache.
+// The partial and not modified responses are never saved, they are produced from the full cached ones.
+func isCacheableStatusCode(statusCode int) bool {
+	return statusCode >= fasthttp.StatusOK && statusCode < 600 &&
+		statusCode != fasthttp.StatusPartialContent && statusCode != fasthttp.StatusNotModified
+}
+
+// responseStatusCode returns the status code of the cached response,
+// which is 200 OK for the responses saved without it.
+func responseStatusCode(r *cache.Response) int {
+	if r.StatusCode == 0 {
+		return fasthttp.StatusOK
+	}
+
+	return r.StatusCode
+}
+
+func isIdempotent(ctx *fasthttp.RequestCtx) bool {
+	return ctx.IsGet() || ctx.IsHead()
+}
+
+// isIdempotentMethod returns true if the requests with the method could be sent again
+// without other effects (RFC 9110, section 9.2.2).
+func isIdempotentMethod(method []byte) bool {
+	switch gstrconv.B2S(method) {
+	case fasthttp.MethodGet, fasthttp.MethodHead, fasthttp.MethodOptions, fasthttp.MethodTrace,
+		fasthttp.MethodPut, fasthttp.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
 func getEvalValue(ctx *fasthttp.RequestCtx, name, key string) string {
 	value := name
 
@@ -81,6 +115,9 @@ func getEvalValue(ctx *fasthttp.RequestCtx, name, key string) string {
 	case config.EvalStatusCodeVar:
 		value = strconv.Itoa(ctx.Response.StatusCode())
 
+	case config.EvalQueryVar:
+		value = gstrconv.B2S(ctx.Request.URI().QueryString())
+
 	default:
 		if strings.HasPrefix(name, config.EvalReqHeaderVar) {
 			value = gstrconv.B2S(ctx.Request.Header.Peek(key))
@@ -90,14 +127,35 @@ func getEvalValue(ctx *fasthttp.RequestCtx, name, key string) string {
 
 		} else if strings.HasPrefix(name, config.EvalCookieVar) {
 			value = gstrconv.B2S(ctx.Request.Header.Cookie(key))
+
+		} else if strings.HasPrefix(name, config.EvalQueryArgVar) {
+			value = gstrconv.B2S(ctx.Request.URI().QueryArgs().Peek(key))
 		}
 	}
 
 	return value
 }
 
-func checkIfNoCache(ctx *fasthttp.RequestCtx, rules []rule, params *evalParams) (bool, error) {
-	for _, r := range rules {
+func evalRule(ctx *fasthttp.RequestCtx, r rule, params *evalParams) (bool, error) {
+	params.reset()
+
+	for _, p := range r.params {
+		params.set(p.name, getEvalValue(ctx, p.name, p.subKey))
+	}
+
+	result, err := r.expr.Evaluate(params.all())
+	if err != nil {
+		return false, err
+	}
+
+	return result.(bool), nil
+}
+
+// checkIfNoCache returns the first nocache rule which matches the request, or nil if none matches.
+func checkIfNoCache(ctx *fasthttp.RequestCtx, rules []nocacheRule, params *evalParams) (*nocacheRule, error) {
+	for i := range rules {
+		r := &rules[i]
+
 		params.reset()
 
 		for _, p := range r.params {
@@ -106,15 +164,15 @@ func checkIfNoCache(ctx *fasthttp.RequestCtx, rules []rule, params *evalParams)
 
 		result, err := r.expr.Evaluate(params.all())
 		if err != nil {
-			return false, fmt.Errorf("Invalid nocache rule: %v", err)
+			return nil, fmt.Errorf("Invalid nocache rule: %v", err)
 		}
 
 		if result.(bool) {
-			return true, nil
+			return r, nil
 		}
 	}
 
-	return false, nil
+	return nil, nil
 }
 
 func processHeaderRules(ctx *fasthttp.RequestCtx, rules []headerRule, params *evalParams) error {
@@ -112,8 +112,11 @@ func Test_getEvalValue(t *testing.T) {
 	respHeaderValue := "false"
 	cookieName := "kratcookie"
 	cookieValue := "1234"
+	queryArgName := "page"
+	queryArgValue := "2"
+	query := queryArgName + "=" + queryArgValue
 
-	ctx.Request.SetRequestURI(path)
+	ctx.Request.SetRequestURI(path + "?" + query)
 	ctx.Request.Header.SetMethod(method)
 	ctx.Request.Header.SetHost(host)
 	ctx.Request.Header.Set(reqHeaderName, reqHeaderValue)
@@ -212,6 +215,25 @@ func Test_getEvalValue(t *testing.T) {
 				value: cookieValue,
 			},
 		},
+		{
+			name: "query",
+			args: args{
+				name: config.EvalQueryVar,
+			},
+			want: want{
+				value: query,
+			},
+		},
+		{
+			name: "query-arg",
+			args: args{
+				name: config.EvalQueryArgVar,
+				key:  queryArgName,
+			},
+			want: want{
+				value: queryArgValue,
+			},
+		},
 		{
 			name: "unknown",
 			args: args{
@@ -232,6 +254,37 @@ func Test_getEvalValue(t *testing.T) {
 	}
 }
 
+func Test_evalRule(t *testing.T) {
+	p, err := New(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expr, params, err := p.newEvaluableExpression("$(path) == '/kratgo'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := rule{expr: expr, params: params}
+
+	ctx := new(fasthttp.RequestCtx)
+	ep := acquireEvalParams()
+
+	ctx.Request.SetRequestURI("/kratgo")
+	if match, err := evalRule(ctx, r, ep); err != nil || !match {
+		t.Errorf("evalRule() = '%v', '%v', want '%v', '%v'", match, err, true, nil)
+	}
+
+	ctx.Request.SetRequestURI("/fast")
+	if match, err := evalRule(ctx, r, ep); err != nil || match {
+		t.Errorf("evalRule() = '%v', '%v', want '%v', '%v'", match, err, false, nil)
+	}
+
+	r.params = r.params[:0]
+	if _, err := evalRule(ctx, r, ep); err == nil {
+		t.Errorf("evalRule() expected error")
+	}
+}
+
 func Test_checkIfNoCache(t *testing.T) {
 	cfg := testConfig()
 	cfg.FileConfig.Nocache = []string{
@@ -328,7 +381,7 @@ func Test_checkIfNoCache(t *testing.T) {
 				}
 			}
 
-			noCache, err := checkIfNoCache(ctx, p.nocacheRules, params)
+			r, err := checkIfNoCache(ctx, p.nocacheRules, params)
 			if (err != nil) != tt.want.err {
 				t.Errorf("Unexpected error: %v", err)
 			}
@@ -337,7 +390,7 @@ func Test_checkIfNoCache(t *testing.T) {
 				return
 			}
 
-			if noCache != tt.want.noCache {
+			if noCache := r != nil; noCache != tt.want.noCache {
 				t.Errorf("checkIfNoCache() = '%v', want '%v'", noCache, tt.want.noCache)
 			}
 		})
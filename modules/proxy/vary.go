@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/savsgio/kratgo/modules/cache"
+	"github.com/valyala/fasthttp"
+)
+
+func isVaryValueSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+func toLower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + ('a' - 'A')
+	}
+
+	return c
+}
+
+// appendNormalizedVaryValue appends to dst the request header value in lower case and without whitespaces,
+// so the equivalent values of a request header select the same response variant.
+func appendNormalizedVaryValue(dst, value []byte) []byte {
+	for _, c := range value {
+		if !isVaryValueSpace(c) {
+			dst = append(dst, toLower(c))
+		}
+	}
+
+	return dst
+}
+
+// equalVaryValue compares a normalized value with the raw value of a request header.
+func equalVaryValue(normalized, value []byte) bool {
+	i := 0
+
+	for _, c := range value {
+		if isVaryValueSpace(c) {
+			continue
+		}
+
+		if i >= len(normalized) || normalized[i] != toLower(c) {
+			return false
+		}
+
+		i++
+	}
+
+	return i == len(normalized)
+}
+
+// varyHeaderNames returns the canonical, unique and sorted header names of the Vary response header,
+// except Accept-Encoding, and false if the response varies on everything ("Vary: *").
+func varyHeaderNames(h *fasthttp.ResponseHeader) ([][]byte, bool) {
+	var names [][]byte
+
+	for _, value := range h.PeekAll(fasthttp.HeaderVary) {
+		for _, name := range bytes.Split(value, []byte(",")) {
+			name = bytes.TrimSpace(name)
+			if len(name) == 0 {
+				continue
+			} else if bytes.Equal(name, varyAll) {
+				return nil, false
+			}
+
+			name = fasthttp.AppendNormalizedHeaderKeyBytes(nil, name)
+			if bytes.Equal(name, headerAcceptEncoding) {
+				// The proxy serves every encoding from the same response
+				continue
+			}
+
+			exists := false
+			for _, n := range names {
+				if bytes.Equal(n, name) {
+					exists = true
+					break
+				}
+			}
+
+			if !exists {
+				names = append(names, name)
+			}
+		}
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return bytes.Compare(names[i], names[j]) < 0
+	})
+
+	return names, true
+}
+
+// setResponseVary saves in the cache response the request values of the headers
+// listed in the Vary backend response header.
+// Returns false if the response varies on everything ("Vary: *") so it could not be saved in cache.
+func setResponseVary(r *cache.Response, req *fasthttp.RequestHeader, resp *fasthttp.ResponseHeader) bool {
+	names, ok := varyHeaderNames(resp)
+	if !ok {
+		return false
+	}
+
+	var value []byte
+
+	for _, name := range names {
+		value = appendNormalizedVaryValue(value[:0], req.PeekBytes(name))
+		r.SetVary(name, value)
+	}
+
+	return true
+}
+
+// matchVary returns true if the request headers select the response variant.
+func matchVary(r *cache.Response, req *fasthttp.RequestHeader) bool {
+	for i := range r.Vary {
+		h := &r.Vary[i]
+
+		if !equalVaryValue(h.Value, req.PeekBytes(h.Key)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// getResponseVariant returns the response variant of the path selected by the request headers.
+func getResponseVariant(entry *cache.Entry, path []byte, req *fasthttp.RequestHeader) *cache.Response {
+	for i := range entry.Responses {
+		r := &entry.Responses[i]
+
+		if bytes.Equal(r.Path, path) && matchVary(r, req) {
+			return r
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package proxy
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/savsgio/kratgo/modules/cache"
+	"github.com/valyala/fasthttp"
+)
+
+func Test_appendNormalizedVaryValue(t *testing.T) {
+	value := []byte(" gzip, Deflate,\tBR ")
+	want := "gzip,deflate,br"
+
+	if v := appendNormalizedVaryValue(nil, value); string(v) != want {
+		t.Errorf("appendNormalizedVaryValue() = '%s', want '%s'", v, want)
+	}
+}
+
+func Test_equalVaryValue(t *testing.T) {
+	type args struct {
+		normalized string
+		value      string
+	}
+
+	type want struct {
+		equal bool
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Equal",
+			args: args{normalized: "gzip,br", value: "GZIP, br"},
+			want: want{equal: true},
+		},
+		{
+			name: "EmptyEqual",
+			args: args{normalized: "", value: ""},
+			want: want{equal: true},
+		},
+		{
+			name: "Different",
+			args: args{normalized: "gzip,br", value: "gzip"},
+			want: want{equal: false},
+		},
+		{
+			name: "Longer",
+			args: args{normalized: "gzip", value: "gzip,br"},
+			want: want{equal: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if equal := equalVaryValue([]byte(tt.args.normalized), []byte(tt.args.value)); equal != tt.want.equal {
+				t.Errorf("equalVaryValue() = '%v', want '%v'", equal, tt.want.equal)
+			}
+		})
+	}
+}
+
+func Test_varyHeaderNames(t *testing.T) {
+	type args struct {
+		vary []string
+	}
+
+	type want struct {
+		names []string
+		ok    bool
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "Empty",
+			args: args{},
+			want: want{ok: true},
+		},
+		{
+			name: "SortedAndUnique",
+			args: args{vary: []string{"accept-language, Accept-Charset", "Accept-Language"}},
+			want: want{names: []string{"Accept-Charset", "Accept-Language"}, ok: true},
+		},
+		{
+			name: "AcceptEncoding",
+			args: args{vary: []string{"Accept-Encoding, Accept-Language"}},
+			want: want{names: []string{"Accept-Language"}, ok: true},
+		},
+		{
+			name: "All",
+			args: args{vary: []string{"Accept-Encoding, *"}},
+			want: want{ok: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := new(fasthttp.ResponseHeader)
+			for _, v := range tt.args.vary {
+				h.Add(fasthttp.HeaderVary, v)
+			}
+
+			names, ok := varyHeaderNames(h)
+			if ok != tt.want.ok {
+				t.Fatalf("varyHeaderNames() ok = '%v', want '%v'", ok, tt.want.ok)
+			}
+
+			var strNames []string
+			for _, n := range names {
+				strNames = append(strNames, string(n))
+			}
+
+			if !reflect.DeepEqual(strNames, tt.want.names) {
+				t.Errorf("varyHeaderNames() = '%v', want '%v'", strNames, tt.want.names)
+			}
+		})
+	}
+}
+
+func Test_getResponseVariant(t *testing.T) {
+	path := []byte("/fast/")
+
+	entry := cache.AcquireEntry()
+	defer cache.ReleaseEntry(entry)
+
+	req := new(fasthttp.RequestHeader)
+	resp := new(fasthttp.ResponseHeader)
+	resp.Set(fasthttp.HeaderVary, "Accept-Language")
+
+	for _, lang := range []string{"es", "en"} {
+		r := cache.AcquireResponse()
+		r.Path = path
+		r.Body = []byte(lang)
+
+		req.Set("Accept-Language", lang)
+		if !setResponseVary(r, req, resp) {
+			t.Fatal("setResponseVary() returns 'false', want 'true'")
+		}
+
+		entry.SetResponse(*r)
+	}
+
+	req.Set("Accept-Language", "EN")
+
+	r := getResponseVariant(entry, path, req)
+	if r == nil {
+		t.Fatalf("getResponseVariant() = '%v', want body '%s'", nil, "en")
+	}
+
+	if string(r.Body) != "en" {
+		t.Errorf("getResponseVariant() body = '%s', want '%s'", r.Body, "en")
+	}
+
+	req.Set("Accept-Language", "fr")
+
+	if r := getResponseVariant(entry, path, req); r != nil {
+		t.Errorf("getResponseVariant() = '%v', want '%v'", *r, nil)
+	}
+}
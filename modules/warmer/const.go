@@ -0,0 +1,17 @@
+package warmer
+
+const defaultConcurrency = 4
+
+// Requests per second of the jobs without a configured rate, and max rate of a job
+const (
+	defaultRate = 100
+	maxRate     = 10000
+)
+
+// Max nested sitemaps followed from a sitemap index
+const maxSitemapDepth = 1
+
+// Max failures kept in the progress, the rest are only counted
+const maxFailures = 100
+
+const userAgent = "Kratgo-Warmer"
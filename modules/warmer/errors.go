@@ -0,0 +1,18 @@
+package warmer
+
+import "errors"
+
+// ErrEmptyJob ...
+var ErrEmptyJob = errors.New("Minimum one url or a sitemap")
+
+// ErrRunning ...
+var ErrRunning = errors.New("Other warming job is running")
+
+// ErrMissingHost ...
+var ErrMissingHost = errors.New("The url has not a host")
+
+// ErrInvalidConcurrency ...
+var ErrInvalidConcurrency = errors.New("Concurrency must be greater than or equal to 0")
+
+// ErrInvalidRate ...
+var ErrInvalidRate = errors.New("Rate must be between 0 and 10000")
@@ -0,0 +1,46 @@
+package warmer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// parseSitemap returns the urls and the nested sitemaps of a sitemap, which could be gzipped.
+func parseSitemap(body []byte) (urls, sitemaps []string, err error) {
+	var r io.Reader = bytes.NewReader(body)
+
+	if bytes.HasPrefix(body, gzipMagic) {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Could not decompress the sitemap: %v", err)
+		}
+		defer zr.Close()
+
+		r = zr
+	}
+
+	sm := new(sitemap)
+	if err := xml.NewDecoder(r).Decode(sm); err != nil {
+		return nil, nil, fmt.Errorf("Could not parse the sitemap: %v", err)
+	}
+
+	for _, u := range sm.URLs {
+		if loc := strings.TrimSpace(u.Loc); loc != "" {
+			urls = append(urls, loc)
+		}
+	}
+
+	for _, s := range sm.Sitemaps {
+		if loc := strings.TrimSpace(s.Loc); loc != "" {
+			sitemaps = append(sitemaps, loc)
+		}
+	}
+
+	return urls, sitemaps, nil
+}
@@ -0,0 +1,90 @@
+package warmer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"testing"
+)
+
+const testSitemapURLSet = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>http://www.kratgo.com/</loc></url>
+	<url>
+		<loc>
+			http://www.kratgo.com/fast/
+		</loc>
+		<lastmod>2023-01-01</lastmod>
+	</url>
+</urlset>`
+
+const testSitemapIndex = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap><loc>http://www.kratgo.com/sitemap-1.xml</loc></sitemap>
+</sitemapindex>`
+
+func gzipBody(body string) []byte {
+	buf := new(bytes.Buffer)
+
+	zw := gzip.NewWriter(buf)
+	zw.Write([]byte(body))
+	zw.Close()
+
+	return buf.Bytes()
+}
+
+func Test_parseSitemap(t *testing.T) {
+	type args struct {
+		body []byte
+	}
+
+	type want struct {
+		urls     []string
+		sitemaps []string
+		err      bool
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "URLSet",
+			args: args{body: []byte(testSitemapURLSet)},
+			want: want{urls: []string{"http://www.kratgo.com/", "http://www.kratgo.com/fast/"}},
+		},
+		{
+			name: "Index",
+			args: args{body: []byte(testSitemapIndex)},
+			want: want{sitemaps: []string{"http://www.kratgo.com/sitemap-1.xml"}},
+		},
+		{
+			name: "Gzip",
+			args: args{body: gzipBody(testSitemapURLSet)},
+			want: want{urls: []string{"http://www.kratgo.com/", "http://www.kratgo.com/fast/"}},
+		},
+		{
+			name: "Invalid",
+			args: args{body: []byte("<html>")},
+			want: want{err: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urls, sitemaps, err := parseSitemap(tt.args.body)
+			if (err != nil) != tt.want.err {
+				t.Fatalf("parseSitemap() error == '%v', want '%v'", err, tt.want.err)
+			}
+
+			if !reflect.DeepEqual(urls, tt.want.urls) {
+				t.Errorf("parseSitemap() urls == '%v', want '%v'", urls, tt.want.urls)
+			}
+
+			if !reflect.DeepEqual(sitemaps, tt.want.sitemaps) {
+				t.Errorf("parseSitemap() sitemaps == '%v', want '%v'", sitemaps, tt.want.sitemaps)
+			}
+		})
+	}
+}
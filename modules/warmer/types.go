@@ -0,0 +1,64 @@
+package warmer
+
+import (
+	"io"
+	"sync"
+
+	logger "github.com/savsgio/go-logger/v4"
+	"github.com/savsgio/kratgo/modules/config"
+	"github.com/valyala/fasthttp"
+)
+
+// Config ...
+type Config struct {
+	FileConfig config.Warmer
+	Handler    fasthttp.RequestHandler
+
+	LogLevel  logger.Level
+	LogOutput io.Writer
+}
+
+// Warmer ...
+type Warmer struct {
+	fileConfig config.Warmer
+
+	handler fasthttp.RequestHandler
+
+	progress Progress
+	mu       sync.RWMutex
+
+	log *logger.Logger
+}
+
+// Job ...
+type Job struct {
+	URLs        []string `json:"urls"`
+	Sitemap     string   `json:"sitemap"`
+	Concurrency int      `json:"concurrency"`
+	Rate        int      `json:"rate"` // Requests per second
+}
+
+// Failure ...
+type Failure struct {
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
+
+// Progress ...
+type Progress struct {
+	Running  bool      `json:"running"`
+	Total    int       `json:"total"`
+	Done     int       `json:"done"`
+	Failed   int       `json:"failed"`
+	Failures []Failure `json:"failures"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// sitemap is either an urlset or a sitemap index, as defined in https://www.sitemaps.org/protocol.html
+type sitemap struct {
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
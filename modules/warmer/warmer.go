@@ -0,0 +1,238 @@
+package warmer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	logger "github.com/savsgio/go-logger/v4"
+	"github.com/valyala/fasthttp"
+)
+
+// New ...
+func New(cfg Config) (*Warmer, error) {
+	if cfg.FileConfig.Concurrency < 0 {
+		return nil, ErrInvalidConcurrency
+	} else if cfg.FileConfig.Rate < 0 || cfg.FileConfig.Rate > maxRate {
+		return nil, ErrInvalidRate
+	}
+
+	log := logger.New(cfg.LogLevel, cfg.LogOutput, logger.Field{Key: "type", Value: "warmer"})
+
+	w := &Warmer{
+		fileConfig: cfg.FileConfig,
+		handler:    cfg.Handler,
+		log:        log,
+	}
+
+	return w, nil
+}
+
+// Start runs the warming job in background. Only one job could run at the same time.
+func (w *Warmer) Start(job Job) error {
+	if len(job.URLs) == 0 && job.Sitemap == "" {
+		return ErrEmptyJob
+	} else if job.Concurrency < 0 {
+		return ErrInvalidConcurrency
+	} else if job.Rate < 0 || job.Rate > maxRate {
+		return ErrInvalidRate
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.progress.Running {
+		return ErrRunning
+	}
+
+	w.progress = Progress{Running: true}
+
+	go w.run(job)
+
+	return nil
+}
+
+// Progress returns the progress of the running warming job, or of the last one if it has finished.
+func (w *Warmer) Progress() Progress {
+	w.mu.RLock()
+
+	p := w.progress
+	p.Failures = append([]Failure(nil), w.progress.Failures...)
+
+	w.mu.RUnlock()
+
+	return p
+}
+
+func (w *Warmer) fail(url string, err error) {
+	w.log.Errorf("Could not warm '%s': %v", url, err)
+
+	w.mu.Lock()
+
+	w.progress.Failed++
+	if len(w.progress.Failures) < maxFailures {
+		w.progress.Failures = append(w.progress.Failures, Failure{URL: url, Error: err.Error()})
+	}
+
+	w.mu.Unlock()
+}
+
+// failSitemap counts the sitemap which could not be fetched as a failed url of the job.
+func (w *Warmer) failSitemap(url string, err error) {
+	w.fail(url, err)
+
+	w.mu.Lock()
+	w.progress.Total++
+	w.progress.Done++
+	w.mu.Unlock()
+}
+
+func (w *Warmer) concurrency(job Job) int {
+	if job.Concurrency > 0 {
+		return job.Concurrency
+	} else if w.fileConfig.Concurrency > 0 {
+		return w.fileConfig.Concurrency
+	}
+
+	return defaultConcurrency
+}
+
+func (w *Warmer) rate(job Job) int {
+	if job.Rate > 0 {
+		return job.Rate
+	} else if w.fileConfig.Rate > 0 {
+		return w.fileConfig.Rate
+	}
+
+	return defaultRate
+}
+
+// fetch requests the url through the proxy handler, as a client request with the host of the url.
+func (w *Warmer) fetch(url string, ctx *fasthttp.RequestCtx) error {
+	uri := fasthttp.AcquireURI()
+	defer fasthttp.ReleaseURI(uri)
+
+	if err := uri.Parse(nil, []byte(url)); err != nil {
+		return err
+	} else if len(uri.Host()) == 0 {
+		return ErrMissingHost
+	}
+
+	ctx.Request.SetRequestURIBytes(uri.RequestURI())
+	ctx.Request.Header.SetHostBytes(uri.Host())
+	ctx.Request.Header.SetUserAgent(userAgent)
+
+	w.handler(ctx)
+
+	if statusCode := ctx.Response.StatusCode(); statusCode >= fasthttp.StatusBadRequest {
+		return fmt.Errorf("Unexpected status code '%d'", statusCode)
+	}
+
+	return nil
+}
+
+// sitemapURLs returns the urls of the sitemap, following the nested sitemaps of a sitemap index.
+func (w *Warmer) sitemapURLs(url string, depth int) ([]string, error) {
+	ctx := new(fasthttp.RequestCtx)
+	defer ctx.Response.Reset()
+
+	if err := w.fetch(url, ctx); err != nil {
+		return nil, err
+	}
+
+	urls, sitemaps, err := parseSitemap(ctx.Response.Body())
+	if err != nil {
+		return nil, err
+	}
+
+	if depth >= maxSitemapDepth {
+		return urls, nil
+	}
+
+	for _, sm := range sitemaps {
+		smURLs, err := w.sitemapURLs(sm, depth+1)
+		if err != nil {
+			w.failSitemap(sm, err)
+			continue
+		}
+
+		urls = append(urls, smURLs...)
+	}
+
+	return urls, nil
+}
+
+func (w *Warmer) warm(url string) {
+	ctx := new(fasthttp.RequestCtx)
+
+	err := w.fetch(url, ctx)
+
+	// Close the body stream of the responses not saved in cache
+	ctx.Response.Reset()
+
+	if err != nil {
+		w.fail(url, err)
+	}
+
+	w.mu.Lock()
+	w.progress.Done++
+	w.mu.Unlock()
+}
+
+func (w *Warmer) run(job Job) {
+	start := time.Now()
+	urls := append([]string(nil), job.URLs...)
+
+	if job.Sitemap != "" {
+		smURLs, err := w.sitemapURLs(job.Sitemap, 0)
+		if err != nil {
+			w.failSitemap(job.Sitemap, err)
+		}
+
+		urls = append(urls, smURLs...)
+	}
+
+	w.mu.Lock()
+	w.progress.Total += len(urls)
+	w.mu.Unlock()
+
+	concurrency, rate := w.concurrency(job), w.rate(job)
+
+	w.log.Infof("Warming %d urls (concurrency: %d, rate: %d/s)", len(urls), concurrency, rate)
+
+	chURLs := make(chan string)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+
+		go func() {
+			for url := range chURLs {
+				w.warm(url)
+			}
+
+			wg.Done()
+		}()
+	}
+
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
+
+	for i, url := range urls {
+		if i > 0 {
+			<-ticker.C
+		}
+
+		chURLs <- url
+	}
+
+	close(chURLs)
+	wg.Wait()
+
+	w.mu.Lock()
+	w.progress.Running = false
+	p := w.progress
+	w.mu.Unlock()
+
+	w.log.Infof("Warmed %d urls in %s, %d failed", p.Done, time.Since(start), p.Failed)
+}
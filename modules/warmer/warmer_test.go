@@ -0,0 +1,230 @@
+package warmer
+
+import (
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	logger "github.com/savsgio/go-logger/v4"
+	"github.com/savsgio/kratgo/modules/config"
+	"github.com/valyala/fasthttp"
+)
+
+type mockHandler struct {
+	delay time.Duration
+
+	requests []string
+	active   int32
+	maxConc  int32
+
+	mu sync.Mutex
+}
+
+func (mock *mockHandler) handle(ctx *fasthttp.RequestCtx) {
+	active := atomic.AddInt32(&mock.active, 1)
+	defer atomic.AddInt32(&mock.active, -1)
+
+	mock.mu.Lock()
+	mock.requests = append(mock.requests, string(ctx.Host())+string(ctx.RequestURI()))
+	if active > mock.maxConc {
+		mock.maxConc = active
+	}
+	mock.mu.Unlock()
+
+	time.Sleep(mock.delay)
+
+	switch string(ctx.Path()) {
+	case "/sitemap.xml":
+		ctx.SetBodyString(testSitemapIndex)
+	case "/sitemap-1.xml":
+		ctx.SetBodyString(testSitemapURLSet)
+	case "/error/":
+		ctx.SetStatusCode(fasthttp.StatusBadGateway)
+	default:
+		ctx.SetBodyString("Kratgo is fast")
+	}
+}
+
+func testConfig(handler fasthttp.RequestHandler) Config {
+	return Config{
+		FileConfig: config.Warmer{Concurrency: 2},
+		Handler:    handler,
+		LogLevel:   logger.FATAL,
+		LogOutput:  os.Stderr,
+	}
+}
+
+func waitFinished(t *testing.T, w *Warmer) Progress {
+	for i := 0; i < 100; i++ {
+		if p := w.Progress(); !p.Running {
+			return p
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	t.Fatal("Warmer job has not finished")
+
+	return Progress{}
+}
+
+func TestWarmer_New(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Warmer
+		err  error
+	}{
+		{name: "Ok", cfg: config.Warmer{Concurrency: 2, Rate: 10}},
+		{name: "InvalidConcurrency", cfg: config.Warmer{Concurrency: -1}, err: ErrInvalidConcurrency},
+		{name: "InvalidRate", cfg: config.Warmer{Rate: -1}, err: ErrInvalidRate},
+		{name: "RateTooHigh", cfg: config.Warmer{Rate: maxRate + 1}, err: ErrInvalidRate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(nil)
+			cfg.FileConfig = tt.cfg
+
+			w, err := New(cfg)
+			if err != tt.err {
+				t.Fatalf("New() error == '%v', want '%v'", err, tt.err)
+			}
+
+			if err == nil && w.log == nil {
+				t.Errorf("New() log is '%v'", nil)
+			}
+		})
+	}
+}
+
+func TestWarmer_Start(t *testing.T) {
+	handler := &mockHandler{delay: 20 * time.Millisecond}
+
+	w, err := New(testConfig(handler.handle))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Start(Job{}); err != ErrEmptyJob {
+		t.Errorf("Warmer.Start() error == '%v', want '%v'", err, ErrEmptyJob)
+	}
+
+	if err := w.Start(Job{URLs: []string{"/"}, Rate: -1}); err != ErrInvalidRate {
+		t.Errorf("Warmer.Start() error == '%v', want '%v'", err, ErrInvalidRate)
+	}
+
+	if err := w.Start(Job{URLs: []string{"/"}, Rate: maxRate + 1}); err != ErrInvalidRate {
+		t.Errorf("Warmer.Start() error == '%v', want '%v'", err, ErrInvalidRate)
+	}
+
+	job := Job{
+		URLs: []string{
+			"http://www.kratgo.com/a/",
+			"http://www.kratgo.com/b/",
+			"http://www.kratgo.com/c/",
+			"http://www.kratgo.com/error/",
+			"/without-host/",
+		},
+		Sitemap: "http://www.kratgo.com/sitemap.xml",
+	}
+
+	if err := w.Start(job); err != nil {
+		t.Fatalf("Warmer.Start() error == '%v'", err)
+	}
+
+	if err := w.Start(job); err != ErrRunning {
+		t.Errorf("Warmer.Start() error == '%v', want '%v'", err, ErrRunning)
+	}
+
+	p := waitFinished(t, w)
+
+	if p.Total != 7 || p.Done != 7 || p.Failed != 2 || len(p.Failures) != 2 {
+		t.Errorf("Warmer.Progress() == '%+v', want total, done and failed '%d %d %d'", p, 7, 7, 2)
+	}
+
+	if handler.maxConc > 2 {
+		t.Errorf("Warmer concurrent requests == '%d', want '%d'", handler.maxConc, 2)
+	}
+
+	sort.Strings(handler.requests)
+
+	want := []string{
+		"www.kratgo.com/",
+		"www.kratgo.com/a/",
+		"www.kratgo.com/b/",
+		"www.kratgo.com/c/",
+		"www.kratgo.com/error/",
+		"www.kratgo.com/fast/",
+		"www.kratgo.com/sitemap-1.xml",
+		"www.kratgo.com/sitemap.xml",
+	}
+
+	if len(handler.requests) != len(want) {
+		t.Fatalf("Warmer requests == '%v', want '%v'", handler.requests, want)
+	}
+
+	for i := range want {
+		if handler.requests[i] != want[i] {
+			t.Errorf("Warmer requests == '%v', want '%v'", handler.requests, want)
+			break
+		}
+	}
+}
+
+func TestWarmer_StartSitemapError(t *testing.T) {
+	handler := new(mockHandler)
+
+	w, err := New(testConfig(handler.handle))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Start(Job{URLs: []string{"http://www.kratgo.com/a/"}, Sitemap: "http://www.kratgo.com/error/"}); err != nil {
+		t.Fatalf("Warmer.Start() error == '%v'", err)
+	}
+
+	p := waitFinished(t, w)
+
+	if p.Total != 2 || p.Done != 2 || p.Failed != 1 || len(p.Failures) != 1 {
+		t.Errorf("Warmer.Progress() == '%+v', want total, done and failed '%d %d %d'", p, 2, 2, 1)
+	}
+}
+
+func TestWarmer_rate(t *testing.T) {
+	handler := new(mockHandler)
+
+	w, err := New(testConfig(handler.handle))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rate := w.rate(Job{}); rate != defaultRate {
+		t.Errorf("Warmer.rate() == '%d', want '%d'", rate, defaultRate)
+	}
+
+	w.fileConfig.Rate = 50
+
+	if rate := w.rate(Job{}); rate != 50 {
+		t.Errorf("Warmer.rate() == '%d', want '%d'", rate, 50)
+	}
+
+	job := Job{
+		URLs: []string{"http://www.kratgo.com/a/", "http://www.kratgo.com/b/", "http://www.kratgo.com/c/"},
+		Rate: 10,
+	}
+
+	start := time.Now()
+
+	if err := w.Start(job); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFinished(t, w)
+
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Warmer job elapsed == '%s', want greater than '%s'", elapsed, 200*time.Millisecond)
+	}
+}